@@ -0,0 +1,402 @@
+// Package braking analyses every braking event of a lap and groups them by the corner they lead into.
+//
+// An Analyzer detects braking events from the Brake variable. Each event describes where the brakes were
+// applied, how hard and how quickly, how the pressure was released while trail braking and how much speed
+// was scrubbed. Events are assigned to the corners of a corners.TrackModel, allowing the braking for each
+// corner to be compared across laps with Compare.
+package braking
+
+import (
+	"errors"
+	"math"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/corners"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/laps"
+)
+
+const (
+	// Default minimum brake pressure, between 0 and 1, for the brakes to be applied
+	defaultThreshold float64 = 0.05
+	// Default minimum duration in seconds of a braking event
+	defaultMinDuration float64 = 0.2
+	// Speed in meters per second the car needs to gain after releasing the brakes for the minimum speed of
+	// the event to be reached
+	recoverySpeed float64 = 0.5
+	// Largest fraction of the lap between the minimum speed of an event and the entry of its corner
+	cornerLookahead float64 = 0.05
+	// Number of points in the release profile of an event
+	releasePoints int = 11
+)
+
+func init() {
+	ibt.Register("braking", func(params ibt.Params) (ibt.Processor, error) {
+		var opts Options
+		if err := params.Decode(&opts); err != nil {
+			return nil, err
+		}
+
+		// The corners are loaded from the store, as no model can be provided through the params
+		if opts.Models == "" {
+			return nil, errors.New("a models directory is required for assigning events to corners")
+		}
+
+		return NewAnalyzer(corners.TrackModel{}, opts), nil
+	})
+}
+
+// Options configures the detection of braking events.
+type Options struct {
+	// Threshold is the minimum brake pressure, between 0 and 1, for the brakes to be applied. Defaults to 0.05.
+	Threshold float64 `yaml:"threshold"`
+	// MinDuration is the minimum duration in seconds of a braking event. Defaults to 0.2.
+	MinDuration float64 `yaml:"min_duration"`
+	// Models is the directory of a corners.ModelStore. When set, the model of the track of the session is
+	// loaded from the store if no model was provided, failing when no model was stored for the track. This
+	// is required when the Analyzer is created from the registry.
+	Models string `yaml:"models"`
+}
+
+// Event is a single application of the brakes, from brake-on until the car reaches its minimum speed.
+type Event struct {
+	// Stub is the index of the stub within the group being processed
+	Stub int
+	// Filename of the stub
+	Filename string
+	// Lap during which the brakes were applied
+	Lap int
+	// Corner the braking leads into. This is 0 when no corner of the model was found.
+	Corner int
+	// Start is the LapDistPct where the brakes were applied
+	Start float64
+	// StartDist is the LapDist in meters where the brakes were applied
+	StartDist float64
+	// StartTime is the session time at which the brakes were applied
+	StartTime float64
+	// End is the LapDistPct where the brakes were released
+	End float64
+	// Duration in seconds that the brakes were applied
+	Duration float64
+	// Distance in meters driven while braking
+	Distance float64
+	// PeakBrake is the highest brake pressure, between 0 and 1
+	PeakBrake float64
+	// PeakBrakeRaw is the highest brake pedal position, between 0 and 1, before any brake assists
+	PeakBrakeRaw float64
+	// TimeToPeak is the number of seconds from brake-on until the peak brake pressure was reached
+	TimeToPeak float64
+	// ReleaseTime is the number of seconds from the peak brake pressure until the brakes were released
+	ReleaseTime float64
+	// Release is the brake pressure at equal intervals of the release, from the peak until the brakes
+	// were released
+	Release []float64
+	// TrailTime is the number of seconds the brakes were applied within the corner
+	TrailTime float64
+	// EntrySpeed is the speed in meters per second when the brakes were applied
+	EntrySpeed float64
+	// MinSpeed is the lowest speed in meters per second reached during or after braking
+	MinSpeed float64
+	// MinSpeedPct is the LapDistPct where the lowest speed was reached
+	MinSpeedPct float64
+	// MaxDecel is the largest deceleration in meters per second squared while braking, from LongAccel
+	MaxDecel float64
+	// AvgDecel is the average deceleration in meters per second squared while braking, from LongAccel
+	AvgDecel float64
+}
+
+// Result of the braking events processed by an Analyzer.
+type Result struct {
+	// Events in the order they occurred
+	Events []Event
+	// Corners contains the events of each corner, compared across laps
+	Corners []CornerBraking
+}
+
+// Analyzer is a processor that detects braking events.
+type Analyzer struct {
+	opts   Options
+	model  corners.TrackModel
+	store  *corners.ModelStore
+	events []Event
+
+	stub    ibt.StubInfo
+	current *event
+	prev    brakingTick
+	hasPrev bool
+}
+
+// brakingTick is the part of a tick used for detecting braking events
+type brakingTick struct {
+	lap         int
+	pct         float64
+	dist        float64
+	sessionTime float64
+	speed       float64
+	brake       float64
+	brakeRaw    float64
+	longAccel   float64
+}
+
+// event is a braking event in progress
+type event struct {
+	Event
+	// braking indicates that the brakes are still applied
+	braking  bool
+	samples  []brakeSample
+	peakIdx  int
+	decelSum float64
+}
+
+// brakeSample is the brake pressure at a tick of a braking event
+type brakeSample struct {
+	time  float64
+	dt    float64
+	pct   float64
+	brake float64
+}
+
+// NewAnalyzer creates an Analyzer assigning events to the corners of the given model.
+//
+// Events are not assigned to corners when the model does not contain any corners and no Models
+// directory was configured.
+func NewAnalyzer(model corners.TrackModel, opts Options) *Analyzer {
+	if opts.Threshold <= 0 {
+		opts.Threshold = defaultThreshold
+	}
+	if opts.MinDuration <= 0 {
+		opts.MinDuration = defaultMinDuration
+	}
+
+	a := &Analyzer{opts: opts, model: model}
+	if len(model.Corners) == 0 && opts.Models != "" {
+		a.store = corners.NewModelStore(opts.Models)
+	}
+
+	return a
+}
+
+// Whitelist of the variables required for detecting braking events
+func (a *Analyzer) Whitelist() []string {
+	return []string{"Lap", "LapDistPct", "LapDist", "Speed", "Brake", "BrakeRaw", "LongAccel"}
+}
+
+// StartStub completes the braking event in progress of the previous stub
+func (a *Analyzer) StartStub(stub ibt.StubInfo) error {
+	a.finish()
+	a.stub = stub
+	a.hasPrev = false
+
+	return nil
+}
+
+// Process the tick without a TickContext. Braking events are timed with the SessionTime of the tick.
+func (a *Analyzer) Process(input ibt.Tick, hasNext bool, session *headers.Session) error {
+	return a.ProcessContext(input, ibt.ContextFromTick(input), hasNext, session)
+}
+
+// ProcessContext adds the tick to the braking event in progress, or starts a new event when the brakes
+// are applied
+func (a *Analyzer) ProcessContext(input ibt.Tick, tickCtx ibt.TickContext, hasNext bool, session *headers.Session) error {
+	lap, err := ibt.GetTickValue[int](input, "Lap")
+	if err != nil {
+		return err
+	}
+
+	pct, err := ibt.GetTickValue[float32](input, "LapDistPct")
+	if err != nil {
+		return err
+	}
+
+	speed, err := ibt.GetTickValue[float32](input, "Speed")
+	if err != nil {
+		return err
+	}
+
+	brake, err := ibt.GetTickValue[float32](input, "Brake")
+	if err != nil {
+		return err
+	}
+
+	// The raw pedal position, distance and deceleration are not required for detecting braking events
+	dist, _ := ibt.GetTickValue[float32](input, "LapDist")
+	brakeRaw, _ := ibt.GetTickValue[float32](input, "BrakeRaw")
+	longAccel, _ := ibt.GetTickValue[float32](input, "LongAccel")
+
+	if a.store != nil && session != nil {
+		model, err := corners.ModelFor(a.store, session)
+		if err != nil {
+			return err
+		}
+		a.model, a.store = model, nil
+	}
+
+	tick := brakingTick{
+		lap:         lap,
+		pct:         float64(pct),
+		dist:        float64(dist),
+		sessionTime: tickCtx.SessionTime,
+		speed:       float64(speed),
+		brake:       float64(brake),
+		brakeRaw:    float64(brakeRaw),
+		longAccel:   float64(longAccel),
+	}
+
+	if a.hasPrev && a.relocated(tick) {
+		// The car was relocated, so the event in progress can no longer be measured
+		a.current = nil
+	}
+
+	applied := tick.brake >= a.opts.Threshold
+
+	if a.current != nil {
+		switch {
+		case a.current.braking && applied:
+			a.current.update(tick, tick.sessionTime-a.prev.sessionTime)
+		case a.current.braking:
+			a.current.release(tick)
+		case applied || tick.speed > a.current.MinSpeed+recoverySpeed:
+			a.finish()
+		case tick.speed < a.current.MinSpeed:
+			a.current.MinSpeed, a.current.MinSpeedPct = tick.speed, tick.pct
+		}
+	}
+
+	if a.current == nil && applied {
+		a.start(tick)
+	}
+
+	a.prev = tick
+	a.hasPrev = true
+
+	if !hasNext {
+		a.finish()
+	}
+
+	return nil
+}
+
+// relocated indicates whether the car moved between the previous and given tick without driving
+func (a *Analyzer) relocated(tick brakingTick) bool {
+	step := tick.pct - a.prev.pct
+
+	if tick.lap == a.prev.lap+1 && step < -laps.MaxPctStep {
+		return false
+	}
+
+	return tick.lap != a.prev.lap || math.Abs(step) >= laps.MaxPctStep
+}
+
+// start a new braking event at the given tick
+func (a *Analyzer) start(tick brakingTick) {
+	a.current = &event{
+		Event: Event{
+			Stub:       a.stub.Index,
+			Filename:   a.stub.Filename,
+			Lap:        tick.lap,
+			Start:      tick.pct,
+			StartDist:  tick.dist,
+			StartTime:  tick.sessionTime,
+			EntrySpeed: tick.speed,
+			MinSpeed:   tick.speed,
+		},
+		braking: true,
+	}
+
+	a.current.update(tick, 0)
+}
+
+// finish the event in progress, keeping it when the brakes were applied long enough
+func (a *Analyzer) finish() {
+	e := a.current
+	a.current = nil
+
+	if e == nil {
+		return
+	}
+
+	if e.braking {
+		e.release(a.prev)
+	}
+
+	if e.Duration < a.opts.MinDuration {
+		return
+	}
+
+	if corner, ok := a.model.Approaching(e.MinSpeedPct, cornerLookahead); ok {
+		e.Corner = corner.Number
+
+		for _, sample := range e.samples {
+			if corner.Contains(sample.pct) {
+				e.TrailTime += sample.dt
+			}
+		}
+	}
+
+	e.Release = e.releaseProfile()
+	e.AvgDecel = e.decelSum / math.Max(e.Duration, math.SmallestNonzeroFloat64)
+
+	a.events = append(a.events, e.Event)
+}
+
+// update the event with a tick during which the brakes are applied, driven for dt seconds
+func (e *event) update(tick brakingTick, dt float64) {
+	e.samples = append(e.samples, brakeSample{time: tick.sessionTime - e.StartTime, dt: dt, pct: tick.pct, brake: tick.brake})
+
+	if tick.brake > e.PeakBrake {
+		e.PeakBrake = tick.brake
+		e.TimeToPeak = tick.sessionTime - e.StartTime
+		e.peakIdx = len(e.samples) - 1
+	}
+
+	e.PeakBrakeRaw = math.Max(e.PeakBrakeRaw, tick.brakeRaw)
+	e.MaxDecel = math.Max(e.MaxDecel, -tick.longAccel)
+	e.decelSum += -tick.longAccel * dt
+	e.Distance += tick.speed * dt
+
+	if tick.speed < e.MinSpeed {
+		e.MinSpeed, e.MinSpeedPct = tick.speed, tick.pct
+	}
+}
+
+// release the brakes of the event at the given tick
+func (e *event) release(tick brakingTick) {
+	e.braking = false
+	e.End = tick.pct
+	e.Duration = tick.sessionTime - e.StartTime
+	e.ReleaseTime = e.Duration - e.TimeToPeak
+
+	if tick.speed < e.MinSpeed {
+		e.MinSpeed, e.MinSpeedPct = tick.speed, tick.pct
+	}
+}
+
+// releaseProfile returns the brake pressure at equal intervals from the peak until the brakes were
+// released, which is 0 at the moment of release
+func (e *event) releaseProfile() []float64 {
+	samples := append(e.samples[e.peakIdx:], brakeSample{time: e.Duration})
+	profile := make([]float64, releasePoints)
+
+	idx := 0
+	for point := range profile {
+		t := e.TimeToPeak + e.ReleaseTime*float64(point)/float64(releasePoints-1)
+
+		for idx < len(samples)-2 && samples[idx+1].time < t {
+			idx++
+		}
+
+		from, to := samples[idx], samples[min(idx+1, len(samples)-1)]
+		if to.time > from.time {
+			profile[point] = from.brake + (to.brake-from.brake)*math.Max(0, math.Min(1, (t-from.time)/(to.time-from.time)))
+		} else {
+			profile[point] = from.brake
+		}
+	}
+
+	return profile
+}
+
+// Result of the braking events processed so far
+func (a *Analyzer) Result() Result {
+	return Result{Events: a.events, Corners: Compare(a.events)}
+}
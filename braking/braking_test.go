@@ -0,0 +1,235 @@
+package braking
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/corners"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/ibttest"
+)
+
+var testModel = corners.TrackModel{
+	TrackID:         1,
+	TrackConfigName: "test",
+	Corners: []corners.Corner{
+		{Number: 1, Entry: 0.23, Apex: 0.27, Exit: 0.31, Direction: corners.Right},
+		{Number: 2, Entry: 0.63, Apex: 0.67, Exit: 0.71, Direction: corners.Left},
+	},
+}
+
+var testSession = &headers.Session{WeekendInfo: headers.WeekendInfo{TrackID: 1, TrackConfigName: "test"}}
+
+// testBraking returns the speed, brake pressure and longitudinal acceleration of a braking zone starting at
+// the given position. The brakes are applied over 0.01 of the lap, held for 0.01 and released over 0.03,
+// after which the car reaches its minimum speed of 25 m/s 0.02 later.
+func testBraking(pct, start, peak float64) (float64, float64, float64) {
+	release, apex := start+0.05, start+0.07
+
+	speed := 60.0
+	switch {
+	case pct >= start && pct <= apex:
+		speed = 60 - 35*(pct-start)/(apex-start)
+	case pct > apex && pct <= apex+0.05:
+		speed = 25 + 35*(pct-apex)/0.05
+	}
+
+	switch {
+	case pct < start || pct >= release:
+		return speed, 0, 0
+	case pct < start+0.01:
+		return speed, peak * (pct - start) / 0.01, -15
+	case pct < start+0.02:
+		return speed, peak, -15
+	}
+
+	return speed, peak * (release - pct) / 0.03, -15
+}
+
+// testTicks creates the ticks of a car driving around a 1 km track from 0.9 of lap 1 until 0.9 of lap 4,
+// braking for each corner of the test model. The brakes are applied 0.01 later for the first corner of lap 3.
+func testTicks() []ibt.Tick {
+	ticks := make([]ibt.Tick, 0)
+	pos, sessionTime := 0.9, 10.0
+
+	for pos < 3.9 {
+		lap := 1 + int(math.Floor(pos))
+		pct := pos - math.Floor(pos)
+
+		first := 0.2
+		if lap == 3 {
+			first = 0.21
+		}
+
+		speed, brake, longAccel := testBraking(pct, first, 0.9)
+		if pct > 0.5 {
+			speed, brake, longAccel = testBraking(pct, 0.6, 0.8)
+		}
+
+		ticks = append(ticks, ibt.Tick{
+			"SessionTime": sessionTime,
+			"Lap":         lap,
+			"LapDistPct":  float32(pct),
+			"LapDist":     float32(pct * 1000),
+			"Speed":       float32(speed),
+			"Brake":       float32(brake),
+			"BrakeRaw":    float32(math.Min(1, brake+0.05)),
+			"LongAccel":   float32(longAccel),
+		})
+
+		pos += speed / 60 / 1000
+		sessionTime += 1.0 / 60
+	}
+
+	return ticks
+}
+
+var testStub = ibt.StubInfo{Index: 0, Filename: "test.ibt"}
+
+func TestAnalyzer(t *testing.T) {
+	t.Run("test Analyzer events", func(t *testing.T) {
+		analyzer := NewAnalyzer(testModel, Options{})
+		ibttest.Process(t, analyzer, testStub, testTicks(), testSession)
+
+		events := analyzer.Result().Events
+		if len(events) != 6 {
+			t.Errorf("expected %d events. received %d", 6, len(events))
+			return
+		}
+
+		for idx, e := range events {
+			if e.Lap != 2+idx/2 || e.Corner != 1+idx%2 || e.Filename != "test.ibt" {
+				t.Errorf("expected event %d to be for corner %d of lap %d. received corner %d of lap %d", idx, 1+idx%2, 2+idx/2, e.Corner, e.Lap)
+			}
+		}
+
+		e := events[0]
+		if math.Abs(e.Start-0.2) > 0.002 || math.Abs(e.StartDist-200) > 2 || math.Abs(e.End-0.25) > 0.002 {
+			t.Errorf("expected braking from %.2f to %.2f. received %.4f (%.1f m) to %.4f", 0.2, 0.25, e.Start, e.StartDist, e.End)
+		}
+
+		if math.Abs(e.PeakBrake-0.9) > 0.01 || math.Abs(e.PeakBrakeRaw-0.95) > 0.01 || e.TimeToPeak < 0.1 || e.TimeToPeak > 0.25 {
+			t.Errorf("expected a peak brake pressure of %.2f within %.2f seconds. received %+v", 0.9, 0.25, e)
+		}
+
+		if math.Abs(e.EntrySpeed-60) > 0.5 || math.Abs(e.MinSpeed-25) > 0.5 || math.Abs(e.MinSpeedPct-0.27) > 0.002 {
+			t.Errorf("expected a minimum speed of %d m/s at %.2f. received %.2f at %.4f", 25, 0.27, e.MinSpeed, e.MinSpeedPct)
+		}
+
+		if e.MaxDecel != 15 || math.Abs(e.AvgDecel-15) > 0.5 || math.Abs(e.Distance-(e.End-e.Start)*1000) > 1.5 {
+			t.Errorf("expected a deceleration of %d m/s^2 while braking. received %.2f and %.2f over %.2f meters", 15, e.MaxDecel, e.AvgDecel, e.Distance)
+		}
+
+		if math.Abs(e.Duration-e.TimeToPeak-e.ReleaseTime) > 0.0001 || e.TrailTime <= 0 || e.TrailTime >= e.ReleaseTime {
+			t.Errorf("expected the brakes to be trailed into the corner. received %+v", e)
+		}
+
+		if len(e.Release) != releasePoints || math.Abs(e.Release[0]-0.9) > 0.01 || e.Release[releasePoints-1] != 0 {
+			t.Errorf("expected the release to go from %.1f to %d. received %v", 0.9, 0, e.Release)
+		}
+
+		for idx := 1; idx < len(e.Release); idx++ {
+			if e.Release[idx] > e.Release[idx-1] {
+				t.Errorf("expected the brake pressure to decrease during the release. received %v", e.Release)
+				break
+			}
+		}
+	})
+
+	t.Run("test Analyzer without a model", func(t *testing.T) {
+		analyzer := NewAnalyzer(corners.TrackModel{}, Options{})
+		ibttest.Process(t, analyzer, testStub, testTicks(), testSession)
+
+		result := analyzer.Result()
+		if len(result.Events) != 6 || result.Events[0].Corner != 0 || result.Events[0].TrailTime != 0 || len(result.Corners) != 0 {
+			t.Errorf("expected events without corners. received %d events and %d corners", len(result.Events), len(result.Corners))
+		}
+	})
+
+	t.Run("test Analyzer minimum duration", func(t *testing.T) {
+		analyzer := NewAnalyzer(testModel, Options{MinDuration: 5})
+		ibttest.Process(t, analyzer, testStub, testTicks(), testSession)
+
+		if len(analyzer.Result().Events) != 0 {
+			t.Errorf("expected no events to last %d seconds. received %d", 5, len(analyzer.Result().Events))
+		}
+	})
+
+	t.Run("test Analyzer relocated car", func(t *testing.T) {
+		ticks := testTicks()
+		for _, tick := range ticks[:300] {
+			// The car is towed to the pits halfway through the first braking zone
+			if pct := tick["LapDistPct"].(float32); tick["Lap"] == 2 && pct > 0.22 {
+				tick["LapDistPct"] = pct - 0.6
+			}
+		}
+
+		analyzer := NewAnalyzer(testModel, Options{})
+		ibttest.Process(t, analyzer, testStub, ticks[:300], testSession)
+
+		if len(analyzer.Result().Events) != 0 {
+			t.Errorf("expected the event to be discarded. received %+v", analyzer.Result().Events)
+		}
+	})
+
+	t.Run("test Analyzer models from registry", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := corners.NewModelStore(dir).Save(testModel); err != nil {
+			t.Fatal(err)
+		}
+
+		processor, err := ibt.DefaultRegistry.NewProcessor("braking", ibt.Params{"models": filepath.Join(dir)})
+		if err != nil {
+			t.Errorf("expected NewProcessor() to run without err. received error: %v", err)
+			return
+		}
+
+		analyzer := processor.(*Analyzer)
+		ibttest.Process(t, analyzer, testStub, testTicks(), testSession)
+
+		if events := analyzer.Result().Events; len(events) != 6 || events[1].Corner != 2 {
+			t.Errorf("expected the events to be assigned to the corners of the stored model. received %+v", events)
+		}
+	})
+
+	t.Run("test Analyzer without a stored model", func(t *testing.T) {
+		if _, err := ibt.DefaultRegistry.NewProcessor("braking", ibt.Params{}); err == nil {
+			t.Error("expected NewProcessor() to return an error without a models directory")
+		}
+
+		analyzer := NewAnalyzer(corners.TrackModel{}, Options{Models: t.TempDir()})
+		if err := analyzer.Process(testTicks()[0], true, testSession); !errors.Is(err, corners.ErrNoModel) {
+			t.Errorf("expected Process() to return ErrNoModel. received error: %v", err)
+		}
+	})
+
+	t.Run("test Analyzer missing variables", func(t *testing.T) {
+		if err := NewAnalyzer(testModel, Options{}).Process(ibt.Tick{"Lap": 1, "LapDistPct": float32(0.1), "Speed": float32(10)}, true, nil); err == nil {
+			t.Error("expected Process() to return an error when Brake is missing")
+		}
+	})
+
+	t.Run("test Analyzer with ibt.Process", func(t *testing.T) {
+		stubs, err := ibt.ParseStubs("../.testing/valid_test_file.ibt")
+		if err != nil {
+			t.Errorf("failed to parse stubs for testing file - %v", err)
+			return
+		}
+		defer stubs.Close()
+
+		analyzer := NewAnalyzer(testModel, Options{})
+		if err := ibt.Process(context.Background(), stubs, analyzer); err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		for _, e := range analyzer.Result().Events {
+			if e.Lap != 9 || e.Duration < defaultMinDuration || e.PeakBrake < defaultThreshold {
+				t.Errorf("expected events of at least %.1f seconds during lap %d. received %+v", defaultMinDuration, 9, e)
+			}
+		}
+	})
+}
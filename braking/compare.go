@@ -0,0 +1,73 @@
+package braking
+
+import (
+	"math"
+	"sort"
+)
+
+// Spread of a value across the braking events of a corner
+type Spread struct {
+	Min  float64
+	Max  float64
+	Mean float64
+}
+
+// CornerBraking compares the braking events of a single corner across laps.
+type CornerBraking struct {
+	// Corner number, as assigned by the track model
+	Corner int
+	// Events of the corner in the order they occurred
+	Events []Event
+	// BrakePoint is the spread of the LapDist in meters where the brakes were applied
+	BrakePoint Spread
+	PeakBrake  Spread
+	TimeToPeak Spread
+	EntrySpeed Spread
+	MinSpeed   Spread
+	MaxDecel   Spread
+}
+
+// Compare groups the given events by corner, ordered by corner number. Events that were not assigned to
+// a corner are excluded.
+func Compare(events []Event) []CornerBraking {
+	byCorner := make(map[int][]Event)
+
+	for _, e := range events {
+		if e.Corner > 0 {
+			byCorner[e.Corner] = append(byCorner[e.Corner], e)
+		}
+	}
+
+	result := make([]CornerBraking, 0, len(byCorner))
+
+	for corner, events := range byCorner {
+		result = append(result, CornerBraking{
+			Corner:     corner,
+			Events:     events,
+			BrakePoint: spread(events, func(e Event) float64 { return e.StartDist }),
+			PeakBrake:  spread(events, func(e Event) float64 { return e.PeakBrake }),
+			TimeToPeak: spread(events, func(e Event) float64 { return e.TimeToPeak }),
+			EntrySpeed: spread(events, func(e Event) float64 { return e.EntrySpeed }),
+			MinSpeed:   spread(events, func(e Event) float64 { return e.MinSpeed }),
+			MaxDecel:   spread(events, func(e Event) float64 { return e.MaxDecel }),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Corner < result[j].Corner })
+
+	return result
+}
+
+// spread of the value of the given events
+func spread(events []Event, value func(Event) float64) Spread {
+	s := Spread{Min: math.Inf(1), Max: math.Inf(-1)}
+
+	for _, e := range events {
+		v := value(e)
+		s.Min = math.Min(s.Min, v)
+		s.Max = math.Max(s.Max, v)
+		s.Mean += v / float64(len(events))
+	}
+
+	return s
+}
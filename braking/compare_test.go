@@ -0,0 +1,42 @@
+package braking
+
+import (
+	"math"
+	"testing"
+
+	"github.com/teamjorge/ibt/ibttest"
+)
+
+func TestCompare(t *testing.T) {
+	t.Run("test Compare() corners", func(t *testing.T) {
+		analyzer := NewAnalyzer(testModel, Options{})
+		ibttest.Process(t, analyzer, testStub, testTicks(), testSession)
+
+		result := analyzer.Result().Corners
+		if len(result) != 2 || result[0].Corner != 1 || result[1].Corner != 2 {
+			t.Errorf("expected corners %d and %d. received %+v", 1, 2, result)
+			return
+		}
+
+		first := result[0]
+		if len(first.Events) != 3 || first.Events[1].Lap != 3 {
+			t.Errorf("expected an event of each lap. received %d events", len(first.Events))
+		}
+
+		// The brakes are applied 10 meters later for the first corner of lap 3
+		if brakePoint := first.BrakePoint; math.Abs(brakePoint.Max-brakePoint.Min-10) > 1 || brakePoint.Mean <= brakePoint.Min || brakePoint.Mean >= brakePoint.Max {
+			t.Errorf("expected the brake points to differ by %d meters. received %+v", 10, brakePoint)
+		}
+
+		second := result[1]
+		if second.PeakBrake.Min != second.PeakBrake.Max || math.Abs(second.PeakBrake.Mean-0.8) > 0.01 || second.MaxDecel.Mean != 15 {
+			t.Errorf("expected equal braking for corner %d. received %+v", 2, second)
+		}
+	})
+
+	t.Run("test Compare() unassigned events", func(t *testing.T) {
+		if result := Compare([]Event{{Corner: 0}, {Corner: 3, MinSpeed: 20}, {Corner: 3, MinSpeed: 30}}); len(result) != 1 || result[0].MinSpeed.Mean != 25 {
+			t.Errorf("expected a single corner with a mean minimum speed of %d. received %+v", 25, result)
+		}
+	})
+}
@@ -0,0 +1,197 @@
+package ibt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// Default number of ticks between checkpoints
+	defaultCheckpointInterval int = 10000
+)
+
+// Checkpoint is the position within a StubGroup up to which all ticks have been processed.
+type Checkpoint struct {
+	// Filename of the stub
+	Filename string `json:"filename"`
+	// StartDate of the stub. This is used along with the filename to identify the stub.
+	StartDate int64 `json:"start_date"`
+	// Tick is the index of the last tick processed within the stub
+	Tick int `json:"tick"`
+	// Complete indicates that all ticks of the stub were processed
+	Complete bool `json:"complete"`
+}
+
+// Checkpointer records the progress of processing so that it can be resumed at a later stage.
+//
+// A Checkpointer should only be used for a single StubGroup.
+type Checkpointer interface {
+	// Load the last saved checkpoint. False is returned when no checkpoint has been saved.
+	Load() (Checkpoint, bool, error)
+	// Save the given checkpoint
+	Save(checkpoint Checkpoint) error
+}
+
+// Flusher is implemented by processors that buffer ticks before storing them.
+//
+// Flush is called before every checkpoint is saved. Once Flush returns, all ticks received by the
+// processor should be stored. This ensures that no ticks are lost when processing is resumed.
+type Flusher interface {
+	Flush() error
+}
+
+// FileCheckpointer stores checkpoints as JSON in a file.
+type FileCheckpointer struct {
+	path string
+}
+
+// NewFileCheckpointer creates a FileCheckpointer that stores checkpoints in the given file.
+func NewFileCheckpointer(path string) *FileCheckpointer {
+	return &FileCheckpointer{path: path}
+}
+
+// Load the checkpoint from the file. False is returned when the file does not exist.
+func (f *FileCheckpointer) Load() (Checkpoint, bool, error) {
+	var checkpoint Checkpoint
+
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoint, false, nil
+	}
+	if err != nil {
+		return checkpoint, false, fmt.Errorf("failed to read checkpoint file %s: %v", f.path, err)
+	}
+
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return checkpoint, false, fmt.Errorf("failed to parse checkpoint file %s: %v", f.path, err)
+	}
+
+	return checkpoint, true, nil
+}
+
+// Save the checkpoint to the file.
+//
+// The checkpoint is written to a temporary file before replacing the existing file, ensuring that
+// a previous checkpoint is never partially overwritten.
+func (f *FileCheckpointer) Save(checkpoint Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary checkpoint file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint file: %v", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync checkpoint file: %v", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close checkpoint file: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to replace checkpoint file %s: %v", f.path, err)
+	}
+
+	return nil
+}
+
+// Remove the checkpoint file. This is useful once a StubGroup has been fully processed and should
+// be processed from the start again in future.
+func (f *FileCheckpointer) Remove() error {
+	if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove checkpoint file %s: %v", f.path, err)
+	}
+
+	return nil
+}
+
+// resumePoint is the stub and tick index from where processing should start.
+type resumePoint struct {
+	stub int
+	tick int
+}
+
+// resume determines where processing should start from the last saved checkpoint.
+//
+// Processing starts from the beginning when no checkpoint exists or the stub of the
+// checkpoint is not part of the given group.
+func (r *runner) resume(stubs StubGroup) (resumePoint, error) {
+	if r.opts.Checkpointer == nil {
+		return resumePoint{}, nil
+	}
+
+	checkpoint, ok, err := r.opts.Checkpointer.Load()
+	if err != nil {
+		return resumePoint{}, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	if !ok {
+		return resumePoint{}, nil
+	}
+
+	for idx, stub := range stubs {
+		if stub.Filename() != checkpoint.Filename || stub.header.DiskHeader.StartDate != checkpoint.StartDate {
+			continue
+		}
+
+		r.report.ResumedFrom = &checkpoint
+
+		if checkpoint.Complete {
+			return resumePoint{stub: idx + 1}, nil
+		}
+
+		return resumePoint{stub: idx, tick: checkpoint.Tick + 1}, nil
+	}
+
+	return resumePoint{}, nil
+}
+
+// checkpoint flushes all processors and saves the given checkpoint.
+//
+// The checkpoint is not saved if any of the processors failed to flush, or when a stub was skipped. As a
+// checkpoint is a single position, saving one after a skipped stub would prevent it from being processed
+// again when resuming.
+func (r *runner) checkpoint(checkpoint Checkpoint) error {
+	r.sinceCheckpoint = 0
+	if r.skipped {
+		return nil
+	}
+
+	flushed := true
+	for _, proc := range r.processors {
+		if flusher, ok := proc.(Flusher); ok {
+			if err := flusher.Flush(); err != nil {
+				flushed = false
+				processErr := &ProcessError{Filename: checkpoint.Filename, Tick: -1, Processor: proc, Err: err}
+				if err := r.record(processErr); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if !flushed {
+		return nil
+	}
+
+	if err := r.opts.Checkpointer.Save(checkpoint); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+
+	r.report.Checkpoints++
+
+	return nil
+}
@@ -0,0 +1,397 @@
+package ibt
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/teamjorge/ibt/headers"
+)
+
+type testCheckpointer struct {
+	checkpoint Checkpoint
+	ok         bool
+	saved      []Checkpoint
+}
+
+func (t *testCheckpointer) Load() (Checkpoint, bool, error) { return t.checkpoint, t.ok, nil }
+
+func (t *testCheckpointer) Save(checkpoint Checkpoint) error {
+	t.checkpoint = checkpoint
+	t.ok = true
+	t.saved = append(t.saved, checkpoint)
+
+	return nil
+}
+
+type testFlushProcessor struct {
+	testContextProcessor
+	flushed  int
+	flushErr error
+	failAt   int
+}
+
+func (t *testFlushProcessor) ProcessContext(input Tick, tickCtx TickContext, hasNext bool, session *headers.Session) error {
+	if t.failAt > 0 && tickCtx.Index == t.failAt {
+		return errors.New("unit test crash")
+	}
+
+	return t.testContextProcessor.ProcessContext(input, tickCtx, hasNext, session)
+}
+
+func (t *testFlushProcessor) Flush() error {
+	t.flushed++
+
+	return t.flushErr
+}
+
+// testSeenProcessor records the ticks contributing to the ticks it receives from the Seen variable, which
+// marks the index of each tick within the stub.
+type testSeenProcessor struct {
+	rate      float64
+	failAt    int
+	delivered int
+	seen      map[int]bool
+}
+
+func (t *testSeenProcessor) Process(input Tick, hasNext bool, session *headers.Session) error {
+	t.delivered++
+	if t.failAt > 0 && t.delivered == t.failAt {
+		return errors.New("unit test crash")
+	}
+
+	seen, _ := GetTickValue[[]float64](input, "Seen")
+	for idx, value := range seen {
+		if value > 0 {
+			t.seen[idx] = true
+		}
+	}
+
+	return nil
+}
+
+func (t *testSeenProcessor) Whitelist() []string { return []string{"SessionTime"} }
+
+func (t *testSeenProcessor) Rate() float64 { return t.rate }
+
+func (t *testSeenProcessor) Decimation() Decimation { return DecimateMean }
+
+func TestCheckpoint(t *testing.T) {
+	f, err := os.Open(".testing/valid_test_file.ibt")
+	if err != nil {
+		t.Errorf("failed to open testing file - %v", err)
+		return
+	}
+	defer f.Close()
+
+	testHeaders, err := headers.ParseHeaders(f)
+	if err != nil {
+		t.Errorf("failed to parse header for testing file - %v", err)
+		return
+	}
+
+	stubs := StubGroup{
+		{filepath: ".testing/valid_test_file.ibt", header: testHeaders, r: f},
+	}
+
+	t.Run("test Process() saves checkpoints", func(t *testing.T) {
+		checkpointer := testCheckpointer{}
+		proc := testFlushProcessor{}
+
+		opts := ProcessOptions{Checkpointer: &checkpointer, CheckpointInterval: 100}
+		report, err := ProcessWithOptions(context.Background(), stubs, opts, &proc)
+		if err != nil {
+			t.Errorf("expected ProcessWithOptions() to run without err. received error: %v", err)
+		}
+
+		if len(checkpointer.saved) != 4 || report.Checkpoints != 4 || proc.flushed != 4 {
+			t.Errorf("expected %d checkpoints to be saved and flushed. received %d saved, %d reported and %d flushed",
+				4, len(checkpointer.saved), report.Checkpoints, proc.flushed)
+			return
+		}
+
+		expectedFirst := Checkpoint{Filename: ".testing/valid_test_file.ibt", StartDate: 1719258336, Tick: 99}
+		if checkpointer.saved[0] != expectedFirst {
+			t.Errorf("expected first checkpoint to be %+v. received %+v", expectedFirst, checkpointer.saved[0])
+		}
+
+		if !checkpointer.saved[3].Complete {
+			t.Errorf("expected final checkpoint to mark the stub as complete. received %+v", checkpointer.saved[3])
+		}
+	})
+
+	t.Run("test Process() resumes after a crash", func(t *testing.T) {
+		checkpointer := testCheckpointer{}
+		crashing := testFlushProcessor{failAt: 250}
+
+		opts := ProcessOptions{Checkpointer: &checkpointer, CheckpointInterval: 100}
+		if _, err := ProcessWithOptions(context.Background(), stubs, opts, &crashing); err == nil {
+			t.Error("expected ProcessWithOptions() to return the processor error")
+		}
+
+		if checkpointer.checkpoint.Tick != 199 {
+			t.Errorf("expected last checkpoint to be at tick %d. received %+v", 199, checkpointer.checkpoint)
+		}
+
+		resumed := testFlushProcessor{}
+		report, err := ProcessWithOptions(context.Background(), stubs, opts, &resumed)
+		if err != nil {
+			t.Errorf("expected ProcessWithOptions() to run without err. received error: %v", err)
+		}
+
+		if report.ResumedFrom == nil || report.ResumedFrom.Tick != 199 {
+			t.Errorf("expected report to indicate processing resumed from tick %d. received %+v", 199, report.ResumedFrom)
+		}
+
+		if len(resumed.contexts) != 189 || resumed.contexts[0].Index != 200 {
+			t.Errorf("expected processing to resume from tick %d. received %d ticks", 200, len(resumed.contexts))
+			return
+		}
+
+		if resumed.contexts[0].SessionTime != crashing.contexts[200].SessionTime {
+			t.Errorf("expected resumed tick to have session time %f. received %f", crashing.contexts[200].SessionTime, resumed.contexts[0].SessionTime)
+		}
+	})
+
+	t.Run("test Process() resumes from a complete stub", func(t *testing.T) {
+		checkpointer := testCheckpointer{
+			checkpoint: Checkpoint{Filename: ".testing/valid_test_file.ibt", StartDate: 1719258336, Complete: true},
+			ok:         true,
+		}
+		proc := testHookProcessor{}
+
+		opts := ProcessOptions{Checkpointer: &checkpointer}
+		if _, err := ProcessWithOptions(context.Background(), stubs, opts, &proc); err != nil {
+			t.Errorf("expected ProcessWithOptions() to run without err. received error: %v", err)
+		}
+
+		if len(proc.events) != 1 || proc.events[0] != "finish" {
+			t.Errorf("expected completed stubs to be skipped. received events %v", proc.events)
+		}
+	})
+
+	t.Run("test Process() with unknown checkpoint stub", func(t *testing.T) {
+		checkpointer := testCheckpointer{checkpoint: Checkpoint{Filename: "other.ibt", Tick: 200}, ok: true}
+		proc := testProcessor{}
+
+		report, err := ProcessWithOptions(context.Background(), stubs, ProcessOptions{Checkpointer: &checkpointer}, &proc)
+		if err != nil {
+			t.Errorf("expected ProcessWithOptions() to run without err. received error: %v", err)
+		}
+
+		if len(proc.results) != 389 || report.ResumedFrom != nil {
+			t.Errorf("expected processing to start from the beginning. received %d ticks", len(proc.results))
+		}
+	})
+
+	t.Run("test Process() flush error", func(t *testing.T) {
+		checkpointer := testCheckpointer{}
+		proc := testFlushProcessor{flushErr: errors.New("unit test flush error")}
+
+		opts := ProcessOptions{Checkpointer: &checkpointer, CheckpointInterval: 100, ErrorPolicy: CollectAll}
+		report, err := ProcessWithOptions(context.Background(), stubs, opts, &proc)
+		if err != nil {
+			t.Errorf("expected ProcessWithOptions() to run without err. received error: %v", err)
+		}
+
+		if len(checkpointer.saved) != 0 || len(report.Errors) != 4 {
+			t.Errorf("expected no checkpoints to be saved and %d errors. received %d checkpoints and %d errors",
+				4, len(checkpointer.saved), len(report.Errors))
+		}
+	})
+
+	t.Run("test Process() checkpoint excludes buffered ticks", func(t *testing.T) {
+		checkpointer := testCheckpointer{}
+		proc := testProcessor{}
+
+		opts := ProcessOptions{Checkpointer: &checkpointer, CheckpointInterval: 100}
+		if _, err := ProcessWithOptions(context.Background(), stubs, opts, Pipeline().Reduce(60).To(&proc)); err != nil {
+			t.Errorf("expected ProcessWithOptions() to run without err. received error: %v", err)
+		}
+
+		// 40 of the first 100 ticks are held by the reduce stage
+		if checkpointer.saved[0].Tick != 59 {
+			t.Errorf("expected first checkpoint to be at the last processed tick %d. received %+v", 59, checkpointer.saved[0])
+		}
+	})
+
+	t.Run("test Process() resumes a pipeline without losing ticks", func(t *testing.T) {
+		contexts := testContextProcessor{}
+		if err := Process(context.Background(), stubs, &contexts); err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+			return
+		}
+
+		// The final tick of the testing file repeats the session time of the tick before it
+		indices := make(map[float64]int)
+		for _, tickCtx := range contexts.contexts {
+			indices[tickCtx.SessionTime] = tickCtx.Index
+		}
+
+		// Every third tick is filtered and each tick is marked with its index before being averaged by the
+		// reduce stage and decimation, so each tick received by the processor marks all of the ticks it contains
+		pipeline := func(proc Processor) Processor {
+			return Pipeline().
+				Filter(func(input Tick, session *headers.Session) bool {
+					sessionTime, _ := GetTickValue[float64](input, "SessionTime")
+					return indices[sessionTime]%3 != 0
+				}, "SessionTime").
+				Map(func(input Tick, session *headers.Session) Tick {
+					sessionTime, _ := GetTickValue[float64](input, "SessionTime")
+					seen := make([]float64, len(contexts.contexts))
+					seen[indices[sessionTime]] = 1
+					input["Seen"] = seen
+					return input
+				}).
+				Reduce(4).
+				To(proc)
+		}
+
+		checkpointer := testCheckpointer{}
+		opts := ProcessOptions{Checkpointer: &checkpointer, CheckpointInterval: 25}
+
+		crashing := testSeenProcessor{rate: 20, failAt: 10, seen: make(map[int]bool)}
+		if _, err := ProcessWithOptions(context.Background(), stubs, opts, pipeline(&crashing)); err == nil {
+			t.Error("expected ProcessWithOptions() to return the processor error")
+			return
+		}
+
+		resumed := testSeenProcessor{rate: 20, seen: crashing.seen}
+		if _, err := ProcessWithOptions(context.Background(), stubs, opts, pipeline(&resumed)); err != nil {
+			t.Errorf("expected ProcessWithOptions() to run without err. received error: %v", err)
+			return
+		}
+
+		for _, idx := range indices {
+			if idx%3 != 0 && !resumed.seen[idx] {
+				t.Errorf("expected tick %d to be processed before or after resuming. received checkpoints %+v", idx, checkpointer.saved)
+				break
+			}
+		}
+	})
+
+	t.Run("test Process() does not complete skipped stubs", func(t *testing.T) {
+		checkpointer := testCheckpointer{}
+		proc := testFailingHookProcessor{}
+
+		opts := ProcessOptions{Checkpointer: &checkpointer, ErrorPolicy: SkipStub}
+		report, err := ProcessWithOptions(context.Background(), stubs, opts, &proc)
+		if err != nil {
+			t.Errorf("expected ProcessWithOptions() to run without err. received error: %v", err)
+		}
+
+		if report.SkippedStubs != 1 || len(checkpointer.saved) != 0 {
+			t.Errorf("expected the skipped stub not to be checkpointed. received %d skipped and %+v", report.SkippedStubs, checkpointer.saved)
+		}
+	})
+
+	t.Run("test Process() resumes past a skipped stub", func(t *testing.T) {
+		// Three consecutive stubs, of which the second is skipped
+		group := make(StubGroup, 0, 3)
+		for idx := 0; idx < 3; idx++ {
+			header, err := headers.ParseHeaders(f)
+			if err != nil {
+				t.Errorf("failed to parse header for testing file - %v", err)
+				return
+			}
+			header.DiskHeader.StartDate += int64(idx)
+			group = append(group, Stub{filepath: ".testing/valid_test_file.ibt", header: header, r: f})
+		}
+
+		checkpointer := testCheckpointer{}
+		failing := testSkipProcessor{skip: 1}
+
+		opts := ProcessOptions{Checkpointer: &checkpointer, CheckpointInterval: 100, ErrorPolicy: SkipStub}
+		report, err := ProcessWithOptions(context.Background(), group, opts, &failing)
+		if err != nil {
+			t.Errorf("expected ProcessWithOptions() to run without err. received error: %v", err)
+		}
+
+		last := checkpointer.checkpoint
+		if report.SkippedStubs != 1 || !last.Complete || last.StartDate != testHeaders.DiskHeader.StartDate {
+			t.Errorf("expected the last checkpoint to complete the first stub. received %+v", last)
+		}
+
+		resumed := testContextProcessor{}
+		if _, err := ProcessWithOptions(context.Background(), group, opts, &resumed); err != nil {
+			t.Errorf("expected ProcessWithOptions() to run without err. received error: %v", err)
+		}
+
+		if len(resumed.contexts) != 2*389 || resumed.contexts[0].Stub.Index != 1 {
+			t.Errorf("expected processing to resume from the skipped stub. received %d ticks", len(resumed.contexts))
+		}
+	})
+}
+
+// testSkipProcessor fails at the start of the stub with the given index
+type testSkipProcessor struct {
+	testProcessor
+	skip int
+}
+
+func (t *testSkipProcessor) StartStub(stub StubInfo) error {
+	if stub.Index == t.skip {
+		return errors.New("unit test error")
+	}
+
+	return nil
+}
+
+func TestFileCheckpointer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	checkpointer := NewFileCheckpointer(path)
+
+	t.Run("test FileCheckpointer Load() without file", func(t *testing.T) {
+		_, ok, err := checkpointer.Load()
+		if err != nil || ok {
+			t.Errorf("expected no checkpoint and no error when the file does not exist. received %v and %v", ok, err)
+		}
+	})
+
+	t.Run("test FileCheckpointer Save() and Load()", func(t *testing.T) {
+		expected := Checkpoint{Filename: "test.ibt", StartDate: 1719258336, Tick: 1234}
+
+		if err := checkpointer.Save(expected); err != nil {
+			t.Errorf("expected Save() to run without err. received error: %v", err)
+		}
+
+		checkpoint, ok, err := checkpointer.Load()
+		if err != nil || !ok {
+			t.Errorf("expected a checkpoint to be loaded. received %v and %v", ok, err)
+		}
+
+		if checkpoint != expected {
+			t.Errorf("expected loaded checkpoint to be %+v. received %+v", expected, checkpoint)
+		}
+
+		entries, _ := os.ReadDir(filepath.Dir(path))
+		if len(entries) != 1 {
+			t.Errorf("expected temporary checkpoint files to be removed. found %d files", len(entries))
+		}
+	})
+
+	t.Run("test FileCheckpointer invalid file", func(t *testing.T) {
+		invalidPath := filepath.Join(t.TempDir(), "invalid.json")
+		os.WriteFile(invalidPath, []byte("{not json"), 0o644)
+
+		if _, _, err := NewFileCheckpointer(invalidPath).Load(); err == nil {
+			t.Error("expected Load() to return an error for an invalid checkpoint file")
+		}
+	})
+
+	t.Run("test FileCheckpointer Remove()", func(t *testing.T) {
+		if err := checkpointer.Remove(); err != nil {
+			t.Errorf("expected Remove() to run without err. received error: %v", err)
+		}
+
+		if _, ok, _ := checkpointer.Load(); ok {
+			t.Error("expected no checkpoint after Remove()")
+		}
+
+		if err := checkpointer.Remove(); err != nil {
+			t.Errorf("expected Remove() of a missing file to run without err. received error: %v", err)
+		}
+	})
+}
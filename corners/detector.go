@@ -0,0 +1,474 @@
+// Package corners detects the corners of a track and describes its layout as a TrackModel.
+//
+// A Detector profiles the speed, steering and lateral acceleration of a clean lap over LapDistPct. Corners
+// are the parts of the lap where the car is steered and loaded laterally in the same direction, with their
+// apex at the minimum speed through the corner. Models can be stored in a ModelStore, allowing analyses of
+// other files driven on the same layout to reuse them.
+package corners
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/laps"
+)
+
+const (
+	// Default number of bins the lap is divided into
+	defaultResolution int = 1000
+	// Default minimum lateral acceleration in meters per second squared of a corner
+	defaultMinLatAccel float64 = 4
+	// Default minimum steering wheel angle in radians of a corner
+	defaultMinSteering float64 = 0.05
+	// Default minimum length of a corner as a fraction of the lap
+	defaultMinLength float64 = 0.002
+	// Default largest gap as a fraction of the lap between two parts of the same corner
+	defaultMergeGap float64 = 0.005
+	// Minimum fraction of the bins of a lap that need to be driven for it to be used
+	minCoverage float64 = 0.97
+	// Fraction of the lap on either side of a bin that is averaged when smoothing the profile
+	smoothing float64 = 0.004
+)
+
+// ErrNoLap is returned when no clean lap was driven from which the corners can be detected.
+var ErrNoLap = errors.New("no clean lap available for detecting corners")
+
+func init() {
+	ibt.Register("corners", func(params ibt.Params) (ibt.Processor, error) {
+		var opts Options
+		if err := params.Decode(&opts); err != nil {
+			return nil, err
+		}
+
+		return NewDetector(opts), nil
+	})
+}
+
+// Options configures the detection of corners.
+type Options struct {
+	// Lap to detect the corners from. Defaults to the fastest clean lap.
+	Lap int `yaml:"lap"`
+	// Resolution is the number of bins the lap is divided into. Defaults to 1000.
+	Resolution int `yaml:"resolution"`
+	// MinLatAccel is the minimum lateral acceleration in meters per second squared of a corner. Defaults to 4.
+	MinLatAccel float64 `yaml:"min_lat_accel"`
+	// MinSteering is the minimum steering wheel angle in radians of a corner. Defaults to 0.05.
+	MinSteering float64 `yaml:"min_steering"`
+	// MinLength is the minimum length of a corner as a fraction of the lap. Defaults to 0.002.
+	MinLength float64 `yaml:"min_length"`
+	// MergeGap is the largest gap as a fraction of the lap between two parts of a corner turning in the
+	// same direction, which are then treated as a single corner. Defaults to 0.005.
+	MergeGap float64 `yaml:"merge_gap"`
+}
+
+// Detector is a processor that detects the corners of the track from a clean lap.
+//
+// A lap is clean when it is driven from crossing the line until crossing it again without entering pit
+// road. Only the profile of the current and fastest clean lap are kept, regardless of the number of laps
+// processed.
+type Detector struct {
+	opts  Options
+	track TrackModel
+	best  *profile
+
+	stub    ibt.StubInfo
+	current *profile
+	prev    detectorTick
+}
+
+// detectorTick is the part of a tick used for detecting corners
+type detectorTick struct {
+	lap         int
+	pct         float32
+	sessionTime float64
+	speed       float64
+	steering    float64
+	latAccel    float64
+	onPitRoad   bool
+}
+
+// profile is the sum of the values of a lap in each bin of the lap
+type profile struct {
+	stub      int
+	lap       int
+	started   bool
+	pitted    bool
+	startTime float64
+	time      float64
+	bins      []bin
+}
+
+// bin is the sum of the values of the ticks within a bin of the lap
+type bin struct {
+	speed    float64
+	steering float64
+	latAccel float64
+	count    int
+}
+
+// NewDetector creates a corner Detector with the given options.
+func NewDetector(opts Options) *Detector {
+	if opts.Resolution <= 0 {
+		opts.Resolution = defaultResolution
+	}
+	if opts.MinLatAccel <= 0 {
+		opts.MinLatAccel = defaultMinLatAccel
+	}
+	if opts.MinSteering <= 0 {
+		opts.MinSteering = defaultMinSteering
+	}
+	if opts.MinLength <= 0 {
+		opts.MinLength = defaultMinLength
+	}
+	if opts.MergeGap <= 0 {
+		opts.MergeGap = defaultMergeGap
+	}
+
+	return &Detector{opts: opts}
+}
+
+// FromStubs detects the corners of the given stubs.
+//
+// The detected corners are not validated against the number of turns of the track, which can be done
+// with TrackModel.Validate.
+func FromStubs(ctx context.Context, stubs ibt.StubGroup, opts Options) (TrackModel, error) {
+	detector := NewDetector(opts)
+
+	if err := ibt.Process(ctx, stubs, detector); err != nil {
+		return TrackModel{}, err
+	}
+
+	return detector.Model()
+}
+
+// Whitelist of the variables required for detecting corners
+func (d *Detector) Whitelist() []string {
+	return []string{"Lap", "LapDistPct", "Speed", "SteeringWheelAngle", "LatAccel", "OnPitRoad"}
+}
+
+// StartStub discards the lap in progress of the previous stub
+func (d *Detector) StartStub(stub ibt.StubInfo) error {
+	d.stub = stub
+	d.current = nil
+
+	return nil
+}
+
+// Process the tick without a TickContext. The SessionTime of the tick determines the lap times used for
+// finding the fastest clean lap.
+func (d *Detector) Process(input ibt.Tick, hasNext bool, session *headers.Session) error {
+	return d.ProcessContext(input, ibt.ContextFromTick(input), hasNext, session)
+}
+
+// ProcessContext adds the tick to the profile of its lap, keeping the profile of the previous lap when it
+// is the fastest clean lap so far
+func (d *Detector) ProcessContext(input ibt.Tick, tickCtx ibt.TickContext, hasNext bool, session *headers.Session) error {
+	lap, err := ibt.GetTickValue[int](input, "Lap")
+	if err != nil {
+		return err
+	}
+
+	pct, err := ibt.GetTickValue[float32](input, "LapDistPct")
+	if err != nil {
+		return err
+	}
+
+	speed, err := ibt.GetTickValue[float32](input, "Speed")
+	if err != nil {
+		return err
+	}
+
+	steering, err := ibt.GetTickValue[float32](input, "SteeringWheelAngle")
+	if err != nil {
+		return err
+	}
+
+	latAccel, err := ibt.GetTickValue[float32](input, "LatAccel")
+	if err != nil {
+		return err
+	}
+
+	onPitRoad, _ := ibt.GetTickValue[bool](input, "OnPitRoad")
+
+	if d.track.TrackID == 0 && session != nil {
+		d.track = newTrackModel(session)
+	}
+
+	tick := detectorTick{
+		lap:         lap,
+		pct:         pct,
+		sessionTime: tickCtx.SessionTime,
+		speed:       float64(speed),
+		steering:    float64(steering),
+		latAccel:    float64(latAccel),
+		onPitRoad:   onPitRoad,
+	}
+
+	step := tick.pct - d.prev.pct
+
+	switch {
+	case d.current == nil:
+		d.current = d.newProfile(tick.lap, false, 0)
+	case tick.lap == d.current.lap+1 && step < -laps.MaxPctStep:
+		// The crossing of the line is interpolated between the ticks on either side of it
+		before, after := 1-float64(d.prev.pct), float64(tick.pct)
+		crossing := d.prev.sessionTime
+		if before+after > 0 {
+			crossing += (tick.sessionTime - d.prev.sessionTime) * before / (before + after)
+		}
+
+		d.complete(crossing)
+		d.current = d.newProfile(tick.lap, true, crossing)
+	case tick.lap != d.current.lap || step >= laps.MaxPctStep || step <= -laps.MaxPctStep:
+		// The car was relocated, so the lap can no longer be used
+		d.current = d.newProfile(tick.lap, false, 0)
+	}
+
+	d.current.add(tick)
+	d.prev = tick
+
+	return nil
+}
+
+// newProfile creates the profile of the given lap
+func (d *Detector) newProfile(lap int, started bool, startTime float64) *profile {
+	return &profile{
+		stub:      d.stub.Index,
+		lap:       lap,
+		started:   started,
+		startTime: startTime,
+		bins:      make([]bin, d.opts.Resolution),
+	}
+}
+
+// complete the current lap at the given time, keeping it if it is the fastest clean lap
+func (d *Detector) complete(endTime float64) {
+	p := d.current
+	if !p.started || p.pitted || (d.opts.Lap > 0 && p.lap != d.opts.Lap) || p.coverage() < minCoverage {
+		return
+	}
+
+	p.time = endTime - p.startTime
+	if d.best == nil || p.time < d.best.time {
+		d.best = p
+	}
+}
+
+// Model of the track, detected from the fastest clean lap processed so far.
+//
+// ErrNoLap is returned when no clean lap was processed.
+func (d *Detector) Model() (TrackModel, error) {
+	model := d.track
+
+	if d.best == nil {
+		return model, ErrNoLap
+	}
+
+	speed, steering, latAccel := d.best.values()
+	n := len(speed)
+
+	for _, z := range d.zones(smooth(steering), smooth(latAccel)) {
+		corner := Corner{Direction: z.direction, MinSpeed: math.Inf(1)}
+		apex := z.start
+
+		for i := z.start; i <= z.end; i++ {
+			if s := speed[i%n]; s < corner.MinSpeed {
+				corner.MinSpeed = s
+				apex = i
+			}
+			corner.PeakLatAccel = math.Max(corner.PeakLatAccel, math.Abs(latAccel[i%n]))
+		}
+
+		corner.Entry = fraction(float64(z.start) / float64(n))
+		corner.Apex = fraction((float64(apex) + 0.5) / float64(n))
+		corner.Exit = fraction(float64(z.end+1) / float64(n))
+		model.Corners = append(model.Corners, corner)
+	}
+
+	sort.Slice(model.Corners, func(i, j int) bool { return model.Corners[i].Apex < model.Corners[j].Apex })
+
+	for idx := range model.Corners {
+		model.Corners[idx].Number = idx + 1
+
+		corner, next := model.Corners[idx], model.Corners[(idx+1)%len(model.Corners)]
+		if corner.Exit != next.Entry {
+			model.Straights = append(model.Straights, Straight{Start: corner.Exit, End: next.Entry})
+		}
+	}
+
+	return model, nil
+}
+
+// Result returns the Model of the track, which contains no corners when no clean lap was processed
+func (d *Detector) Result() TrackModel {
+	model, _ := d.Model()
+
+	return model
+}
+
+// zone is a range of bins turning in the same direction. The end may exceed the number of bins for zones
+// spanning the start/finish line.
+type zone struct {
+	start, end int
+	direction  Direction
+}
+
+// zones of the lap in which the car is steered and loaded laterally
+func (d *Detector) zones(steering, latAccel []float64) []zone {
+	n := len(steering)
+
+	directions := make([]Direction, n)
+	for i := range directions {
+		if math.Abs(latAccel[i]) >= d.opts.MinLatAccel && math.Abs(steering[i]) >= d.opts.MinSteering {
+			// A positive steering wheel angle turns the car to the left
+			directions[i] = Right
+			if steering[i] > 0 {
+				directions[i] = Left
+			}
+		}
+	}
+
+	// Bins are scanned from the middle of the longest straight, so that no zone is split by the scan
+	start, ok := longestStraight(directions)
+	if !ok {
+		return nil
+	}
+
+	zones := make([]zone, 0)
+	gap := int(d.opts.MergeGap * float64(n))
+
+	for i := start + 1; i < start+n; i++ {
+		direction := directions[i%n]
+		if direction == "" {
+			continue
+		}
+
+		if last := len(zones) - 1; last >= 0 && zones[last].direction == direction && i-zones[last].end-1 <= gap {
+			zones[last].end = i
+			continue
+		}
+
+		zones = append(zones, zone{start: i, end: i, direction: direction})
+	}
+
+	minLength := int(math.Max(1, d.opts.MinLength*float64(n)))
+	filtered := zones[:0]
+
+	for _, z := range zones {
+		if z.end-z.start+1 >= minLength {
+			filtered = append(filtered, z)
+		}
+	}
+
+	return filtered
+}
+
+// longestStraight returns the middle of the longest run of bins without a direction. False is returned
+// when every bin has a direction.
+func longestStraight(directions []Direction) (int, bool) {
+	n := len(directions)
+	bestStart, bestLength, runStart, runLength := 0, 0, 0, 0
+
+	for i := 0; i < 2*n && runLength < n; i++ {
+		if directions[i%n] != "" {
+			runLength = 0
+			continue
+		}
+
+		if runLength == 0 {
+			runStart = i
+		}
+		runLength++
+
+		if runLength > bestLength {
+			bestStart, bestLength = runStart, runLength
+		}
+	}
+
+	return (bestStart + bestLength/2) % n, bestLength > 0
+}
+
+// add the values of the tick to its bin
+func (p *profile) add(tick detectorTick) {
+	idx := int(float64(tick.pct) * float64(len(p.bins)))
+	idx = max(0, min(len(p.bins)-1, idx))
+
+	b := &p.bins[idx]
+	b.speed += tick.speed
+	b.steering += tick.steering
+	b.latAccel += tick.latAccel
+	b.count++
+
+	if tick.onPitRoad {
+		p.pitted = true
+	}
+}
+
+// coverage is the fraction of bins that contain values
+func (p *profile) coverage() float64 {
+	filled := 0
+	for _, b := range p.bins {
+		if b.count > 0 {
+			filled++
+		}
+	}
+
+	return float64(filled) / float64(len(p.bins))
+}
+
+// values returns the average speed, steering and lateral acceleration of each bin. Values of empty bins
+// are interpolated between the nearest bins on either side.
+func (p *profile) values() (speed, steering, latAccel []float64) {
+	n := len(p.bins)
+	speed, steering, latAccel = make([]float64, n), make([]float64, n), make([]float64, n)
+
+	filled := make([]int, 0, n)
+	for i, b := range p.bins {
+		if b.count > 0 {
+			count := float64(b.count)
+			speed[i], steering[i], latAccel[i] = b.speed/count, b.steering/count, b.latAccel/count
+			filled = append(filled, i)
+		}
+	}
+
+	for idx, from := range filled {
+		to := filled[(idx+1)%len(filled)]
+		steps := (to - from + n) % n
+		if steps == 0 {
+			steps = n
+		}
+
+		for step := 1; step < steps; step++ {
+			i, f := (from+step)%n, float64(step)/float64(steps)
+			speed[i] = speed[from] + (speed[to]-speed[from])*f
+			steering[i] = steering[from] + (steering[to]-steering[from])*f
+			latAccel[i] = latAccel[from] + (latAccel[to]-latAccel[from])*f
+		}
+	}
+
+	return speed, steering, latAccel
+}
+
+// smooth the values of a lap with a moving average, which continues across the start/finish line
+func smooth(values []float64) []float64 {
+	n := len(values)
+	window := int(smoothing * float64(n))
+	smoothed := make([]float64, n)
+
+	for i := range values {
+		sum := 0.0
+		for j := i - window; j <= i+window; j++ {
+			sum += values[(j%n+n)%n]
+		}
+		smoothed[i] = sum / float64(2*window+1)
+	}
+
+	return smoothed
+}
+
+// fraction of the lap of the given position, which may exceed a single lap
+func fraction(pos float64) float64 { return pos - math.Floor(pos) }
@@ -0,0 +1,204 @@
+package corners
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/ibttest"
+)
+
+// testCorner is a corner of the synthetic track used for testing
+type testCorner struct {
+	entry, exit float64
+	direction   Direction
+}
+
+var testCorners = []testCorner{
+	{entry: 0.97, exit: 0.03, direction: Left},
+	{entry: 0.25, exit: 0.32, direction: Right},
+	{entry: 0.60, exit: 0.70, direction: Left},
+}
+
+// shape returns how far into the given corners the position is, between 0 at entry and exit and 1 at the apex
+func shape(corners []testCorner, pct float64) (float64, float64) {
+	for _, c := range corners {
+		if into := fraction(pct - c.entry); into < length(c.entry, c.exit) {
+			sign := -1.0
+			if c.direction == Left {
+				sign = 1
+			}
+			return math.Sin(math.Pi * into / length(c.entry, c.exit)), sign
+		}
+	}
+
+	return 0, 0
+}
+
+// testTicks creates the ticks of a car driving laps of the given times around the synthetic track, starting
+// at 0.9 of lap 1. The car is on pit road during the given lap.
+func testTicks(corners []testCorner, lapTimes []float64, pitLap int) []ibt.Tick {
+	ticks := make([]ibt.Tick, 0)
+	pos, sessionTime := 0.9, 50.0
+
+	for int(math.Floor(pos)) < len(lapTimes) {
+		pct := fraction(pos)
+		s, sign := shape(corners, pct)
+		lap := 1 + int(math.Floor(pos))
+
+		ticks = append(ticks, ibt.Tick{
+			"SessionTime":        sessionTime,
+			"Lap":                lap,
+			"LapDistPct":         float32(pct),
+			"Speed":              float32(60 - 30*s),
+			"SteeringWheelAngle": float32(sign * 0.3 * s),
+			"LatAccel":           float32(sign * 15 * s),
+			"OnPitRoad":          lap == pitLap && pct > 0.5,
+		})
+
+		pos += 1 / (60 * lapTimes[int(math.Floor(pos))])
+		sessionTime += 1.0 / 60
+	}
+
+	return ticks
+}
+
+var testStub = ibt.StubInfo{Index: 0, Filename: "test.ibt"}
+
+func TestDetector(t *testing.T) {
+	session := &headers.Session{WeekendInfo: headers.WeekendInfo{
+		TrackID:         403,
+		TrackName:       "test track",
+		TrackConfigName: "Grand Prix",
+		TrackLength:     "2.00 km",
+		TrackNumTurns:   3,
+	}}
+
+	t.Run("test Detector corners", func(t *testing.T) {
+		detector := NewDetector(Options{})
+		ibttest.Process(t, detector, testStub, testTicks(testCorners, []float64{40, 40, 40}, 0), session)
+
+		model, err := detector.Model()
+		if err != nil {
+			t.Errorf("expected Model() to run without err. received error: %v", err)
+			return
+		}
+
+		if model.TrackID != 403 || model.Key() != "403-grand-prix" || model.TrackLength != 2000 || model.NumTurns != 3 {
+			t.Errorf("expected the model to contain the track of the session. received %+v", model)
+		}
+
+		if len(model.Corners) != 3 {
+			t.Errorf("expected %d corners. received %d", 3, len(model.Corners))
+			return
+		}
+
+		if err := model.Validate(); err != nil {
+			t.Errorf("expected the model to match the number of turns. received error: %v", err)
+		}
+
+		// Corners are ordered by their apex, so the corner spanning the line is the last corner
+		expected := []testCorner{testCorners[1], testCorners[2], testCorners[0]}
+		for idx, corner := range model.Corners {
+			apex := fraction(expected[idx].entry + length(expected[idx].entry, expected[idx].exit)/2)
+
+			if corner.Number != idx+1 || corner.Direction != expected[idx].direction {
+				t.Errorf("expected corner %d to turn %s. received corner %d turning %s", idx+1, expected[idx].direction, corner.Number, corner.Direction)
+			}
+			if math.Abs(fraction(corner.Apex-apex+0.5)-0.5) > 0.002 || math.Abs(corner.MinSpeed-30) > 0.1 || math.Abs(corner.PeakLatAccel-15) > 0.1 {
+				t.Errorf("expected corner %d to have its apex at %.3f at %d m/s. received %+v", idx+1, apex, 30, corner)
+			}
+			if math.Abs(fraction(corner.Entry-expected[idx].entry+0.5)-0.5) > 0.01 || math.Abs(fraction(corner.Exit-expected[idx].exit+0.5)-0.5) > 0.01 {
+				t.Errorf("expected corner %d from %.2f to %.2f. received %.3f to %.3f", idx+1, expected[idx].entry, expected[idx].exit, corner.Entry, corner.Exit)
+			}
+		}
+
+		if last := model.Corners[2]; last.Entry < last.Exit || !last.Contains(0.995) || !last.Contains(0.005) || last.Contains(0.5) {
+			t.Errorf("expected the last corner to span the start/finish line. received %+v", last)
+		}
+
+		if len(model.Straights) != 3 || model.Straights[0].Start != model.Corners[0].Exit || model.Straights[2].End != model.Corners[0].Entry {
+			t.Errorf("expected a straight between each corner. received %+v", model.Straights)
+		}
+
+		if corner, ok := model.Corner(0.65); !ok || corner.Number != 2 {
+			t.Errorf("expected 0.65 to lie within corner %d. received %+v", 2, corner)
+		}
+		if _, ok := model.Corner(0.45); ok {
+			t.Error("expected 0.45 to lie on a straight")
+		}
+	})
+
+	t.Run("test Detector fastest clean lap", func(t *testing.T) {
+		detector := NewDetector(Options{})
+		ibttest.Process(t, detector, testStub, testTicks(testCorners, []float64{40, 40, 38, 35, 40}, 4), session)
+
+		if detector.best == nil || detector.best.lap != 3 || math.Abs(detector.best.time-38) > 0.01 {
+			t.Errorf("expected lap %d to be the fastest clean lap. received %+v", 3, detector.best)
+		}
+
+		detector = NewDetector(Options{Lap: 4})
+		ibttest.Process(t, detector, testStub, testTicks(testCorners, []float64{40, 40, 38, 35, 40}, 0), session)
+
+		if detector.best == nil || detector.best.lap != 4 {
+			t.Errorf("expected lap %d to be used. received %+v", 4, detector.best)
+		}
+	})
+
+	t.Run("test Detector without a clean lap", func(t *testing.T) {
+		detector := NewDetector(Options{})
+		ibttest.Process(t, detector, testStub, testTicks(testCorners, []float64{40, 40, 40}, 2), session)
+
+		model, err := detector.Model()
+		if !errors.Is(err, ErrNoLap) {
+			t.Errorf("expected error %v. received %v", ErrNoLap, err)
+		}
+
+		if result := detector.Result(); len(result.Corners) != 0 || result.TrackID != model.TrackID {
+			t.Errorf("expected a model without corners. received %+v", result)
+		}
+	})
+
+	t.Run("test Detector missing variables", func(t *testing.T) {
+		if err := NewDetector(Options{}).Process(ibt.Tick{"Lap": 1, "LapDistPct": float32(0.1), "Speed": float32(10)}, true, nil); err == nil {
+			t.Error("expected Process() to return an error when SteeringWheelAngle is missing")
+		}
+	})
+
+	t.Run("test Detector from registry", func(t *testing.T) {
+		processor, err := ibt.DefaultRegistry.NewProcessor("corners", ibt.Params{"min_lat_accel": 20})
+		if err != nil {
+			t.Errorf("expected NewProcessor() to run without err. received error: %v", err)
+			return
+		}
+
+		detector := processor.(*Detector)
+		ibttest.Process(t, detector, testStub, testTicks(testCorners, []float64{40, 40, 40}, 0), session)
+
+		if model := detector.Result(); len(model.Corners) != 0 || !errors.Is(model.Validate(), ErrTurnCount) {
+			t.Errorf("expected no corners above a lateral acceleration of %d. received %d", 20, len(model.Corners))
+		}
+	})
+}
+
+func TestFromStubs(t *testing.T) {
+	stubs, err := ibt.ParseStubs("../.testing/valid_test_file.ibt")
+	if err != nil {
+		t.Errorf("failed to parse stubs for testing file - %v", err)
+		return
+	}
+	defer stubs.Close()
+
+	// The testing file only contains a part of a lap driven on pit road
+	model, err := FromStubs(context.Background(), stubs, Options{})
+	if !errors.Is(err, ErrNoLap) {
+		t.Errorf("expected error %v. received %v", ErrNoLap, err)
+	}
+
+	if model.TrackID == 0 || model.TrackLength != 4280 {
+		t.Errorf("expected the model to contain the track of the session. received %+v", model)
+	}
+}
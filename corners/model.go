@@ -0,0 +1,318 @@
+package corners
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/teamjorge/ibt/headers"
+)
+
+// ErrTurnCount is returned by Validate when the number of detected corners does not match the number of
+// turns reported by the session.
+var ErrTurnCount = errors.New("detected corners do not match the number of turns of the track")
+
+// ErrNoModel is returned by ModelFor when no model was stored for the track layout of the session.
+var ErrNoModel = errors.New("no track model stored for the track")
+
+// Direction in which a corner turns.
+type Direction string
+
+const (
+	Left  Direction = "left"
+	Right Direction = "right"
+)
+
+// Corner is a single corner of a TrackModel. Positions are fractions of the lap, as reported by LapDistPct.
+//
+// A corner spanning the start/finish line has an Entry greater than its Exit.
+type Corner struct {
+	// Number of the corner, starting at 1 for the first apex after the start/finish line
+	Number int `json:"number"`
+	// Entry is where the car starts turning in
+	Entry float64 `json:"entry"`
+	// Apex is where the car is slowest through the corner
+	Apex float64 `json:"apex"`
+	// Exit is where the car stops turning
+	Exit      float64   `json:"exit"`
+	Direction Direction `json:"direction"`
+	// MinSpeed is the speed at the apex in meters per second
+	MinSpeed float64 `json:"min_speed"`
+	// PeakLatAccel is the largest lateral acceleration through the corner in meters per second squared
+	PeakLatAccel float64 `json:"peak_lat_accel"`
+}
+
+// Contains indicates whether the given LapDistPct lies within the corner
+func (c Corner) Contains(pct float64) bool { return contains(c.Entry, c.Exit, pct) }
+
+// Length of the corner as a fraction of the lap
+func (c Corner) Length() float64 { return length(c.Entry, c.Exit) }
+
+// PastApex indicates whether the given position is between the apex and exit of the corner
+func (c Corner) PastApex(pct float64) bool {
+	return c.Contains(pct) && length(c.Entry, pct) >= length(c.Entry, c.Apex)
+}
+
+// Straight is the part of the track between the exit of a corner and the entry of the next.
+type Straight struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// Contains indicates whether the given LapDistPct lies within the straight
+func (s Straight) Contains(pct float64) bool { return contains(s.Start, s.End, pct) }
+
+// Length of the straight as a fraction of the lap
+func (s Straight) Length() float64 { return length(s.Start, s.End) }
+
+// TrackModel describes the layout of a track as a sequence of corners and straights.
+//
+// Models are identified by the TrackID and TrackConfigName of the session they were detected in, allowing
+// them to be stored and reused for other files driven on the same layout.
+type TrackModel struct {
+	TrackID         int    `json:"track_id"`
+	TrackName       string `json:"track_name"`
+	TrackConfigName string `json:"track_config_name"`
+	// TrackLength in meters. This is 0 when not reported by the session.
+	TrackLength float64 `json:"track_length"`
+	// NumTurns is the number of turns reported by the session
+	NumTurns int `json:"num_turns"`
+	// Corners ordered by their apex
+	Corners []Corner `json:"corners"`
+	// Straights ordered by their start, where the first straight follows the first corner
+	Straights []Straight `json:"straights"`
+}
+
+// newTrackModel creates an empty TrackModel for the track of the given session
+func newTrackModel(session *headers.Session) TrackModel {
+	if session == nil {
+		return TrackModel{}
+	}
+
+	info := session.WeekendInfo
+	length, _ := info.TrackLengthMeters()
+
+	return TrackModel{
+		TrackID:         info.TrackID,
+		TrackName:       info.TrackName,
+		TrackConfigName: info.TrackConfigName,
+		TrackLength:     length,
+		NumTurns:        info.TrackNumTurns,
+	}
+}
+
+// Key identifying the layout of the model
+func (m TrackModel) Key() string { return ModelKey(m.TrackID, m.TrackConfigName) }
+
+// Corner containing the given LapDistPct
+func (m TrackModel) Corner(pct float64) (Corner, bool) {
+	for _, corner := range m.Corners {
+		if corner.Contains(pct) {
+			return corner, true
+		}
+	}
+
+	return Corner{}, false
+}
+
+// Approaching returns the corner containing the given LapDistPct, or otherwise the first corner that
+// is entered within the given fraction of the lap ahead of it
+func (m TrackModel) Approaching(pct, within float64) (Corner, bool) {
+	if corner, ok := m.Corner(pct); ok {
+		return corner, true
+	}
+
+	var next Corner
+	found := false
+
+	for _, corner := range m.Corners {
+		if ahead := length(pct, corner.Entry); ahead <= within && (!found || ahead < length(pct, next.Entry)) {
+			next, found = corner, true
+		}
+	}
+
+	return next, found
+}
+
+// Matches indicates whether the model describes the layout of the track of the given session
+func (m TrackModel) Matches(session *headers.Session) bool {
+	if session == nil {
+		return false
+	}
+
+	return m.Key() == ModelKey(session.WeekendInfo.TrackID, session.WeekendInfo.TrackConfigName)
+}
+
+// Validate the detected corners against the number of turns reported by the session.
+//
+// ErrTurnCount is returned when the numbers differ. Models without a reported number of turns are
+// always valid.
+func (m TrackModel) Validate() error {
+	if m.NumTurns > 0 && len(m.Corners) != m.NumTurns {
+		return fmt.Errorf("%w: detected %d, expected %d", ErrTurnCount, len(m.Corners), m.NumTurns)
+	}
+
+	return nil
+}
+
+// ModelKey identifies the layout of a track by its ID and configuration, such as "403-grand-prix".
+func ModelKey(trackID int, configName string) string {
+	var config strings.Builder
+
+	for _, r := range strings.ToLower(configName) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			config.WriteRune(r)
+		case config.Len() > 0 && !strings.HasSuffix(config.String(), "-"):
+			config.WriteRune('-')
+		}
+	}
+
+	if key := strings.TrimSuffix(config.String(), "-"); key != "" {
+		return fmt.Sprintf("%d-%s", trackID, key)
+	}
+
+	return fmt.Sprint(trackID)
+}
+
+// WriteModel writes the model as JSON
+func WriteModel(w io.Writer, m TrackModel) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(m); err != nil {
+		return fmt.Errorf("failed to encode track model: %v", err)
+	}
+
+	return nil
+}
+
+// ReadModel reads a model written by WriteModel
+func ReadModel(r io.Reader) (TrackModel, error) {
+	var m TrackModel
+
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return m, fmt.Errorf("failed to decode track model: %v", err)
+	}
+
+	return m, nil
+}
+
+// ModelStore stores track models as JSON files in a directory, named after the key of their layout.
+type ModelStore struct {
+	dir string
+}
+
+// NewModelStore creates a ModelStore for the given directory.
+func NewModelStore(dir string) *ModelStore {
+	return &ModelStore{dir: dir}
+}
+
+// path of the file of the model with the given key
+func (s *ModelStore) path(key string) string { return filepath.Join(s.dir, key+".json") }
+
+// Load the model of the given track layout. False is returned when no model was stored for it.
+func (s *ModelStore) Load(trackID int, configName string) (TrackModel, bool, error) {
+	path := s.path(ModelKey(trackID, configName))
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return TrackModel{}, false, nil
+	}
+	if err != nil {
+		return TrackModel{}, false, fmt.Errorf("failed to open track model %s: %v", path, err)
+	}
+	defer f.Close()
+
+	m, err := ReadModel(f)
+	if err != nil {
+		return m, false, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return m, true, nil
+}
+
+// LoadSession loads the model of the track layout of the given session
+func (s *ModelStore) LoadSession(session *headers.Session) (TrackModel, bool, error) {
+	if session == nil {
+		return TrackModel{}, false, nil
+	}
+
+	return s.Load(session.WeekendInfo.TrackID, session.WeekendInfo.TrackConfigName)
+}
+
+// ModelFor loads the model of the track layout of the given session from the store.
+//
+// This is used by analyses that assign their results to corners and need a model to do so. ErrNoModel is
+// returned when no model was stored for the layout.
+func ModelFor(store *ModelStore, session *headers.Session) (TrackModel, error) {
+	m, ok, err := store.LoadSession(session)
+	if err != nil {
+		return m, err
+	}
+	if !ok {
+		key := ""
+		if session != nil {
+			key = ModelKey(session.WeekendInfo.TrackID, session.WeekendInfo.TrackConfigName)
+		}
+		return m, fmt.Errorf("%w %s in %s", ErrNoModel, key, store.dir)
+	}
+
+	return m, nil
+}
+
+// Save the model, replacing any model previously stored for its layout.
+//
+// The model is written to a temporary file before replacing the existing file, ensuring that a stored
+// model is never partially overwritten.
+func (s *ModelStore) Save(m TrackModel) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create track model directory %s: %v", s.dir, err)
+	}
+
+	path := s.path(m.Key())
+
+	tmp, err := os.CreateTemp(s.dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary track model file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := WriteModel(tmp, m); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close track model file: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace track model file %s: %v", path, err)
+	}
+
+	return nil
+}
+
+// contains indicates whether pct lies between start and end, which may span the start/finish line
+func contains(start, end, pct float64) bool {
+	if start <= end {
+		return pct >= start && pct < end
+	}
+
+	return pct >= start || pct < end
+}
+
+// length of the part of the lap between start and end, which may span the start/finish line
+func length(start, end float64) float64 {
+	if start <= end {
+		return end - start
+	}
+
+	return 1 - start + end
+}
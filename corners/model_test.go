@@ -0,0 +1,172 @@
+package corners
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/teamjorge/ibt/headers"
+)
+
+func testModel() TrackModel {
+	return TrackModel{
+		TrackID:         403,
+		TrackName:       "test track",
+		TrackConfigName: "Grand Prix",
+		TrackLength:     2000,
+		NumTurns:        2,
+		Corners: []Corner{
+			{Number: 1, Entry: 0.2, Apex: 0.25, Exit: 0.3, Direction: Right, MinSpeed: 30, PeakLatAccel: 15},
+			{Number: 2, Entry: 0.95, Apex: 0.99, Exit: 0.05, Direction: Left, MinSpeed: 40, PeakLatAccel: 12},
+		},
+		Straights: []Straight{{Start: 0.3, End: 0.95}, {Start: 0.05, End: 0.2}},
+	}
+}
+
+func TestModelKey(t *testing.T) {
+	tests := []struct {
+		id     int
+		config string
+		key    string
+	}{
+		{id: 403, config: "Grand Prix", key: "403-grand-prix"},
+		{id: 12, config: "", key: "12"},
+		{id: 7, config: " Oval - Infield (2019) ", key: "7-oval-infield-2019"},
+	}
+
+	for _, test := range tests {
+		if key := ModelKey(test.id, test.config); key != test.key {
+			t.Errorf("expected key %s for %d %q. received %s", test.key, test.id, test.config, key)
+		}
+	}
+}
+
+func TestTrackModel(t *testing.T) {
+	t.Run("test TrackModel segments", func(t *testing.T) {
+		model := testModel()
+
+		if corner, ok := model.Corner(0.02); !ok || corner.Number != 2 {
+			t.Errorf("expected 0.02 to lie within corner %d. received %+v", 2, corner)
+		}
+
+		if corner, ok := model.Approaching(0.9, 0.1); !ok || corner.Number != 2 {
+			t.Errorf("expected corner %d to be approached at 0.9. received %+v", 2, corner)
+		}
+
+		if corner, ok := model.Approaching(0.25, 0.1); !ok || corner.Number != 1 {
+			t.Errorf("expected 0.25 to lie within corner %d. received %+v", 1, corner)
+		}
+
+		if _, ok := model.Approaching(0.5, 0.1); ok {
+			t.Error("expected no corner to be approached at 0.5")
+		}
+
+		if length := model.Corners[1].Length(); length < 0.0999 || length > 0.1001 {
+			t.Errorf("expected corner %d to span %.2f of the lap. received %f", 2, 0.1, length)
+		}
+
+		if corner := model.Corners[1]; !corner.PastApex(0.995) || !corner.PastApex(0.02) || corner.PastApex(0.97) || corner.PastApex(0.1) {
+			t.Errorf("expected only the part of corner %d from the apex to the exit to be past the apex", 2)
+		}
+
+		if straight := model.Straights[0]; !straight.Contains(0.5) || straight.Contains(0.96) || math.Abs(straight.Length()-0.65) > 0.0001 {
+			t.Errorf("expected the first straight to span from %.2f to %.2f. received %+v", 0.3, 0.95, straight)
+		}
+	})
+
+	t.Run("test TrackModel Validate() and Matches()", func(t *testing.T) {
+		model := testModel()
+		if err := model.Validate(); err != nil {
+			t.Errorf("expected Validate() to run without err. received error: %v", err)
+		}
+
+		model.NumTurns = 3
+		if err := model.Validate(); err == nil || !strings.Contains(err.Error(), "detected 2, expected 3") {
+			t.Errorf("expected Validate() to return the number of corners. received %v", err)
+		}
+
+		session := &headers.Session{WeekendInfo: headers.WeekendInfo{TrackID: 403, TrackConfigName: "Grand Prix"}}
+		if !model.Matches(session) || model.Matches(nil) {
+			t.Error("expected the model to only match the session of its layout")
+		}
+
+		session.WeekendInfo.TrackConfigName = "Oval"
+		if model.Matches(session) {
+			t.Error("expected the model not to match another configuration of the track")
+		}
+	})
+
+	t.Run("test WriteModel() and ReadModel()", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteModel(&buf, testModel()); err != nil {
+			t.Errorf("expected WriteModel() to run without err. received error: %v", err)
+		}
+
+		if !strings.Contains(buf.String(), `"track_config_name": "Grand Prix"`) {
+			t.Errorf("expected the model to be written as JSON. received %s", buf.String())
+		}
+
+		model, err := ReadModel(&buf)
+		if err != nil || !reflect.DeepEqual(model, testModel()) {
+			t.Errorf("expected the model to be read back. received %+v and error %v", model, err)
+		}
+
+		if _, err := ReadModel(strings.NewReader("{")); err == nil {
+			t.Error("expected ReadModel() to return an error for invalid JSON")
+		}
+	})
+}
+
+func TestModelStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "models")
+	store := NewModelStore(dir)
+
+	if _, ok, err := store.Load(403, "Grand Prix"); ok || err != nil {
+		t.Errorf("expected no model to be stored. received %v and error %v", ok, err)
+	}
+
+	if err := store.Save(testModel()); err != nil {
+		t.Errorf("expected Save() to run without err. received error: %v", err)
+		return
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "403-grand-prix.json")); err != nil {
+		t.Errorf("expected the model to be stored by its key. received error: %v", err)
+	}
+
+	session := &headers.Session{WeekendInfo: headers.WeekendInfo{TrackID: 403, TrackConfigName: "Grand Prix"}}
+	model, ok, err := store.LoadSession(session)
+	if !ok || err != nil || !reflect.DeepEqual(model, testModel()) {
+		t.Errorf("expected the stored model to be loaded. received %+v, %v and error %v", model, ok, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "12.json"), []byte("invalid"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := store.Load(12, ""); err == nil {
+		t.Error("expected Load() to return an error for an invalid model")
+	}
+}
+
+func TestModelFor(t *testing.T) {
+	store := NewModelStore(t.TempDir())
+	session := &headers.Session{WeekendInfo: headers.WeekendInfo{TrackID: 403, TrackConfigName: "Grand Prix"}}
+
+	if _, err := ModelFor(store, session); !errors.Is(err, ErrNoModel) {
+		t.Errorf("expected ErrNoModel before the model was stored. received error: %v", err)
+	}
+
+	if err := store.Save(testModel()); err != nil {
+		t.Fatal(err)
+	}
+
+	if model, err := ModelFor(store, session); err != nil || !reflect.DeepEqual(model, testModel()) {
+		t.Errorf("expected the stored model. received %+v and error %v", model, err)
+	}
+}
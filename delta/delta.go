@@ -0,0 +1,170 @@
+package delta
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+const (
+	// Default distance in meters between the points of a comparison
+	defaultResolution float64 = 1
+)
+
+// Options configures the comparison of two traces.
+type Options struct {
+	// Resolution is the distance in meters between points. Defaults to 1 meter.
+	Resolution float64
+	// Normalise aligns the traces using LapDistPct scaled to the length of the reference lap, instead of
+	// LapDist. This avoids a drifting delta when the traces report slightly different track lengths.
+	Normalise bool
+}
+
+// Point compares the reference and comparison laps at a single distance.
+type Point struct {
+	// Distance in meters from the start of the reference lap
+	Distance float64
+	// Pct is the fraction of the reference lap at the distance
+	Pct float64
+	// ReferenceTime and ComparisonTime are the seconds since the start of each lap
+	ReferenceTime  float64
+	ComparisonTime float64
+	// Delta is the time lost by the comparison lap in seconds since the first point. Negative values
+	// indicate that the comparison lap is ahead.
+	Delta float64
+	// ReferenceSpeed and ComparisonSpeed are the speeds of each lap in meters per second
+	ReferenceSpeed  float64
+	ComparisonSpeed float64
+	// SpeedDelta is the speed of the comparison lap minus the speed of the reference lap
+	SpeedDelta float64
+}
+
+// Compare computes the delta of the comparison lap to the reference lap over distance.
+//
+// Points are created at every multiple of the resolution where both traces were recorded. Times and
+// speeds are linearly interpolated between the samples of each trace. Samples that do not increase the
+// distance of their trace, such as after a reset or due to jitter in LapDistPct, are ignored.
+func Compare(reference, comparison Trace, opts Options) ([]Point, error) {
+	if opts.Resolution <= 0 {
+		opts.Resolution = defaultResolution
+	}
+
+	length := reference.Length()
+	refSamples, refDist := aligned(reference, length, opts.Normalise)
+	compSamples, compDist := aligned(comparison, length, opts.Normalise)
+
+	if len(refSamples) < 2 || len(compSamples) < 2 {
+		return nil, errors.New("traces require at least two samples to be compared")
+	}
+
+	start := math.Max(refDist[0], compDist[0])
+	end := math.Min(refDist[len(refDist)-1], compDist[len(compDist)-1])
+	if end < start {
+		return nil, errors.New("traces do not overlap")
+	}
+
+	points := make([]Point, 0, int((end-start)/opts.Resolution)+1)
+	var refStart, compStart float64
+
+	for step := math.Ceil(start / opts.Resolution); step*opts.Resolution <= end; step++ {
+		distance := step * opts.Resolution
+
+		refTime, refSpeed := interpolateAt(refSamples, refDist, distance)
+		compTime, compSpeed := interpolateAt(compSamples, compDist, distance)
+
+		if len(points) == 0 {
+			refStart, compStart = refTime, compTime
+		}
+
+		point := Point{
+			Distance:        distance,
+			ReferenceTime:   refTime,
+			ComparisonTime:  compTime,
+			Delta:           (compTime - compStart) - (refTime - refStart),
+			ReferenceSpeed:  refSpeed,
+			ComparisonSpeed: compSpeed,
+			SpeedDelta:      compSpeed - refSpeed,
+		}
+		if length > 0 {
+			point.Pct = distance / length
+		}
+
+		points = append(points, point)
+	}
+
+	return points, nil
+}
+
+// aligned returns the samples of the trace along with the distance used for aligning each of them.
+//
+// Only samples that increase the distance are included, ensuring the distances are sorted.
+func aligned(trace Trace, length float64, normalise bool) ([]Sample, []float64) {
+	samples := make([]Sample, 0, len(trace.Samples))
+	dists := make([]float64, 0, len(trace.Samples))
+
+	for _, sample := range trace.Samples {
+		dist := sample.Dist
+		if normalise {
+			dist = sample.Pct * length
+		}
+
+		if n := len(dists); n > 0 && dist <= dists[n-1] {
+			continue
+		}
+
+		samples = append(samples, sample)
+		dists = append(dists, dist)
+	}
+
+	return samples, dists
+}
+
+// interpolateAt returns the time and speed of the samples at the given distance
+func interpolateAt(samples []Sample, dists []float64, distance float64) (float64, float64) {
+	idx := sort.SearchFloat64s(dists, distance)
+	if idx == 0 {
+		return samples[0].Time, samples[0].Speed
+	}
+	if idx >= len(samples) {
+		last := samples[len(samples)-1]
+		return last.Time, last.Speed
+	}
+
+	before, after := samples[idx-1], samples[idx]
+	fraction := 0.0
+	if span := dists[idx] - dists[idx-1]; span > 0 {
+		fraction = (distance - dists[idx-1]) / span
+	}
+
+	return before.Time + (after.Time-before.Time)*fraction, before.Speed + (after.Speed-before.Speed)*fraction
+}
+
+// WriteCSV writes the points with a header row to the given writer
+func WriteCSV(w io.Writer, points []Point) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"distance", "pct", "reference_time", "comparison_time", "delta", "reference_speed", "comparison_speed", "speed_delta"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, p := range points {
+		values := []float64{p.Distance, p.Pct, p.ReferenceTime, p.ComparisonTime, p.Delta, p.ReferenceSpeed, p.ComparisonSpeed, p.SpeedDelta}
+
+		record := make([]string, len(values))
+		for idx, value := range values {
+			record[idx] = strconv.FormatFloat(value, 'f', -1, 64)
+		}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
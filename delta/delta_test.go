@@ -0,0 +1,168 @@
+package delta
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+// testTrace creates a trace of a lap of the given length, driven at the given speed over distance
+func testTrace(length, scale float64, speed func(d float64) float64) Trace {
+	trace := Trace{TrackLength: length * scale}
+
+	elapsed := 0.0
+	for d := 0.0; d <= length; d += 10 {
+		if d > 0 {
+			elapsed += 10 / speed(d-5)
+		}
+		trace.Samples = append(trace.Samples, Sample{Pct: d / length, Dist: d * scale, Time: elapsed, Speed: speed(d)})
+	}
+
+	return trace
+}
+
+func constant(d float64) float64 { return 50 }
+
+func varying(d float64) float64 {
+	if d < 500 {
+		return 40
+	}
+	return 60
+}
+
+func TestCompare(t *testing.T) {
+	t.Run("test Compare() delta over distance", func(t *testing.T) {
+		points, err := Compare(testTrace(1000, 1, constant), testTrace(1000, 1, varying), Options{Resolution: 10})
+		if err != nil {
+			t.Errorf("expected Compare() to run without err. received error: %v", err)
+			return
+		}
+
+		if len(points) != 101 {
+			t.Errorf("expected %d points. received %d", 101, len(points))
+			return
+		}
+
+		tests := []struct {
+			idx   int
+			delta float64
+			speed float64
+		}{
+			{idx: 0, delta: 0, speed: -10},
+			{idx: 50, delta: 2.5, speed: 10},
+			{idx: 100, delta: 0.8333, speed: 10},
+		}
+
+		for _, test := range tests {
+			point := points[test.idx]
+			if math.Abs(point.Delta-test.delta) > 0.001 || math.Abs(point.SpeedDelta-test.speed) > 0.001 {
+				t.Errorf("expected delta %.4f and speed delta %.1f at %.0f meters. received %+v", test.delta, test.speed, point.Distance, point)
+			}
+		}
+
+		if points[50].Distance != 500 || points[50].Pct != 0.5 {
+			t.Errorf("expected point at %d meters and %.1f of the lap. received %+v", 500, 0.5, points[50])
+		}
+	})
+
+	t.Run("test Compare() normalised track length", func(t *testing.T) {
+		reference := testTrace(1000, 1, constant)
+		comparison := testTrace(1000, 1.01, varying)
+
+		normalised, err := Compare(reference, comparison, Options{Resolution: 10, Normalise: true})
+		if err != nil {
+			t.Errorf("expected Compare() to run without err. received error: %v", err)
+			return
+		}
+
+		if len(normalised) != 101 || math.Abs(normalised[100].Delta-0.8333) > 0.001 {
+			t.Errorf("expected the comparison to be scaled to the reference length. received %+v", normalised[len(normalised)-1])
+		}
+
+		raw, _ := Compare(reference, comparison, Options{Resolution: 10})
+		if math.Abs(raw[100].Delta-0.8333) < 0.01 {
+			t.Errorf("expected the delta to drift without normalisation. received %+v", raw[100])
+		}
+	})
+
+	t.Run("test Compare() partial traces", func(t *testing.T) {
+		comparison := testTrace(1000, 1, constant)
+		comparison.Samples = comparison.Samples[20:60]
+
+		points, err := Compare(testTrace(1000, 1, constant), comparison, Options{})
+		if err != nil {
+			t.Errorf("expected Compare() to run without err. received error: %v", err)
+			return
+		}
+
+		if points[0].Distance != 200 || points[len(points)-1].Distance != 590 || len(points) != 391 {
+			t.Errorf("expected points from %d to %d meters. received %d points", 200, 590, len(points))
+		}
+
+		if points[len(points)-1].Delta > 0.0001 {
+			t.Errorf("expected no delta between identical partial laps. received %f", points[len(points)-1].Delta)
+		}
+	})
+
+	t.Run("test Compare() distances going backwards", func(t *testing.T) {
+		// The reference moves back from 600 to 50 meters, such as after a reset, and repeats a sample due to
+		// jitter before continuing the lap
+		reference := testTrace(1000, 1, constant)
+		samples := append([]Sample{}, reference.Samples[:61]...)
+		for d := 590.0; d >= 50; d -= 10 {
+			samples = append(samples, Sample{Pct: d / 1000, Dist: d, Time: 12 + (600-d)/10, Speed: 10})
+		}
+		samples = append(samples, reference.Samples[60])
+		reference.Samples = append(samples, reference.Samples[61:]...)
+
+		points, err := Compare(reference, testTrace(1000, 1, varying), Options{Resolution: 10})
+		if err != nil {
+			t.Errorf("expected Compare() to run without err. received error: %v", err)
+			return
+		}
+
+		if len(points) != 101 || math.Abs(points[50].Delta-2.5) > 0.001 || math.Abs(points[100].Delta-0.8333) > 0.001 {
+			t.Errorf("expected the samples going backwards to be ignored. received %+v and %+v", points[50], points[len(points)-1])
+		}
+	})
+
+	t.Run("test Compare() invalid traces", func(t *testing.T) {
+		if _, err := Compare(Trace{}, testTrace(1000, 1, constant), Options{}); err == nil {
+			t.Error("expected Compare() to return an error for an empty trace")
+		}
+
+		first, second := testTrace(1000, 1, constant), testTrace(1000, 1, constant)
+		first.Samples, second.Samples = first.Samples[:10], second.Samples[50:]
+		if _, err := Compare(first, second, Options{}); err == nil {
+			t.Error("expected Compare() to return an error for traces that do not overlap")
+		}
+	})
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("unit test write error") }
+
+func TestWriteCSV(t *testing.T) {
+	points := []Point{
+		{Distance: 0, Pct: 0, ReferenceTime: 0, ComparisonTime: 0, Delta: 0, ReferenceSpeed: 50, ComparisonSpeed: 40, SpeedDelta: -10},
+		{Distance: 10, Pct: 0.01, ReferenceTime: 0.2, ComparisonTime: 0.25, Delta: 0.05, ReferenceSpeed: 50, ComparisonSpeed: 40, SpeedDelta: -10},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, points); err != nil {
+		t.Errorf("expected WriteCSV() to run without err. received error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[0] != "distance,pct,reference_time,comparison_time,delta,reference_speed,comparison_speed,speed_delta" ||
+		lines[2] != "10,0.01,0.2,0.25,0.05,50,40,-10" {
+		t.Errorf("expected a header and a row for each point. received %v", lines)
+	}
+
+	if err := WriteCSV(failingWriter{}, points); err == nil {
+		t.Error("expected WriteCSV() to return the error of the writer")
+	}
+}
@@ -0,0 +1,251 @@
+// Package delta compares laps by computing the time delta between them over the distance of a lap.
+//
+// A Recorder captures a Trace of every lap while processing telemetry. Compare aligns two traces by
+// distance and returns a Point for every step of the configured resolution, which can be plotted or
+// exported with WriteCSV.
+package delta
+
+import (
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/laps"
+)
+
+func init() {
+	ibt.Register("delta-recorder", func(params ibt.Params) (ibt.Processor, error) {
+		var cfg struct {
+			Laps []int `yaml:"laps"`
+		}
+		if err := params.Decode(&cfg); err != nil {
+			return nil, err
+		}
+
+		return NewRecorder(cfg.Laps...), nil
+	})
+}
+
+// Sample is the position, time and speed of the car at a single tick of a lap.
+type Sample struct {
+	// Pct is the fraction of the lap driven, as reported by LapDistPct
+	Pct float64
+	// Dist is the distance driven in meters, as reported by LapDist
+	Dist float64
+	// Time is the number of seconds since the start of the lap
+	Time float64
+	// Speed in meters per second
+	Speed float64
+}
+
+// Trace is the recorded samples of a single lap.
+type Trace struct {
+	// Stub is the index of the stub within the group being processed
+	Stub int
+	// Filename of the stub
+	Filename string
+	// Lap number, as reported by the Lap variable
+	Lap int
+	// TrackLength in meters, as reported by the session. This is 0 when not available.
+	TrackLength float64
+	// StartTime is the session time at the start of the lap
+	StartTime float64
+	// Samples of the lap, ordered by distance. Samples where the car did not move forward are excluded.
+	Samples []Sample
+}
+
+// Complete indicates whether the trace covers the entire lap, from crossing the line until crossing it again
+func (t Trace) Complete() bool {
+	return len(t.Samples) > 1 && t.Samples[0].Pct == 0 && t.Samples[len(t.Samples)-1].Pct == 1
+}
+
+// Length of the lap in meters. This is the TrackLength when available, or the last distance recorded otherwise.
+func (t Trace) Length() float64 {
+	if t.TrackLength > 0 || len(t.Samples) == 0 {
+		return t.TrackLength
+	}
+
+	return t.Samples[len(t.Samples)-1].Dist
+}
+
+// Recorder is a processor that records a Trace of every lap.
+//
+// Traces start and end where the car crosses the start/finish line, which is interpolated between the
+// ticks on either side of it.
+type Recorder struct {
+	laps   map[int]struct{}
+	traces []Trace
+
+	stub        ibt.StubInfo
+	trackLength float64
+	current     *Trace
+	prev        recorderTick
+	hasPrev     bool
+}
+
+// recorderTick is the part of a tick used for recording traces
+type recorderTick struct {
+	lap         int
+	sessionTime float64
+	sample      Sample
+}
+
+// NewRecorder creates a Recorder for the given laps. All laps are recorded when none are provided.
+func NewRecorder(laps ...int) *Recorder {
+	r := new(Recorder)
+
+	if len(laps) > 0 {
+		r.laps = make(map[int]struct{}, len(laps))
+		for _, lap := range laps {
+			r.laps[lap] = struct{}{}
+		}
+	}
+
+	return r
+}
+
+// Whitelist of the variables required for recording traces
+func (r *Recorder) Whitelist() []string { return []string{"Lap", "LapDist", "LapDistPct", "Speed"} }
+
+// StartStub completes the trace of the previous stub
+func (r *Recorder) StartStub(stub ibt.StubInfo) error {
+	r.end()
+	r.stub = stub
+	r.trackLength = 0
+	r.hasPrev = false
+
+	return nil
+}
+
+// Process the tick without a TickContext. The samples of the trace are timed with the SessionTime of the
+// tick, so it should be included when the times of the laps are compared.
+func (r *Recorder) Process(input ibt.Tick, hasNext bool, session *headers.Session) error {
+	return r.ProcessContext(input, ibt.ContextFromTick(input), hasNext, session)
+}
+
+// ProcessContext adds the tick to the trace of its lap
+func (r *Recorder) ProcessContext(input ibt.Tick, tickCtx ibt.TickContext, hasNext bool, session *headers.Session) error {
+	lap, err := ibt.GetTickValue[int](input, "Lap")
+	if err != nil {
+		return err
+	}
+
+	pct, err := ibt.GetTickValue[float32](input, "LapDistPct")
+	if err != nil {
+		return err
+	}
+
+	dist, err := ibt.GetTickValue[float32](input, "LapDist")
+	if err != nil {
+		return err
+	}
+
+	speed, _ := ibt.GetTickValue[float32](input, "Speed")
+
+	if r.trackLength == 0 && session != nil {
+		r.trackLength, _ = session.WeekendInfo.TrackLengthMeters()
+	}
+
+	tick := recorderTick{
+		lap:         lap,
+		sessionTime: tickCtx.SessionTime,
+		sample:      Sample{Pct: float64(pct), Dist: float64(dist), Speed: float64(speed)},
+	}
+
+	switch {
+	case r.current == nil:
+		r.start(tick, tick.sessionTime)
+	case lap != r.current.Lap:
+		r.next(tick)
+	}
+
+	r.add(tick)
+	r.prev = tick
+	r.hasPrev = true
+
+	if !hasNext {
+		r.end()
+	}
+
+	return nil
+}
+
+// next completes the current trace and starts the trace of the lap of the given tick
+func (r *Recorder) next(tick recorderTick) {
+	prev := r.prev.sample
+
+	if tick.lap != r.current.Lap+1 || float32(prev.Pct-tick.sample.Pct) <= laps.MaxPctStep {
+		r.end()
+		r.start(tick, tick.sessionTime)
+		return
+	}
+
+	// The crossing of the line is interpolated between the ticks on either side of it
+	before, after := 1-prev.Pct, tick.sample.Pct
+	fraction := 0.0
+	if before+after > 0 {
+		fraction = before / (before + after)
+	}
+
+	crossing := r.prev.sessionTime + (tick.sessionTime-r.prev.sessionTime)*fraction
+	speed := prev.Speed + (tick.sample.Speed-prev.Speed)*fraction
+
+	length := r.trackLength
+	if length == 0 && prev.Pct > 0 {
+		length = prev.Dist / prev.Pct
+	}
+
+	r.current.Samples = append(r.current.Samples, Sample{Pct: 1, Dist: length, Time: crossing - r.current.StartTime, Speed: speed})
+	r.end()
+
+	r.start(tick, crossing)
+	r.current.Samples = append(r.current.Samples, Sample{Pct: 0, Dist: 0, Time: 0, Speed: speed})
+}
+
+// start the trace of the lap of the given tick
+func (r *Recorder) start(tick recorderTick, startTime float64) {
+	r.current = &Trace{
+		Stub:        r.stub.Index,
+		Filename:    r.stub.Filename,
+		Lap:         tick.lap,
+		TrackLength: r.trackLength,
+		StartTime:   startTime,
+	}
+}
+
+// add the sample of the tick to the current trace if the car moved forward
+func (r *Recorder) add(tick recorderTick) {
+	sample := tick.sample
+	sample.Time = tick.sessionTime - r.current.StartTime
+
+	if n := len(r.current.Samples); n > 0 && sample.Pct <= r.current.Samples[n-1].Pct {
+		return
+	}
+
+	r.current.Samples = append(r.current.Samples, sample)
+}
+
+// end the current trace, keeping it if its lap was requested
+func (r *Recorder) end() {
+	if r.current == nil {
+		return
+	}
+
+	if _, ok := r.laps[r.current.Lap]; r.laps == nil || ok {
+		r.traces = append(r.traces, *r.current)
+	}
+
+	r.current = nil
+}
+
+// Trace of the given lap of the stub with the given index
+func (r *Recorder) Trace(stub, lap int) (Trace, bool) {
+	for _, trace := range r.traces {
+		if trace.Stub == stub && trace.Lap == lap {
+			return trace, true
+		}
+	}
+
+	return Trace{}, false
+}
+
+// Result returns the traces of all laps recorded so far
+func (r *Recorder) Result() []Trace { return r.traces }
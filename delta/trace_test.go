@@ -0,0 +1,122 @@
+package delta
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/ibttest"
+)
+
+// testTicks creates the ticks of a car driving a 1 km lap at 50 m/s, starting at 0.955 of lap 1
+func testTicks(duration float64) []ibt.Tick {
+	ticks := make([]ibt.Tick, 0)
+
+	for idx := 0; float64(idx)/60 <= duration; idx++ {
+		t := float64(idx) / 60
+		pos := 0.955 + t/20
+		pct := pos - math.Floor(pos)
+
+		ticks = append(ticks, ibt.Tick{
+			"SessionTime": 200 + t,
+			"Lap":         1 + int(math.Floor(pos)),
+			"LapDistPct":  float32(pct),
+			"LapDist":     float32(pct * 1000),
+			"Speed":       float32(50),
+		})
+	}
+
+	return ticks
+}
+
+var testStub = ibt.StubInfo{Index: 0, Filename: "test.ibt"}
+
+func TestRecorder(t *testing.T) {
+	session := &headers.Session{WeekendInfo: headers.WeekendInfo{TrackLength: "1.00 km"}}
+
+	t.Run("test Recorder traces", func(t *testing.T) {
+		recorder := NewRecorder()
+		ibttest.Process(t, recorder, testStub, testTicks(30), session)
+
+		traces := recorder.Result()
+		if len(traces) != 3 {
+			t.Errorf("expected %d traces. received %d", 3, len(traces))
+			return
+		}
+
+		if traces[0].Complete() || !traces[1].Complete() || traces[2].Complete() {
+			t.Error("expected only the trace of lap 2 to be complete")
+		}
+
+		full := traces[1]
+		last := full.Samples[len(full.Samples)-1]
+		if full.Lap != 2 || full.Length() != 1000 || math.Abs(full.StartTime-200.9) > 0.001 || math.Abs(last.Time-20) > 0.001 {
+			t.Errorf("expected lap 2 to take %d seconds over %d meters. received lap %d of %.0f meters ending at %f", 20, 1000, full.Lap, full.Length(), last.Time)
+		}
+
+		if first := full.Samples[0]; first.Dist != 0 || first.Time != 0 || first.Speed != 50 {
+			t.Errorf("expected the trace to start at the line. received %+v", first)
+		}
+
+		if traces[2].Samples[0].Time != 0 || traces[2].StartTime != full.StartTime+20 {
+			t.Errorf("expected lap 3 to start at the end of lap 2. received %+v", traces[2].Samples[0])
+		}
+	})
+
+	t.Run("test Recorder selected laps", func(t *testing.T) {
+		recorder := NewRecorder(2)
+		ibttest.Process(t, recorder, testStub, testTicks(30), nil)
+
+		if len(recorder.Result()) != 1 {
+			t.Errorf("expected only lap 2 to be recorded. received %d traces", len(recorder.Result()))
+		}
+
+		trace, ok := recorder.Trace(0, 2)
+		if !ok || trace.TrackLength != 0 || math.Abs(trace.Length()-1000) > 0.01 {
+			t.Errorf("expected the length of lap 2 to be estimated without a track length. received %v", trace.Length())
+		}
+
+		if _, ok := recorder.Trace(0, 3); ok {
+			t.Error("expected lap 3 not to be recorded")
+		}
+	})
+
+	t.Run("test Recorder compare laps", func(t *testing.T) {
+		recorder := NewRecorder()
+		ibttest.Process(t, recorder, testStub, testTicks(45), session)
+
+		reference, _ := recorder.Trace(0, 2)
+		comparison, _ := recorder.Trace(0, 3)
+
+		points, err := Compare(reference, comparison, Options{Resolution: 5})
+		if err != nil {
+			t.Errorf("expected Compare() to run without err. received error: %v", err)
+			return
+		}
+
+		if len(points) != 201 || math.Abs(points[200].Delta) > 0.001 {
+			t.Errorf("expected no delta between identical laps. received %+v", points[len(points)-1])
+		}
+	})
+
+	t.Run("test Recorder with ibt.Process", func(t *testing.T) {
+		stubs, err := ibt.ParseStubs("../.testing/valid_test_file.ibt")
+		if err != nil {
+			t.Errorf("failed to parse stubs for testing file - %v", err)
+			return
+		}
+		defer stubs.Close()
+
+		recorder := NewRecorder()
+		if err := ibt.Process(context.Background(), stubs, recorder); err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		traces := recorder.Result()
+		if len(traces) != 1 || traces[0].Lap != 9 || traces[0].TrackLength != 4280 || len(traces[0].Samples) == 0 {
+			t.Errorf("expected a trace of lap 9 with the track length of the session. received %d traces", len(traces))
+		}
+	})
+}
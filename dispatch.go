@@ -0,0 +1,150 @@
+package ibt
+
+import (
+	"math"
+
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/utilities/fifo"
+)
+
+// handle wraps a processor with any state required for delivering ticks to it.
+type handle struct {
+	proc Processor
+
+	// Preceding ticks for processors implementing WindowProcessor
+	window *fifo.Simple[Tick]
+
+	// Decimation for processors implementing RateProcessor
+	decimation Decimation
+	step       int
+	count      int
+	pending    []Tick
+	// pendingFrom is the index of the first tick of the stub contributing to the pending ticks
+	pendingFrom int
+}
+
+func newHandles(processors ...Processor) []*handle {
+	handles := make([]*handle, len(processors))
+
+	for i, proc := range processors {
+		h := &handle{proc: proc, step: 1}
+		if windowProc, ok := proc.(WindowProcessor); ok {
+			h.window = fifo.NewSimple[Tick](windowProc.WindowSize())
+		}
+		if decimator, ok := proc.(Decimator); ok {
+			h.decimation = decimator.Decimation()
+		}
+		handles[i] = h
+	}
+
+	return handles
+}
+
+// startStub resets the state of the handle for a new stub
+func (h *handle) startStub(stub StubInfo) {
+	if h.window != nil {
+		h.window.Reset()
+	}
+
+	h.count = 0
+	h.pending = h.pending[:0]
+	h.step = 1
+	if rateProc, ok := h.proc.(RateProcessor); ok && stub.Header != nil {
+		h.step = decimationStep(stub.Header.TelemetryHeader.TickRate, rateProc.Rate())
+	}
+
+	if r, isResetter := h.proc.(stubResetter); isResetter {
+		r.resetStub(stub)
+	}
+}
+
+// deliver the tick to the processor, decimating ticks for processors implementing RateProcessor.
+//
+// from is the index of the first tick of the stub contributing to the input, which differs from the index
+// of the TickContext when the input was reduced from several ticks by a pipeline.
+func (h *handle) deliver(input Tick, from int, tickCtx TickContext, hasNext bool, session *headers.Session) error {
+	if h.step <= 1 {
+		return h.process(input, tickCtx, hasNext, session)
+	}
+
+	h.count++
+
+	if h.decimation == DecimateMean {
+		if len(h.pending) == 0 {
+			h.pendingFrom = from
+		}
+		h.pending = append(h.pending, input)
+		if len(h.pending) < h.step && hasNext {
+			return nil
+		}
+
+		mean := MeanTicks(h.pending)
+		h.pending = h.pending[:0]
+
+		return h.process(mean, tickCtx, hasNext, session)
+	}
+
+	// The final tick is always delivered to indicate the end of the stub
+	if (h.count-1)%h.step != 0 && hasNext {
+		return nil
+	}
+
+	return h.process(input, tickCtx, hasNext, session)
+}
+
+// process the tick with the processor.
+//
+// ProcessWindow or ProcessContext will be used when the processor implements WindowProcessor or
+// ContextProcessor respectively. ProcessWindow takes precedence, with the TickContext available from
+// the TickWindow.
+func (h *handle) process(input Tick, tickCtx TickContext, hasNext bool, session *headers.Session) error {
+	if h.window != nil {
+		err := h.proc.(WindowProcessor).ProcessWindow(input, TickWindow{ring: h.window, ctx: tickCtx}, hasNext, session)
+		h.window.Add(input)
+
+		return err
+	}
+
+	if contextProc, ok := h.proc.(ContextProcessor); ok {
+		return contextProc.ProcessContext(input, tickCtx, hasNext, session)
+	}
+
+	return h.proc.Process(input, hasNext, session)
+}
+
+// undelivered is the index of the first tick of the stub received by the handle that has not been fully
+// processed. False is returned when all ticks received were processed.
+//
+// This includes ticks waiting to be decimated, as well as any ticks buffered by a pipeline.
+func (h *handle) undelivered() (int, bool) {
+	from, ok := h.pendingFrom, len(h.pending) > 0
+
+	if b, isBuffer := h.proc.(tickBuffer); isBuffer {
+		if bufferedFrom, buffered := b.undelivered(); buffered && (!ok || bufferedFrom < from) {
+			from, ok = bufferedFrom, true
+		}
+	}
+
+	return from, ok
+}
+
+// stubResetter is implemented by processors with internal state that is reset for each stub, regardless
+// of whether they implement StubStarter.
+type stubResetter interface {
+	resetStub(stub StubInfo)
+}
+
+// tickBuffer is implemented by processors that hold ticks before processing them.
+type tickBuffer interface {
+	// undelivered is the index of the first tick of the stub held by the processor
+	undelivered() (int, bool)
+}
+
+// decimationStep is the number of ticks at the given tick rate that make up a single tick at the desired rate.
+func decimationStep(tickRate int, rate float64) int {
+	if rate <= 0 || tickRate <= 0 || rate >= float64(tickRate) {
+		return 1
+	}
+
+	return int(math.Round(float64(tickRate) / rate))
+}
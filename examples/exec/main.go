@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/examples"
+)
+
+func main() {
+	// Parse the files into stubs
+	stubs, err := examples.ParseExampleStubs()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	groups := stubs.Group()
+	defer ibt.CloseAllStubs(groups)
+
+	for groupIdx, group := range groups {
+		// Start the python processor for this group. Its whitelist is declared during the handshake.
+		processor, err := ibt.NewExecProcessor(ibt.ExecConfig{
+			Command: "python3",
+			Args:    []string{"examples/exec/max_speed.py"},
+			Stderr:  os.Stderr,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// The python process is stopped once all stubs of the group have been processed
+		if err := ibt.Process(context.Background(), group, processor); err != nil {
+			processor.Close()
+			log.Fatalf("failed to process telemetry for group %d: %v", groupIdx, err)
+		}
+
+		// Print the results returned by the python processor
+		for _, result := range processor.Results() {
+			fmt.Printf("Max speed per lap for group %d: %s\n", groupIdx, result)
+		}
+	}
+}
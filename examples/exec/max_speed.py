@@ -0,0 +1,41 @@
+"""External processor tracking the maximum speed of each lap.
+
+Speaks the ibt ExecProcessor protocol using newline-delimited JSON on stdin and stdout.
+"""
+import json
+import sys
+
+
+def reply(message):
+    sys.stdout.write(json.dumps(message) + "\n")
+    sys.stdout.flush()
+
+
+def main():
+    max_speeds = {}
+
+    for line in sys.stdin:
+        message = json.loads(line)
+        kind = message["type"]
+
+        if kind == "hello":
+            if message.get("framing", "ndjson") != "ndjson":
+                reply({"error": "only ndjson framing is supported"})
+                return
+            reply({"whitelist": ["Lap", "Speed"]})
+        elif kind == "tick":
+            values = message["values"]
+            lap, speed = values.get("Lap"), values.get("Speed")
+            if lap is None or speed is None:
+                reply({"error": "tick is missing Lap or Speed"})
+                continue
+            max_speeds[lap] = max(speed, max_speeds.get(lap, speed))
+            reply({})
+        elif kind == "finish":
+            reply({"result": max_speeds})
+        else:
+            reply({})
+
+
+if __name__ == "__main__":
+    main()
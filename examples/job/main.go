@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/teamjorge/ibt"
+)
+
+func main() {
+	flag.Parse()
+
+	// Use the included job config if none was provided
+	path := "examples/job/job.yaml"
+	if flag.Arg(0) != "" {
+		path = flag.Arg(0)
+	}
+
+	// The processors and outputs referred to by the config are registered in init
+	job, err := ibt.LoadJob(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := job.Run(context.Background()); err != nil {
+		log.Fatalf("failed to run job %s: %v", job.Config.Name, err)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"golang.org/x/exp/maps"
+)
+
+// Register the processors and outputs so they can be referred to by name in job configs
+func init() {
+	ibt.Register("max-value", newMaxValueProcessor)
+	ibt.RegisterOutput("stdout", newStdoutOutput)
+}
+
+// maxValueProcessor tracks the maximum value of a telemetry variable for each lap
+type maxValueProcessor struct {
+	variable string
+	maxMap   map[int]float32
+}
+
+// newMaxValueProcessor creates a maxValueProcessor from the params of a job config
+func newMaxValueProcessor(params ibt.Params) (ibt.Processor, error) {
+	var cfg struct {
+		Variable string `yaml:"variable"`
+	}
+	if err := params.Decode(&cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.Variable == "" {
+		return nil, fmt.Errorf("max-value requires a variable")
+	}
+
+	return &maxValueProcessor{variable: cfg.Variable, maxMap: make(map[int]float32)}, nil
+}
+
+// Method used for processing every tick of telemetry
+func (m *maxValueProcessor) Process(input ibt.Tick, hasNext bool, session *headers.Session) error {
+	value, err := ibt.GetTickValue[float32](input, m.variable)
+	if err != nil {
+		return err
+	}
+
+	lap, err := ibt.GetTickValue[int](input, "Lap")
+	if err != nil {
+		return err
+	}
+
+	if current, ok := m.maxMap[lap]; !ok || value > current {
+		m.maxMap[lap] = value
+	}
+
+	return nil
+}
+
+// Columns required for the processor
+func (m *maxValueProcessor) Whitelist() []string { return []string{"Lap", m.variable} }
+
+// stdoutOutput prints the results of each group
+type stdoutOutput struct{}
+
+func newStdoutOutput(params ibt.Params) (ibt.Output, error) { return stdoutOutput{}, nil }
+
+// Write the per-lap maximum values of the group
+func (stdoutOutput) Write(result ibt.GroupResult) error {
+	fmt.Printf("Group %d:\n", result.Index)
+
+	for _, proc := range result.Processors {
+		maxValue, ok := proc.(*maxValueProcessor)
+		if !ok {
+			continue
+		}
+
+		fmt.Printf("Max %s:\n", maxValue.variable)
+		laps := maps.Keys(maxValue.maxMap)
+		sort.Ints(laps)
+
+		for _, lap := range laps {
+			fmt.Printf("%03d - %.3f\n", lap, maxValue.maxMap[lap])
+		}
+	}
+
+	return nil
+}
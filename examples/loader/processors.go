@@ -30,6 +30,18 @@ func (l *loaderProcessor) Whitelist() []string {
 }
 
 // Our method for processing a single tick of telemetry.
+//
+// Implementing ProcessContext allows us to receive the location and time of each tick.
+func (l *loaderProcessor) ProcessContext(input ibt.Tick, tickCtx ibt.TickContext, hasNext bool, session *headers.Session) error {
+	// Add the position and time of the tick in the group to the tick of telemetry.
+	// This will be useful to order ticks in our storage.
+	input["tickNum"] = tickCtx.GroupIndex
+	input["timestamp"] = tickCtx.Time
+
+	return l.Process(input, hasNext, session)
+}
+
+// Process a single tick of telemetry. This is used directly when no tick context is available.
 func (l *loaderProcessor) Process(input ibt.Tick, hasNext bool, session *headers.Session) error {
 	// Add our group number to the tick of telemetry.
 	// This will be useful to seperate ticks by group in our storage.
@@ -31,6 +31,6 @@ func main() {
 		}
 
 		// Print the summarised track temperature
-		processor.Print()
+		printTrackTemp(processor.Result())
 	}
 }
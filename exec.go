@@ -0,0 +1,742 @@
+package ibt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/teamjorge/ibt/headers"
+)
+
+const (
+	// Version of the protocol spoken with external processors
+	execProtocolVersion int = 1
+	// Default time allowed for an external processor to complete the handshake
+	defaultExecStartTimeout time.Duration = 10 * time.Second
+	// Default time allowed for an external processor to reply to a message
+	defaultExecTimeout time.Duration = 5 * time.Second
+	// Number of stderr bytes included in the error when an external processor exits unexpectedly
+	execStderrTail int = 2048
+	// Largest frame accepted from an external processor using FramingLengthPrefixed or FramingBinary
+	execMaxFrameSize uint32 = 64 << 20
+)
+
+// Kinds of the messages sent to an external processor using FramingBinary
+const (
+	execJSONMessage byte = iota
+	execTickMessage
+)
+
+var (
+	// ErrExecExited is returned when an external processor exits unexpectedly.
+	ErrExecExited = errors.New("external processor exited")
+	// ErrExecTimeout is returned when an external processor does not reply in time.
+	ErrExecTimeout = errors.New("external processor timed out")
+)
+
+// ExecFraming determines how messages are framed on the stdin and stdout of an external processor.
+type ExecFraming int
+
+const (
+	// FramingNDJSON sends every message as a single line of JSON. This is the default.
+	FramingNDJSON ExecFraming = iota
+	// FramingLengthPrefixed prefixes every JSON message with its length as a big endian uint32, which avoids
+	// scanning for the end of each line.
+	FramingLengthPrefixed
+	// FramingBinary sends ticks as typed fixed-width values keyed by the index of their variable, avoiding
+	// the cost of encoding them as JSON. Every message is prefixed with its length as in FramingLengthPrefixed.
+	// See ExecProcessor for the layout of the messages.
+	FramingBinary
+)
+
+// String representation of the ExecFraming
+func (f ExecFraming) String() string {
+	switch f {
+	case FramingNDJSON:
+		return "ndjson"
+	case FramingLengthPrefixed:
+		return "length-prefixed"
+	case FramingBinary:
+		return "binary"
+	}
+
+	return fmt.Sprintf("ExecFraming(%d)", int(f))
+}
+
+// ParseExecFraming returns the ExecFraming matching the given string representation.
+//
+// An empty string results in the default FramingNDJSON.
+func ParseExecFraming(s string) (ExecFraming, error) {
+	switch s {
+	case "", FramingNDJSON.String():
+		return FramingNDJSON, nil
+	case FramingLengthPrefixed.String():
+		return FramingLengthPrefixed, nil
+	case FramingBinary.String():
+		return FramingBinary, nil
+	}
+
+	return FramingNDJSON, fmt.Errorf("unknown framing %q", s)
+}
+
+// ExecConfig configures the command of an ExecProcessor.
+type ExecConfig struct {
+	// Command to run, along with its arguments
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+	// Dir is the working directory of the command. Defaults to the current directory.
+	Dir string `yaml:"dir"`
+	// Env of the command in the form key=value. Defaults to the environment of the current process.
+	Env []string `yaml:"env"`
+	// Framing of the messages sent after the handshake
+	Framing ExecFraming `yaml:"-"`
+	// StartTimeout is the time allowed for the handshake. Defaults to 10 seconds.
+	StartTimeout time.Duration `yaml:"start_timeout"`
+	// Timeout is the time allowed for a reply to each message. Defaults to 5 seconds.
+	Timeout time.Duration `yaml:"timeout"`
+	// Stderr receives the stderr of the command. The tail of stderr is always included in crash errors.
+	Stderr io.Writer `yaml:"-"`
+	// RestartOnCrash restarts the command at the start of the next stub after it exited unexpectedly.
+	RestartOnCrash bool `yaml:"restart_on_crash"`
+}
+
+// ExecProcessor processes ticks with an external command, allowing processors to be written in any language.
+//
+// The command communicates over its stdin and stdout. Each message sent to the command is a JSON object
+// with a type, to which the command replies with a single JSON object:
+//
+//	{"type": "hello", "version": 1, "framing": "ndjson"}                         -> {"whitelist": ["Speed", "Lap"]}
+//	{"type": "start_stub", "stub": {"index": 0, "filename": "..."}}              -> {}
+//	{"type": "tick", "index": 0, "session_time": 932.1, "has_next": true, "values": {"Speed": 1.2}} -> {}
+//	{"type": "end_stub", "stub": {"index": 0, "filename": "..."}}                -> {"result": ...}
+//	{"type": "finish"}                                                           -> {"result": ...}
+//
+// The hello handshake is always sent as a single line, after which the configured framing is used. An empty
+// whitelist in the handshake reply results in all variables being sent. Values that cannot be represented
+// in JSON, such as NaN, are sent as null, while arrays of bytes are sent as arrays of numbers. A reply
+// containing an error, such as {"error": "invalid value"}, is returned as the error of the message. Any
+// result included in a reply is collected and available from Results.
+//
+// With FramingBinary, replies are still JSON, while the first byte of every message sent to the command
+// indicates its kind. A kind of 0 is followed by a JSON message, while a kind of 1 is followed by a tick.
+// Before the first tick containing a variable that has not been declared, the variables are declared with
+// a JSON message assigning them the next indices, starting at 0:
+//
+//	{"type": "vars", "vars": [{"index": 0, "name": "Speed", "type": "float32", "count": 1}]} -> {}
+//
+// A variable is declared again with a new index when its type or count changes. The type is one of uint8,
+// bool, int32, bitfield (uint32), float32 or float64. A tick is encoded in little endian as its int32 index,
+// float64 session time, uint8 has next flag and uint16 number of values, followed by the uint16 index of
+// the variable and count fixed-width values of its type for each value.
+//
+// The command is stopped after Finish, or when Close is called.
+type ExecProcessor struct {
+	cfg       ExecConfig
+	whitelist []string
+	results   []json.RawMessage
+
+	// Variables declared to the command when using FramingBinary
+	vars     map[string]execVar
+	varCount int
+
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writer  *bufio.Writer
+	reader  *bufio.Reader
+	stderr  *tailWriter
+	running bool
+}
+
+func init() { Register("exec", newExecProcessorFromParams) }
+
+// newExecProcessorFromParams creates an ExecProcessor from the params of a JobConfig, for example:
+//
+//	processors:
+//	  - name: exec
+//	    params:
+//	      command: python3
+//	      args: [lap_times.py]
+//	      framing: length-prefixed
+//	      timeout: 2s
+func newExecProcessorFromParams(params Params) (Processor, error) {
+	var cfg struct {
+		ExecConfig `yaml:",inline"`
+		Framing    string `yaml:"framing"`
+	}
+	if err := params.Decode(&cfg); err != nil {
+		return nil, err
+	}
+
+	framing, err := ParseExecFraming(cfg.Framing)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ExecConfig.Framing = framing
+
+	return NewExecProcessor(cfg.ExecConfig)
+}
+
+// NewExecProcessor starts the configured command and completes the handshake.
+func NewExecProcessor(cfg ExecConfig) (*ExecProcessor, error) {
+	if cfg.Command == "" {
+		return nil, errors.New("exec processor requires a command")
+	}
+	if cfg.StartTimeout <= 0 {
+		cfg.StartTimeout = defaultExecStartTimeout
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultExecTimeout
+	}
+
+	p := &ExecProcessor{cfg: cfg}
+	if err := p.start(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// start the command and perform the handshake
+func (p *ExecProcessor) start() error {
+	p.cmd = exec.Command(p.cfg.Command, p.cfg.Args...)
+	p.cmd.Dir = p.cfg.Dir
+	p.cmd.Env = p.cfg.Env
+	p.cmd.WaitDelay = p.cfg.Timeout
+
+	p.stderr = &tailWriter{size: execStderrTail}
+	p.cmd.Stderr = p.stderr
+	if p.cfg.Stderr != nil {
+		p.cmd.Stderr = io.MultiWriter(p.cfg.Stderr, p.stderr)
+	}
+
+	stdin, err := p.cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdin for %s: %w", p.cfg.Command, err)
+	}
+	stdout, err := p.cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdout for %s: %w", p.cfg.Command, err)
+	}
+
+	if err := p.cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", p.cfg.Command, err)
+	}
+
+	p.stdin = stdin
+	p.writer = bufio.NewWriter(stdin)
+	p.reader = bufio.NewReader(stdout)
+	p.running = true
+	p.vars, p.varCount = make(map[string]execVar), 0
+
+	hello := execMessage{Type: "hello", Version: execProtocolVersion, Framing: p.cfg.Framing.String()}
+	reply, err := p.request(hello, FramingNDJSON, p.cfg.StartTimeout)
+	if err != nil {
+		p.kill()
+		return fmt.Errorf("handshake with %s failed: %w", p.cfg.Command, err)
+	}
+
+	p.whitelist = reply.Whitelist
+
+	return nil
+}
+
+// Whitelist declared by the command during the handshake
+func (p *ExecProcessor) Whitelist() []string { return p.whitelist }
+
+// Results collected from the replies of the command, in the order they were received
+func (p *ExecProcessor) Results() []json.RawMessage { return p.results }
+
+// Process the tick without a TickContext
+func (p *ExecProcessor) Process(input Tick, hasNext bool, session *headers.Session) error {
+	return p.ProcessContext(input, TickContext{}, hasNext, session)
+}
+
+// ProcessContext sends the tick to the command and waits for its reply
+func (p *ExecProcessor) ProcessContext(input Tick, tickCtx TickContext, hasNext bool, session *headers.Session) error {
+	if p.cfg.Framing == FramingBinary {
+		return p.processBinary(input, tickCtx, hasNext)
+	}
+
+	values := make(map[string]interface{}, len(input))
+	for key, value := range input {
+		values[key] = execValue(value)
+	}
+
+	msg := execMessage{
+		Type:        "tick",
+		Index:       &tickCtx.Index,
+		SessionTime: tickCtx.SessionTime,
+		HasNext:     &hasNext,
+		Values:      values,
+	}
+
+	_, err := p.request(msg, p.cfg.Framing, p.cfg.Timeout)
+
+	return err
+}
+
+// processBinary declares any new variables of the tick and sends it as a binary message
+func (p *ExecProcessor) processBinary(input Tick, tickCtx TickContext, hasNext bool) error {
+	if !p.running {
+		return fmt.Errorf("%w: %s is not running", ErrExecExited, p.cfg.Command)
+	}
+
+	names := make([]string, 0, len(input))
+	for name := range input {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	declared := make([]execVar, 0)
+	for _, name := range names {
+		varType, count, err := execBinaryType(input[name])
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", name, err)
+		}
+
+		if v, ok := p.vars[name]; ok && v.Type == varType && v.Count == count {
+			continue
+		}
+
+		v := execVar{Index: p.varCount, Name: name, Type: varType, Count: count}
+		p.vars[name] = v
+		p.varCount++
+		declared = append(declared, v)
+	}
+
+	if len(declared) > 0 {
+		if _, err := p.request(execMessage{Type: "vars", Vars: declared}, FramingBinary, p.cfg.Timeout); err != nil {
+			return err
+		}
+	}
+
+	payload := encodeExecTick(input, names, p.vars, tickCtx.Index, tickCtx.SessionTime, hasNext)
+	_, err := p.send("tick", payload, FramingBinary, p.cfg.Timeout)
+
+	return err
+}
+
+// StartStub notifies the command of the new stub. The command is restarted first if it crashed
+// and RestartOnCrash is enabled.
+func (p *ExecProcessor) StartStub(stub StubInfo) error {
+	if !p.running && p.cfg.RestartOnCrash {
+		if err := p.start(); err != nil {
+			return err
+		}
+	}
+
+	_, err := p.request(execMessage{Type: "start_stub", Stub: newExecStub(stub)}, p.cfg.Framing, p.cfg.Timeout)
+
+	return err
+}
+
+// EndStub notifies the command that the stub has been processed
+func (p *ExecProcessor) EndStub(stub StubInfo) error {
+	_, err := p.request(execMessage{Type: "end_stub", Stub: newExecStub(stub)}, p.cfg.Framing, p.cfg.Timeout)
+
+	return err
+}
+
+// Finish notifies the command that all stubs have been processed and stops it
+func (p *ExecProcessor) Finish() error {
+	_, err := p.request(execMessage{Type: "finish"}, p.cfg.Framing, p.cfg.Timeout)
+
+	return errors.Join(err, p.Close())
+}
+
+// Close stops the command by closing its stdin. The command is killed if it does not exit within the timeout.
+func (p *ExecProcessor) Close() error {
+	if !p.running {
+		return nil
+	}
+	p.running = false
+
+	p.stdin.Close()
+
+	timer := time.AfterFunc(p.cfg.Timeout, func() { p.cmd.Process.Kill() })
+	defer timer.Stop()
+
+	if err := p.cmd.Wait(); err != nil {
+		return fmt.Errorf("%s did not exit cleanly: %w", p.cfg.Command, err)
+	}
+
+	return nil
+}
+
+// request encodes the message as JSON, sends it to the command and reads its reply
+func (p *ExecProcessor) request(msg execMessage, framing ExecFraming, timeout time.Duration) (execReply, error) {
+	var reply execReply
+
+	if !p.running {
+		return reply, fmt.Errorf("%w: %s is not running", ErrExecExited, p.cfg.Command)
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return reply, fmt.Errorf("failed to encode %s message: %w", msg.Type, err)
+	}
+	if framing == FramingBinary {
+		payload = append([]byte{execJSONMessage}, payload...)
+	}
+
+	return p.send(msg.Type, payload, framing, timeout)
+}
+
+// send the encoded message to the command and read its reply.
+//
+// The command is killed when it does not reply within the timeout.
+func (p *ExecProcessor) send(msgType string, payload []byte, framing ExecFraming, timeout time.Duration) (execReply, error) {
+	var reply execReply
+
+	// The command is only killed while the reply is outstanding, so a timer firing after the reply was read
+	// but before it was stopped has no effect
+	var state atomic.Int32
+	timer := time.AfterFunc(timeout, func() {
+		if state.CompareAndSwap(execAwaiting, execTimedOut) {
+			p.cmd.Process.Kill()
+		}
+	})
+
+	var raw []byte
+	err := writeFrame(p.writer, payload, framing)
+	if err == nil {
+		raw, err = readFrame(p.reader, framing)
+	}
+
+	timedOut := !state.CompareAndSwap(execAwaiting, execReplied)
+	timer.Stop()
+
+	if err != nil {
+		return reply, p.exited(err, timedOut)
+	}
+	if timedOut {
+		// The reply was read after the command was killed, so the reply is used but the command is stopped
+		p.kill()
+	}
+
+	if err := json.Unmarshal(raw, &reply); err != nil {
+		return reply, fmt.Errorf("invalid reply from %s to %s message: %w", p.cfg.Command, msgType, err)
+	}
+
+	if len(reply.Result) > 0 {
+		p.results = append(p.results, reply.Result)
+	}
+
+	if reply.Error != "" {
+		return reply, errors.New(reply.Error)
+	}
+
+	return reply, nil
+}
+
+// States of a request, which determine whether its timer may kill the command
+const (
+	execAwaiting int32 = iota
+	execReplied
+	execTimedOut
+)
+
+// kill the command if it is still running and wait for it to exit
+func (p *ExecProcessor) kill() error {
+	if !p.running {
+		return nil
+	}
+	p.running = false
+	p.cmd.Process.Kill()
+
+	return p.cmd.Wait()
+}
+
+// exited waits for a command that stopped communicating and describes why it exited
+func (p *ExecProcessor) exited(cause error, timedOut bool) error {
+	waitErr := p.kill()
+
+	if timedOut {
+		return fmt.Errorf("%w: %s did not reply within %s", ErrExecTimeout, p.cfg.Command, p.cfg.Timeout)
+	}
+
+	if waitErr == nil {
+		waitErr = cause
+	}
+
+	if stderr := p.stderr.String(); stderr != "" {
+		return fmt.Errorf("%w: %s: %v: %s", ErrExecExited, p.cfg.Command, waitErr, stderr)
+	}
+
+	return fmt.Errorf("%w: %s: %v", ErrExecExited, p.cfg.Command, waitErr)
+}
+
+// execMessage is a message sent to an external processor
+type execMessage struct {
+	Type        string                 `json:"type"`
+	Version     int                    `json:"version,omitempty"`
+	Framing     string                 `json:"framing,omitempty"`
+	Stub        *execStub              `json:"stub,omitempty"`
+	Index       *int                   `json:"index,omitempty"`
+	SessionTime float64                `json:"session_time,omitempty"`
+	HasNext     *bool                  `json:"has_next,omitempty"`
+	Values      map[string]interface{} `json:"values,omitempty"`
+	Vars        []execVar              `json:"vars,omitempty"`
+}
+
+// execVar declares a variable sent to an external processor using FramingBinary
+type execVar struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+// execStub describes a stub to an external processor
+type execStub struct {
+	Index    int    `json:"index"`
+	Filename string `json:"filename"`
+}
+
+func newExecStub(stub StubInfo) *execStub {
+	return &execStub{Index: stub.Index, Filename: stub.Filename}
+}
+
+// execReply is the reply of an external processor to a message
+type execReply struct {
+	Whitelist []string        `json:"whitelist,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// execValue replaces values that cannot be represented in JSON, such as NaN, with null
+func execValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float32:
+		if !isFinite(float64(v)) {
+			return nil
+		}
+	case float64:
+		if !isFinite(v) {
+			return nil
+		}
+	case []float32:
+		for _, f := range v {
+			if !isFinite(float64(f)) {
+				return execValues(v)
+			}
+		}
+	case []float64:
+		for _, f := range v {
+			if !isFinite(f) {
+				return execValues(v)
+			}
+		}
+	case []uint8:
+		// Byte slices would otherwise be encoded as a base64 string
+		converted := make([]int, len(v))
+		for idx, b := range v {
+			converted[idx] = int(b)
+		}
+		return converted
+	}
+
+	return value
+}
+
+// execValues converts the slice to values that can be represented in JSON
+func execValues[T float32 | float64](values []T) []interface{} {
+	converted := make([]interface{}, len(values))
+	for idx, value := range values {
+		converted[idx] = execValue(value)
+	}
+
+	return converted
+}
+
+func isFinite(f float64) bool { return !math.IsNaN(f) && !math.IsInf(f, 0) }
+
+// execBinaryType returns the type and number of values used to encode the value with FramingBinary
+func execBinaryType(value interface{}) (string, int, error) {
+	switch v := value.(type) {
+	case uint8:
+		return "uint8", 1, nil
+	case bool:
+		return "bool", 1, nil
+	case int:
+		return "int32", 1, nil
+	case string:
+		if _, err := parseBitField(v); err != nil {
+			return "", 0, err
+		}
+		return "bitfield", 1, nil
+	case float32:
+		return "float32", 1, nil
+	case float64:
+		return "float64", 1, nil
+	case []uint8:
+		return "uint8", len(v), nil
+	case []bool:
+		return "bool", len(v), nil
+	case []int:
+		return "int32", len(v), nil
+	case []string:
+		for _, s := range v {
+			if _, err := parseBitField(s); err != nil {
+				return "", 0, err
+			}
+		}
+		return "bitfield", len(v), nil
+	case []float32:
+		return "float32", len(v), nil
+	case []float64:
+		return "float64", len(v), nil
+	}
+
+	return "", 0, fmt.Errorf("unsupported type %T", value)
+}
+
+// parseBitField parses a bitfield in the hexadecimal representation of the parser, such as 0x1f
+func parseBitField(s string) (uint32, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return 0, fmt.Errorf("invalid bitfield %q", s)
+	}
+
+	bits, err := strconv.ParseUint(s[2:], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bitfield %q", s)
+	}
+
+	return uint32(bits), nil
+}
+
+// encodeExecTick encodes the given variables of the tick as a binary message.
+//
+// All variables must have been declared with the type returned by execBinaryType.
+func encodeExecTick(input Tick, names []string, vars map[string]execVar, idx int, sessionTime float64, hasNext bool) []byte {
+	buf := make([]byte, 0, 16+len(names)*8)
+
+	buf = append(buf, execTickMessage)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(idx)))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(sessionTime))
+	buf = appendExecBool(buf, hasNext)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(names)))
+
+	for _, name := range names {
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(vars[name].Index))
+
+		switch v := input[name].(type) {
+		case uint8:
+			buf = append(buf, v)
+		case bool:
+			buf = appendExecBool(buf, v)
+		case int:
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(v)))
+		case string:
+			bits, _ := parseBitField(v)
+			buf = binary.LittleEndian.AppendUint32(buf, bits)
+		case float32:
+			buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(v))
+		case float64:
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+		case []uint8:
+			buf = append(buf, v...)
+		case []bool:
+			for _, b := range v {
+				buf = appendExecBool(buf, b)
+			}
+		case []int:
+			for _, i := range v {
+				buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(i)))
+			}
+		case []string:
+			for _, s := range v {
+				bits, _ := parseBitField(s)
+				buf = binary.LittleEndian.AppendUint32(buf, bits)
+			}
+		case []float32:
+			for _, f := range v {
+				buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(f))
+			}
+		case []float64:
+			for _, f := range v {
+				buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(f))
+			}
+		}
+	}
+
+	return buf
+}
+
+func appendExecBool(buf []byte, b bool) []byte {
+	if b {
+		return append(buf, 1)
+	}
+
+	return append(buf, 0)
+}
+
+// writeFrame writes and flushes the payload using the given framing
+func writeFrame(w *bufio.Writer, payload []byte, framing ExecFraming) error {
+	if framing != FramingNDJSON {
+		if err := binary.Write(w, binary.BigEndian, uint32(len(payload))); err != nil {
+			return err
+		}
+		if _, err := w.Write(payload); err != nil {
+			return err
+		}
+	} else {
+		if _, err := w.Write(payload); err != nil {
+			return err
+		}
+		if err := w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// readFrame reads a single payload using the given framing
+func readFrame(r *bufio.Reader, framing ExecFraming) ([]byte, error) {
+	if framing != FramingNDJSON {
+		var size uint32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return nil, err
+		}
+		if size > execMaxFrameSize {
+			return nil, fmt.Errorf("frame of %d bytes exceeds the maximum of %d bytes", size, execMaxFrameSize)
+		}
+
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return nil, err
+		}
+
+		return payload, nil
+	}
+
+	return r.ReadBytes('\n')
+}
+
+// tailWriter keeps the last bytes written to it
+type tailWriter struct {
+	size int
+	buf  []byte
+}
+
+func (t *tailWriter) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.size {
+		t.buf = t.buf[len(t.buf)-t.size:]
+	}
+
+	return len(p), nil
+}
+
+func (t *tailWriter) String() string { return string(t.buf) }
@@ -0,0 +1,378 @@
+package ibt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestExecHelperProcess is not a real test. It is started by the ExecProcessor tests as the external
+// processor, speaking the protocol over stdin and stdout.
+//
+// The behaviour of the helper is configured with the IBT_EXEC_MODE environment variable.
+func TestExecHelperProcess(t *testing.T) {
+	if os.Getenv("IBT_EXEC_HELPER") != "1" {
+		return
+	}
+
+	mode := os.Getenv("IBT_EXEC_MODE")
+	failAt, _ := strconv.Atoi(os.Getenv("IBT_EXEC_FAIL_AT"))
+
+	reader := bufio.NewReader(os.Stdin)
+	writer := bufio.NewWriter(os.Stdout)
+	framing := FramingNDJSON
+
+	reply := func(v interface{}) {
+		payload, _ := json.Marshal(v)
+		writeFrame(writer, payload, framing)
+	}
+
+	if mode == "bad-handshake" {
+		fmt.Println("not json")
+		os.Exit(0)
+	}
+
+	ticks := 0
+	vars := make(map[int]execVar)
+	for {
+		raw, err := readFrame(reader, framing)
+		if err != nil {
+			os.Exit(0)
+		}
+
+		var msg struct {
+			Type    string                 `json:"type"`
+			Framing string                 `json:"framing"`
+			Index   int                    `json:"index"`
+			Values  map[string]interface{} `json:"values"`
+			Vars    []execVar              `json:"vars"`
+		}
+
+		if framing == FramingBinary && raw[0] == execTickMessage {
+			msg.Type = "tick"
+			if msg.Index, _, _, msg.Values, err = decodeExecTick(raw, vars); err != nil {
+				reply(map[string]string{"error": err.Error()})
+				continue
+			}
+		} else {
+			if framing == FramingBinary {
+				raw = raw[1:]
+			}
+			json.Unmarshal(raw, &msg)
+		}
+
+		switch msg.Type {
+		case "vars":
+			for _, v := range msg.Vars {
+				vars[v.Index] = v
+			}
+			reply(struct{}{})
+		case "hello":
+			reply(map[string]interface{}{"whitelist": []string{"Speed", "Lap"}})
+			framing, _ = ParseExecFraming(msg.Framing)
+		case "tick":
+			ticks++
+			switch {
+			case mode == "crash" && msg.Index == failAt:
+				fmt.Fprint(os.Stderr, "helper crashed")
+				os.Exit(3)
+			case mode == "hang" && msg.Index == failAt:
+				time.Sleep(time.Minute)
+			case mode == "error" && msg.Index == failAt:
+				reply(map[string]string{"error": "helper error"})
+			case len(msg.Values) != 2:
+				reply(map[string]string{"error": fmt.Sprintf("expected 2 values. received %v", msg.Values)})
+			default:
+				reply(struct{}{})
+			}
+		case "end_stub":
+			reply(map[string]interface{}{"result": map[string]int{"ticks": ticks}})
+		case "finish":
+			reply(map[string]interface{}{"result": "done"})
+		default:
+			reply(struct{}{})
+		}
+	}
+}
+
+// decodeExecTick decodes a tick encoded with FramingBinary using the declared variables
+func decodeExecTick(raw []byte, vars map[int]execVar) (idx int, sessionTime float64, hasNext bool, values map[string]interface{}, err error) {
+	r := bytes.NewReader(raw[1:])
+
+	var header struct {
+		Index       int32
+		SessionTime float64
+		HasNext     uint8
+		Count       uint16
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return 0, 0, false, nil, err
+	}
+
+	values = make(map[string]interface{}, header.Count)
+	for i := 0; i < int(header.Count); i++ {
+		var varIdx uint16
+		if err := binary.Read(r, binary.LittleEndian, &varIdx); err != nil {
+			return 0, 0, false, nil, err
+		}
+
+		v, ok := vars[int(varIdx)]
+		if !ok {
+			return 0, 0, false, nil, fmt.Errorf("undeclared variable %d", varIdx)
+		}
+
+		var value interface{}
+		switch v.Type {
+		case "uint8":
+			value = make([]uint8, v.Count)
+		case "bool":
+			value = make([]bool, v.Count)
+		case "int32":
+			value = make([]int32, v.Count)
+		case "bitfield":
+			value = make([]uint32, v.Count)
+		case "float32":
+			value = make([]float32, v.Count)
+		case "float64":
+			value = make([]float64, v.Count)
+		}
+		if err := binary.Read(r, binary.LittleEndian, value); err != nil {
+			return 0, 0, false, nil, fmt.Errorf("failed to read %s: %w", v.Name, err)
+		}
+
+		values[v.Name] = value
+	}
+
+	if r.Len() > 0 {
+		return 0, 0, false, nil, fmt.Errorf("%d bytes remaining after the tick", r.Len())
+	}
+
+	return int(header.Index), header.SessionTime, header.HasNext == 1, values, nil
+}
+
+func newTestExecConfig(mode string, failAt int) ExecConfig {
+	return ExecConfig{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^TestExecHelperProcess$"},
+		Env:     append(os.Environ(), "IBT_EXEC_HELPER=1", "IBT_EXEC_MODE="+mode, "IBT_EXEC_FAIL_AT="+strconv.Itoa(failAt)),
+		Timeout: 5 * time.Second,
+	}
+}
+
+func TestExecProcessor(t *testing.T) {
+	stubs, err := ParseStubs(".testing/valid_test_file.ibt")
+	if err != nil {
+		t.Errorf("failed to parse stubs for testing file - %v", err)
+		return
+	}
+	defer stubs.Close()
+
+	for _, framing := range []ExecFraming{FramingNDJSON, FramingLengthPrefixed, FramingBinary} {
+		t.Run(fmt.Sprintf("test ExecProcessor with %s framing", framing), func(t *testing.T) {
+			cfg := newTestExecConfig("", 0)
+			cfg.Framing = framing
+
+			proc, err := NewExecProcessor(cfg)
+			if err != nil {
+				t.Errorf("expected NewExecProcessor() to run without err. received error: %v", err)
+				return
+			}
+			defer proc.Close()
+
+			if whitelist := proc.Whitelist(); len(whitelist) != 2 || whitelist[0] != "Speed" {
+				t.Errorf("expected whitelist from the handshake. received %v", whitelist)
+			}
+
+			if err := Process(context.Background(), stubs, proc); err != nil {
+				t.Errorf("expected Process() to run without err. received error: %v", err)
+			}
+
+			results := proc.Results()
+			if len(results) != 2 || string(results[0]) != `{"ticks":389}` || string(results[1]) != `"done"` {
+				t.Errorf("expected results from the end of the stub and finish. received %s", results)
+			}
+		})
+	}
+
+	t.Run("test ExecProcessor error reply", func(t *testing.T) {
+		proc, err := NewExecProcessor(newTestExecConfig("error", 10))
+		if err != nil {
+			t.Errorf("expected NewExecProcessor() to run without err. received error: %v", err)
+			return
+		}
+		defer proc.Close()
+
+		report, err := ProcessWithOptions(context.Background(), stubs, ProcessOptions{ErrorPolicy: CollectAll}, proc)
+		if err != nil {
+			t.Errorf("expected ProcessWithOptions() to run without err. received error: %v", err)
+		}
+
+		if len(report.Errors) != 1 || report.Errors[0].Tick != 10 || report.Errors[0].Err.Error() != "helper error" {
+			t.Errorf("expected the helper error at tick %d to be recorded. received %v", 10, report.Errors)
+		}
+	})
+
+	t.Run("test ExecProcessor crash", func(t *testing.T) {
+		cfg := newTestExecConfig("crash", 20)
+		cfg.RestartOnCrash = true
+
+		proc, err := NewExecProcessor(cfg)
+		if err != nil {
+			t.Errorf("expected NewExecProcessor() to run without err. received error: %v", err)
+			return
+		}
+		defer proc.Close()
+
+		err = Process(context.Background(), stubs, proc)
+		if !errors.Is(err, ErrExecExited) {
+			t.Errorf("expected Process() to return ErrExecExited. received: %v", err)
+		}
+
+		var processErr *ProcessError
+		if !errors.As(err, &processErr) || processErr.Tick != 20 {
+			t.Errorf("expected the crash to occur at tick %d. received: %v", 20, err)
+		}
+
+		if err == nil || !strings.Contains(err.Error(), "helper crashed") {
+			t.Errorf("expected the error to include the stderr of the helper. received: %v", err)
+		}
+
+		if err := proc.ProcessContext(Tick{}, TickContext{}, true, nil); !errors.Is(err, ErrExecExited) {
+			t.Errorf("expected ErrExecExited after the helper crashed. received: %v", err)
+		}
+
+		// The helper is restarted at the start of the next stub
+		if err := proc.StartStub(StubInfo{Filename: "restart.ibt"}); err != nil {
+			t.Errorf("expected StartStub() to restart the helper. received: %v", err)
+		}
+	})
+
+	t.Run("test ExecProcessor timeout", func(t *testing.T) {
+		cfg := newTestExecConfig("hang", 5)
+		cfg.Timeout = 200 * time.Millisecond
+
+		proc, err := NewExecProcessor(cfg)
+		if err != nil {
+			t.Errorf("expected NewExecProcessor() to run without err. received error: %v", err)
+			return
+		}
+		defer proc.Close()
+
+		if err := Process(context.Background(), stubs, proc); !errors.Is(err, ErrExecTimeout) {
+			t.Errorf("expected Process() to return ErrExecTimeout. received: %v", err)
+		}
+	})
+
+	t.Run("test ExecProcessor invalid handshake", func(t *testing.T) {
+		if _, err := NewExecProcessor(newTestExecConfig("bad-handshake", 0)); err == nil {
+			t.Error("expected NewExecProcessor() to return an error for an invalid handshake")
+		}
+
+		if _, err := NewExecProcessor(ExecConfig{Command: "ibt-command-that-does-not-exist"}); err == nil {
+			t.Error("expected NewExecProcessor() to return an error for a missing command")
+		}
+	})
+
+	t.Run("test ExecProcessor from registry", func(t *testing.T) {
+		cfg := newTestExecConfig("", 0)
+		params := Params{"command": cfg.Command, "args": cfg.Args, "env": cfg.Env, "framing": "length-prefixed", "timeout": "2s"}
+
+		proc, err := DefaultRegistry.NewProcessor("exec", params)
+		if err != nil {
+			t.Errorf("expected NewProcessor() to run without err. received error: %v", err)
+			return
+		}
+
+		execProc := proc.(*ExecProcessor)
+		defer execProc.Close()
+
+		if execProc.cfg.Framing != FramingLengthPrefixed || execProc.cfg.Timeout != 2*time.Second {
+			t.Errorf("expected params to configure the processor. received %+v", execProc.cfg)
+		}
+	})
+}
+
+func TestExecValue(t *testing.T) {
+	nan := float32(math.NaN())
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"a": execValue(nan),
+		"b": execValue([]float32{1, nan}),
+		"c": execValue(float64(2)),
+		"d": execValue([]uint8{1, 255}),
+	})
+	if err != nil {
+		t.Errorf("expected values to be encoded without err. received error: %v", err)
+	}
+
+	if string(payload) != `{"a":null,"b":[1,null],"c":2,"d":[1,255]}` {
+		t.Errorf("expected NaN values to be encoded as null and bytes as numbers. received %s", payload)
+	}
+}
+
+func TestEncodeExecTick(t *testing.T) {
+	input := Tick{
+		"Speed":        float32(math.NaN()),
+		"SessionTime":  float64(12.5),
+		"Lap":          3,
+		"IsOnTrack":    true,
+		"SessionFlags": "0x1f",
+		"CarIdxLap":    []int{1, -1},
+		"Gears":        []uint8{1, 255},
+	}
+
+	names := make([]string, 0, len(input))
+	vars := make(map[string]execVar)
+	declared := make(map[int]execVar)
+	for name, value := range input {
+		varType, count, err := execBinaryType(value)
+		if err != nil {
+			t.Errorf("expected the type of %s to be supported. received error: %v", name, err)
+			return
+		}
+
+		v := execVar{Index: len(names), Name: name, Type: varType, Count: count}
+		names = append(names, name)
+		vars[name], declared[v.Index] = v, v
+	}
+
+	idx, sessionTime, hasNext, values, err := decodeExecTick(encodeExecTick(input, names, vars, 7, 12.5, true), declared)
+	if err != nil {
+		t.Errorf("expected the tick to be decoded without err. received error: %v", err)
+		return
+	}
+
+	if idx != 7 || sessionTime != 12.5 || !hasNext || len(values) != len(input) {
+		t.Errorf("expected tick %d at %.1f with %d values. received tick %d at %f with %v", 7, 12.5, len(input), idx, sessionTime, values)
+	}
+
+	if speed := values["Speed"].([]float32); !math.IsNaN(float64(speed[0])) {
+		t.Errorf("expected NaN to be sent as is. received %v", speed)
+	}
+	if lap := values["CarIdxLap"].([]int32); lap[0] != 1 || lap[1] != -1 || values["Lap"].([]int32)[0] != 3 {
+		t.Errorf("expected ints to be sent as int32. received %v and %v", lap, values["Lap"])
+	}
+	if flags := values["SessionFlags"].([]uint32); flags[0] != 0x1f || !values["IsOnTrack"].([]bool)[0] {
+		t.Errorf("expected the bitfield and bool to be sent. received %v and %v", flags, values["IsOnTrack"])
+	}
+	if gears := values["Gears"].([]uint8); gears[1] != 255 {
+		t.Errorf("expected bytes to be sent as is. received %v", gears)
+	}
+
+	if _, _, err := execBinaryType(map[string]int{}); err == nil {
+		t.Error("expected execBinaryType() to fail for an unsupported type")
+	}
+	if _, _, err := execBinaryType("fast"); err == nil {
+		t.Error("expected execBinaryType() to fail for a string that is not a bitfield")
+	}
+}
@@ -0,0 +1,390 @@
+// Package fuel measures the fuel used on every lap and plans the fuel required to finish a race.
+//
+// A Tracker records the fuel used per lap from FuelLevel, with statistics for all laps and for laps driven
+// under green flag conditions. Its Result projects the laps remaining with the current fuel and, when the
+// race is limited by laps or time, plans the pit stops required to finish it. Result can be called at any
+// time, which allows a Tracker to be used with live telemetry as well as with completed files.
+package fuel
+
+import (
+	"math"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/laps"
+)
+
+const (
+	// Caution and caution waving flags of SessionFlags
+	cautionFlags uint32 = 0x4000 | 0x8000
+	// Smallest increase in liters of the fuel level between ticks that is considered refuelling
+	refuelThreshold float64 = 0.05
+)
+
+func init() {
+	ibt.Register("fuel", func(params ibt.Params) (ibt.Processor, error) {
+		var opts Options
+		if err := params.Decode(&opts); err != nil {
+			return nil, err
+		}
+
+		return NewTracker(opts), nil
+	})
+}
+
+// Options configures the planning of fuel.
+type Options struct {
+	// Margin in liters of fuel to finish the race with
+	Margin float64 `yaml:"margin"`
+}
+
+// Lap is the fuel used during a single lap.
+type Lap struct {
+	// Number of the lap, as reported by the Lap variable
+	Number int
+	// Stub is the index of the stub within the group being processed
+	Stub int
+	// Filename of the stub
+	Filename string
+	// StartFuel and EndFuel are the fuel levels in liters at the start and end of the lap
+	StartFuel float64
+	EndFuel   float64
+	// Used is the fuel in liters used during the lap. For laps during which the car was refuelled, this is
+	// estimated from FuelUsePerHour.
+	Used float64
+	// Time in seconds of the lap
+	Time float64
+	// Full indicates that the lap was driven from crossing the line until crossing it again
+	Full bool
+	// Green indicates that no caution flags were shown during the lap
+	Green bool
+	// Pitted indicates that the car was on pit road during the lap
+	Pitted bool
+	// Refuelled indicates that the fuel level increased during the lap
+	Refuelled bool
+}
+
+// Stats of the fuel used on full laps where the car did not enter pit road.
+type Stats struct {
+	// Laps included in the statistics
+	Laps int
+	// Average and Worst fuel used per lap in liters
+	Average float64
+	Worst   float64
+	// GreenLaps is the number of laps without caution flags
+	GreenLaps int
+	// GreenAverage is the average fuel used per lap in liters without caution flags
+	GreenAverage float64
+	// LapTime is the average time of a lap in seconds, preferring laps without caution flags
+	LapTime float64
+}
+
+// PerLap is the fuel used per lap used for projections, which is the GreenAverage when available
+func (s Stats) PerLap() float64 {
+	if s.GreenLaps > 0 {
+		return s.GreenAverage
+	}
+
+	return s.Average
+}
+
+// Result of the fuel tracked by a Tracker.
+type Result struct {
+	// Capacity of the tank in liters, as reported by the session
+	Capacity float64
+	// Fuel level in liters at the latest tick
+	Fuel float64
+	// Laps completed in the order they were driven
+	Laps  []Lap
+	Stats Stats
+	// LapsRemaining is the number of laps that can be driven with the current fuel
+	LapsRemaining float64
+	// Target is the remaining length of the race. This is nil when the session is not limited by laps or time.
+	Target *Target
+	// Plan of the fuel required to finish the race. This is nil when no Target or fuel usage is available.
+	Plan *Plan
+}
+
+// Tracker is a processor that tracks the fuel used on every lap.
+type Tracker struct {
+	opts Options
+	laps []Lap
+	car  headers.DriverInfo
+	sub  *headers.Sessions
+	// joined is the session whose DriverInfo is used as the car
+	joined *headers.Session
+
+	stub    ibt.StubInfo
+	current *Lap
+	// estimated is the fuel in liters used during the current lap according to FuelUsePerHour
+	estimated float64
+	prev      fuelTick
+	hasPrev   bool
+}
+
+// fuelTick is the part of a tick used for tracking fuel
+type fuelTick struct {
+	lap         int
+	pct         float64
+	sessionTime float64
+	// timeRemain is the number of seconds remaining in the sub-session
+	timeRemain float64
+	fuel       float64
+	usePerHour float64
+	onPitRoad  bool
+	flags      uint32
+}
+
+// NewTracker creates a fuel Tracker with the given options.
+func NewTracker(opts Options) *Tracker {
+	return &Tracker{opts: opts}
+}
+
+// Whitelist of the variables required for tracking fuel
+func (t *Tracker) Whitelist() []string {
+	return []string{"Lap", "LapDistPct", "FuelLevel", "FuelUsePerHour", "OnPitRoad", "SessionFlags", "SessionNum", "SessionTimeRemain"}
+}
+
+// StartStub completes the lap in progress of the previous stub
+func (t *Tracker) StartStub(stub ibt.StubInfo) error {
+	t.end()
+	t.stub = stub
+	t.hasPrev = false
+
+	return nil
+}
+
+// Process the tick without a TickContext, reading the SessionTime of the tick for the duration of each lap
+func (t *Tracker) Process(input ibt.Tick, hasNext bool, session *headers.Session) error {
+	return t.ProcessContext(input, ibt.ContextFromTick(input), hasNext, session)
+}
+
+// ProcessContext adds the fuel used since the previous tick to its lap
+func (t *Tracker) ProcessContext(input ibt.Tick, tickCtx ibt.TickContext, hasNext bool, session *headers.Session) error {
+	lap, err := ibt.GetTickValue[int](input, "Lap")
+	if err != nil {
+		return err
+	}
+
+	pct, err := ibt.GetTickValue[float32](input, "LapDistPct")
+	if err != nil {
+		return err
+	}
+
+	fuel, err := ibt.GetTickValue[float32](input, "FuelLevel")
+	if err != nil {
+		return err
+	}
+
+	// Flags and the usage rate are not available in every session
+	usePerHour, _ := ibt.GetTickValue[float32](input, "FuelUsePerHour")
+	onPitRoad, _ := ibt.GetTickValue[bool](input, "OnPitRoad")
+	flags, _ := ibt.GetTickBitField(input, "SessionFlags")
+
+	if session != nil {
+		if session != t.joined {
+			t.car = session.DriverInfo
+			t.joined = session
+		}
+
+		sessionNum, _ := ibt.GetTickValue[int](input, "SessionNum")
+		if t.sub == nil || t.sub.SessionNum != sessionNum {
+			if sub, ok := session.SubSession(sessionNum); ok {
+				t.sub = &sub
+			}
+		}
+	}
+
+	// SessionTime includes the time before the start of a race, such as gridding and pace laps, so the time
+	// remaining is only estimated from it when SessionTimeRemain is not available
+	timeRemain, err := ibt.GetTickValue[float64](input, "SessionTimeRemain")
+	if err != nil && t.sub != nil {
+		if duration, ok := t.sub.Duration(); ok {
+			timeRemain = duration - tickCtx.SessionTime
+		}
+	}
+
+	tick := fuelTick{
+		lap:         lap,
+		pct:         float64(pct),
+		sessionTime: tickCtx.SessionTime,
+		timeRemain:  timeRemain,
+		fuel:        float64(fuel),
+		usePerHour:  float64(usePerHour),
+		onPitRoad:   onPitRoad,
+		flags:       flags,
+	}
+
+	switch {
+	case t.current == nil:
+		t.start(tick, tick.fuel, tick.sessionTime, false)
+	case tick.lap == t.current.Number+1 && t.prev.pct-tick.pct > laps.MaxPctStep:
+		// The fuel level and time at the crossing of the line are interpolated between the ticks on either side of it
+		before, after := 1-t.prev.pct, tick.pct
+		fraction := 0.0
+		if before+after > 0 {
+			fraction = before / (before + after)
+		}
+
+		fuel := t.prev.fuel + (tick.fuel-t.prev.fuel)*fraction
+		crossing := t.prev.sessionTime + (tick.sessionTime-t.prev.sessionTime)*fraction
+
+		t.complete(fuel, crossing, true)
+		t.start(tick, fuel, crossing, true)
+	case tick.lap != t.current.Number || math.Abs(tick.pct-t.prev.pct) >= laps.MaxPctStep:
+		// The car was relocated, so neither lap was driven fully
+		t.complete(t.prev.fuel, t.prev.sessionTime, false)
+		t.start(tick, tick.fuel, tick.sessionTime, false)
+	default:
+		t.update(tick)
+	}
+
+	t.prev = tick
+	t.hasPrev = true
+
+	if !hasNext {
+		t.end()
+	}
+
+	return nil
+}
+
+// start the lap of the given tick with the given fuel level and time
+func (t *Tracker) start(tick fuelTick, fuel, startTime float64, full bool) {
+	t.current = &Lap{
+		Number:    tick.lap,
+		Stub:      t.stub.Index,
+		Filename:  t.stub.Filename,
+		StartFuel: fuel,
+		Time:      startTime,
+		Full:      full,
+		Green:     tick.flags&cautionFlags == 0,
+		Pitted:    tick.onPitRoad,
+	}
+	t.estimated = 0
+}
+
+// update the current lap with the fuel used since the previous tick
+func (t *Tracker) update(tick fuelTick) {
+	lap := t.current
+
+	if tick.fuel > t.prev.fuel+refuelThreshold {
+		lap.Refuelled = true
+	}
+
+	// FuelUsePerHour is reported in kilograms
+	if kgPerLtr := t.car.DriverCarFuelKgPerLtr; kgPerLtr > 0 {
+		t.estimated += tick.usePerHour / kgPerLtr * (tick.sessionTime - t.prev.sessionTime) / 3600
+	}
+
+	lap.Green = lap.Green && tick.flags&cautionFlags == 0
+	lap.Pitted = lap.Pitted || tick.onPitRoad
+}
+
+// complete the current lap with the given fuel level and time
+func (t *Tracker) complete(fuel, endTime float64, full bool) {
+	lap := t.current
+
+	lap.EndFuel = fuel
+	lap.Time = endTime - lap.Time
+	lap.Full = lap.Full && full
+	lap.Used = lap.StartFuel - lap.EndFuel
+	if lap.Refuelled {
+		lap.Used = t.estimated
+	}
+
+	t.laps = append(t.laps, *lap)
+	t.current = nil
+}
+
+// end the current stub, completing the lap in progress as a partial lap
+func (t *Tracker) end() {
+	if t.current != nil {
+		t.complete(t.prev.fuel, t.prev.sessionTime, false)
+	}
+}
+
+// Stats of the laps completed so far
+func (t *Tracker) Stats() Stats {
+	var stats Stats
+	var used, greenUsed, lapTime, greenLapTime float64
+
+	for _, lap := range t.laps {
+		if !lap.Full || lap.Pitted || lap.Refuelled || lap.Used <= 0 {
+			continue
+		}
+
+		stats.Laps++
+		used += lap.Used
+		lapTime += lap.Time
+		stats.Worst = math.Max(stats.Worst, lap.Used)
+
+		if lap.Green {
+			stats.GreenLaps++
+			greenUsed += lap.Used
+			greenLapTime += lap.Time
+		}
+	}
+
+	if stats.Laps > 0 {
+		stats.Average = used / float64(stats.Laps)
+		stats.LapTime = lapTime / float64(stats.Laps)
+	}
+
+	if stats.GreenLaps > 0 {
+		stats.GreenAverage = greenUsed / float64(stats.GreenLaps)
+		stats.LapTime = greenLapTime / float64(stats.GreenLaps)
+	}
+
+	return stats
+}
+
+// Capacity of the tank in liters, as reported by the session. DriverCarMaxFuelPct is the fraction of the
+// tank that may be filled.
+func (t *Tracker) Capacity() float64 {
+	capacity := float64(t.car.DriverCarFuelMaxLtr)
+	if t.car.DriverCarMaxFuelPct > 0 {
+		capacity *= float64(t.car.DriverCarMaxFuelPct)
+	}
+
+	return capacity
+}
+
+// Result of the fuel tracked so far, including the projection and plan from the latest tick
+func (t *Tracker) Result() Result {
+	result := Result{
+		Capacity: t.Capacity(),
+		Fuel:     t.prev.fuel,
+		Laps:     t.laps,
+		Stats:    t.Stats(),
+	}
+
+	perLap := result.Stats.PerLap()
+	if perLap > 0 {
+		result.LapsRemaining = result.Fuel / perLap
+	}
+
+	if t.sub == nil || !t.hasPrev {
+		return result
+	}
+
+	target, ok := TargetFromSession(*t.sub, t.prev.lap, t.prev.pct, t.prev.timeRemain)
+	if !ok {
+		return result
+	}
+	result.Target = &target
+
+	strategy := Strategy{
+		Fuel:     result.Fuel,
+		PerLap:   perLap,
+		LapTime:  result.Stats.LapTime,
+		Capacity: result.Capacity,
+		Margin:   t.opts.Margin,
+	}
+
+	if plan, err := strategy.Plan(target); err == nil {
+		result.Plan = &plan
+	}
+
+	return result
+}
@@ -0,0 +1,249 @@
+package fuel
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/ibttest"
+)
+
+var testSession = &headers.Session{
+	DriverInfo: headers.DriverInfo{DriverCarFuelMaxLtr: 50, DriverCarMaxFuelPct: 1, DriverCarFuelKgPerLtr: 0.75},
+	SessionInfo: headers.SessionInfo{Sessions: []headers.Sessions{
+		{SessionNum: 0, SessionLaps: "unlimited", SessionTime: "unlimited", SessionType: "Practice"},
+		{SessionNum: 1, SessionLaps: "20", SessionTime: "unlimited", SessionType: "Race"},
+	}},
+}
+
+// testUsage returns the fuel used in liters per lap, whether caution flags are shown and whether the car
+// is on pit road at the given lap and LapDistPct.
+//
+// Lap 3 uses more fuel, lap 4 is driven under caution and the car is refuelled with 20 liters at the
+// middle of lap 6.
+func testUsage(lap int, pct float64) (float64, bool, bool) {
+	switch lap {
+	case 3:
+		return 3, false, false
+	case 4:
+		return 1.5, true, false
+	case 6:
+		return 2.5, false, pct > 0.5 && pct < 0.7
+	}
+
+	return 2.5, false, false
+}
+
+// testTicks creates the ticks of 60 second laps from 0.9 of lap 1 until the middle of lap 7, starting with
+// 30 liters of fuel
+func testTicks() []ibt.Tick {
+	ticks := make([]ibt.Tick, 0)
+	fuel := 30.0
+
+	for idx := 0; ; idx++ {
+		pos := 0.9 + float64(idx)/3600
+		lap, pct := 1+int(math.Floor(pos)), pos-math.Floor(pos)
+		if lap == 7 && pct >= 0.5 {
+			break
+		}
+
+		perLap, caution, pitted := testUsage(lap, pct)
+
+		flags := "0x10004"
+		if caution {
+			flags = "0x14000"
+		}
+
+		if lap == 6 && pct >= 0.6 && pct < 0.6+1.0/3600 {
+			fuel += 20
+		}
+
+		ticks = append(ticks, ibt.Tick{
+			"SessionTime":    float64(idx) / 60,
+			"SessionNum":     1,
+			"Lap":            lap,
+			"LapDistPct":     float32(pct),
+			"FuelLevel":      float32(fuel),
+			"FuelUsePerHour": float32(perLap * 60 * 0.75),
+			"OnPitRoad":      pitted,
+			"SessionFlags":   flags,
+		})
+
+		fuel -= perLap / 3600
+	}
+
+	return ticks
+}
+
+var testStub = ibt.StubInfo{Index: 0, Filename: "test.ibt"}
+
+func TestTracker(t *testing.T) {
+	t.Run("test Tracker laps", func(t *testing.T) {
+		tracker := NewTracker(Options{})
+		ibttest.Process(t, tracker, testStub, testTicks(), testSession)
+
+		result := tracker.Result()
+		if len(result.Laps) != 7 {
+			t.Errorf("expected %d laps. received %d", 7, len(result.Laps))
+			return
+		}
+
+		tests := []struct {
+			used                           float64
+			full, green, pitted, refuelled bool
+		}{
+			{used: 0.25, green: true},
+			{used: 2.5, full: true, green: true},
+			{used: 3, full: true, green: true},
+			{used: 1.5, full: true},
+			{used: 2.5, full: true, green: true},
+			{used: 2.5, full: true, green: true, pitted: true, refuelled: true},
+			{used: 1.25, green: true},
+		}
+
+		for idx, test := range tests {
+			lap := result.Laps[idx]
+			if lap.Number != idx+1 || math.Abs(lap.Used-test.used) > 0.01 || lap.Filename != "test.ibt" {
+				t.Errorf("expected lap %d to use %.2f liters. received lap %d using %f", idx+1, test.used, lap.Number, lap.Used)
+			}
+			if lap.Full != test.full || lap.Green != test.green || lap.Pitted != test.pitted || lap.Refuelled != test.refuelled {
+				t.Errorf("expected lap %d to be %+v. received %+v", idx+1, test, lap)
+			}
+		}
+
+		if lap := result.Laps[1]; math.Abs(lap.Time-60) > 0.001 || math.Abs(lap.StartFuel-29.75) > 0.01 || math.Abs(lap.EndFuel-27.25) > 0.01 {
+			t.Errorf("expected lap 2 to take %d seconds from %.2f to %.2f liters. received %+v", 60, 29.75, 27.25, lap)
+		}
+	})
+
+	t.Run("test Tracker stats and plan", func(t *testing.T) {
+		tracker := NewTracker(Options{Margin: 1})
+		ibttest.Process(t, tracker, testStub, testTicks(), testSession)
+
+		result := tracker.Result()
+		stats := result.Stats
+		if stats.Laps != 4 || math.Abs(stats.Average-2.375) > 0.01 || math.Abs(stats.Worst-3) > 0.01 {
+			t.Errorf("expected an average of %.3f and worst of %d liters over %d laps. received %+v", 2.375, 3, 4, stats)
+		}
+
+		if stats.GreenLaps != 3 || math.Abs(stats.GreenAverage-8.0/3) > 0.01 || math.Abs(stats.PerLap()-8.0/3) > 0.01 || math.Abs(stats.LapTime-60) > 0.01 {
+			t.Errorf("expected a green average of %.3f liters over %d laps. received %+v", 8.0/3, 3, stats)
+		}
+
+		if result.Capacity != 50 || math.Abs(result.Fuel-36.5) > 0.01 || math.Abs(result.LapsRemaining-36.5/(8.0/3)) > 0.01 {
+			t.Errorf("expected %.1f liters to last %.2f laps. received %f and %f", 36.5, 36.5/(8.0/3), result.Fuel, result.LapsRemaining)
+		}
+
+		if result.Target == nil || math.Abs(result.Target.Laps-13.5) > 0.01 {
+			t.Errorf("expected %.1f laps of the race to remain. received %+v", 13.5, result.Target)
+			return
+		}
+
+		if plan := result.Plan; plan == nil || math.Abs(plan.FuelRequired-37) > 0.05 || math.Abs(plan.FuelToAdd-0.5) > 0.05 || plan.Stops != 1 {
+			t.Errorf("expected a single stop to add %.1f liters. received %+v", 0.5, plan)
+		}
+	})
+
+	t.Run("test Tracker without a session", func(t *testing.T) {
+		tracker := NewTracker(Options{})
+		ibttest.Process(t, tracker, testStub, testTicks(), nil)
+
+		result := tracker.Result()
+		if result.Capacity != 0 || result.Target != nil || result.Plan != nil || result.Stats.Laps != 4 {
+			t.Errorf("expected stats without a plan. received %+v", result)
+		}
+
+		// The usage during the lap with a refuel can not be estimated without the density of the fuel
+		if lap := result.Laps[5]; lap.Used != 0 || !lap.Refuelled {
+			t.Errorf("expected no usage for the refuelled lap. received %+v", lap)
+		}
+	})
+
+	t.Run("test Tracker live telemetry", func(t *testing.T) {
+		tracker := NewTracker(Options{})
+		ticks := testTicks()
+
+		tracker.StartStub(ibt.StubInfo{})
+		for _, tick := range ticks[:3600] {
+			if err := tracker.Process(tick, true, testSession); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		result := tracker.Result()
+		if len(result.Laps) != 1 || result.Stats.Laps != 0 || result.Target == nil || result.Plan != nil {
+			t.Errorf("expected a target without a plan before a full lap was completed. received %+v", result)
+		}
+	})
+
+	t.Run("test Tracker timed race", func(t *testing.T) {
+		session := &headers.Session{SessionInfo: headers.SessionInfo{Sessions: []headers.Sessions{
+			{SessionNum: 1, SessionLaps: "unlimited", SessionTime: "3600.0000 sec", SessionType: "Race"},
+		}}}
+
+		// The race started 90 seconds into the session, after the pace laps
+		ticks := testTicks()
+		for _, tick := range ticks {
+			tick["SessionTimeRemain"] = 3600 + 90 - tick["SessionTime"].(float64)
+		}
+
+		tracker := NewTracker(Options{})
+		ibttest.Process(t, tracker, testStub, ticks, session)
+
+		last := ticks[len(ticks)-1]["SessionTime"].(float64)
+		if target := tracker.Result().Target; target == nil || math.Abs(target.Time-(3690-last)) > 0.001 {
+			t.Errorf("expected %f seconds of the race to remain. received %+v", 3690-last, target)
+		}
+
+		// Without SessionTimeRemain, the time remaining is estimated from the SessionTime
+		tracker = NewTracker(Options{})
+		ibttest.Process(t, tracker, testStub, testTicks(), session)
+
+		if target := tracker.Result().Target; target == nil || math.Abs(target.Time-(3600-last)) > 0.001 {
+			t.Errorf("expected %f seconds of the race to remain. received %+v", 3600-last, target)
+		}
+	})
+
+	t.Run("test Tracker missing variables", func(t *testing.T) {
+		if err := NewTracker(Options{}).Process(ibt.Tick{"Lap": 1, "LapDistPct": float32(0.1)}, true, nil); err == nil {
+			t.Error("expected Process() to return an error when FuelLevel is missing")
+		}
+	})
+
+	t.Run("test Tracker from registry", func(t *testing.T) {
+		processor, err := ibt.DefaultRegistry.NewProcessor("fuel", ibt.Params{"margin": 2})
+		if err != nil {
+			t.Errorf("expected NewProcessor() to run without err. received error: %v", err)
+			return
+		}
+
+		tracker := processor.(*Tracker)
+		ibttest.Process(t, tracker, testStub, testTicks(), testSession)
+
+		if plan := tracker.Result().Plan; plan == nil || math.Abs(plan.FuelToAdd-1.5) > 0.05 {
+			t.Errorf("expected a margin of %d liters. received %+v", 2, plan)
+		}
+	})
+
+	t.Run("test Tracker with ibt.Process", func(t *testing.T) {
+		stubs, err := ibt.ParseStubs("../.testing/valid_test_file.ibt")
+		if err != nil {
+			t.Errorf("failed to parse stubs for testing file - %v", err)
+			return
+		}
+		defer stubs.Close()
+
+		tracker := NewTracker(Options{})
+		if err := ibt.Process(context.Background(), stubs, tracker); err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		// The testing file only contains a part of a lap during an unlimited session
+		result := tracker.Result()
+		if len(result.Laps) != 1 || result.Laps[0].Full || result.Capacity <= 0 || result.Fuel <= 0 || result.Target != nil {
+			t.Errorf("expected a partial lap of an unlimited session. received %+v", result)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package fuel
+
+import (
+	"errors"
+	"math"
+
+	"github.com/teamjorge/ibt/headers"
+)
+
+var (
+	// ErrNoUsage is returned when a plan is requested without the fuel used per lap.
+	ErrNoUsage = errors.New("no fuel usage available for planning")
+	// ErrNoTarget is returned when a plan is requested for a race without a limited number of laps or
+	// time, or for a time-limited race without a lap time.
+	ErrNoTarget = errors.New("no race length available for planning")
+)
+
+// Target is the remaining length of a race.
+type Target struct {
+	// Laps remaining in a lap-limited race. When 0, the laps are projected from the Time remaining.
+	Laps float64
+	// Time remaining in seconds of a time-limited race
+	Time float64
+	// Pct is the fraction of the current lap that was completed, used to project the laps of a
+	// time-limited race
+	Pct float64
+}
+
+// TargetFromSession returns the remaining length of the given sub-session for a car at the given lap and
+// LapDistPct, with the given number of seconds remaining in the sub-session as reported by
+// SessionTimeRemain. Laps before the current lap are considered completed.
+//
+// False is returned when the sub-session is neither limited by laps nor time.
+func TargetFromSession(sub headers.Sessions, lap int, pct, timeRemain float64) (Target, bool) {
+	if laps, ok := sub.Laps(); ok {
+		return Target{Laps: math.Max(0, float64(laps-lap+1)-pct)}, true
+	}
+
+	if _, ok := sub.Duration(); ok {
+		return Target{Time: math.Max(0, timeRemain), Pct: pct}, true
+	}
+
+	return Target{}, false
+}
+
+// Strategy contains the fuel state of a car used to plan the rest of a race.
+type Strategy struct {
+	// Fuel level in liters
+	Fuel float64
+	// PerLap is the fuel used per lap in liters
+	PerLap float64
+	// LapTime in seconds, used for projecting the laps of a time-limited race
+	LapTime float64
+	// Capacity of the tank in liters. When 0, the fuel is added in a single stop.
+	Capacity float64
+	// Margin in liters of fuel to finish the race with
+	Margin float64
+}
+
+// Plan of the fuel required to finish a race.
+type Plan struct {
+	// Laps remaining in the race
+	Laps float64
+	// FuelRequired in liters to finish the race, including the margin
+	FuelRequired float64
+	// FuelToAdd in liters during pit stops
+	FuelToAdd float64
+	// Stops required to add the fuel, assuming the tank is nearly empty at each stop
+	Stops int
+	// FuelPerStop in liters
+	FuelPerStop float64
+}
+
+// Plan the pit stops required to complete the target.
+//
+// The laps of a time-limited race are projected using the LapTime, where the lap during which the time
+// expires is completed.
+func (s Strategy) Plan(target Target) (Plan, error) {
+	if s.PerLap <= 0 {
+		return Plan{}, ErrNoUsage
+	}
+
+	var plan Plan
+
+	switch {
+	case target.Laps > 0:
+		plan.Laps = target.Laps
+	case target.Time > 0 && s.LapTime > 0:
+		plan.Laps = math.Ceil(target.Pct+target.Time/s.LapTime) - target.Pct
+	default:
+		return Plan{}, ErrNoTarget
+	}
+
+	plan.FuelRequired = plan.Laps*s.PerLap + s.Margin
+	plan.FuelToAdd = math.Max(0, plan.FuelRequired-s.Fuel)
+
+	if plan.FuelToAdd > 0 {
+		plan.Stops = 1
+		if s.Capacity > 0 {
+			plan.Stops = int(math.Ceil(plan.FuelToAdd / s.Capacity))
+		}
+		plan.FuelPerStop = plan.FuelToAdd / float64(plan.Stops)
+	}
+
+	return plan, nil
+}
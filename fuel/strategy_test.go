@@ -0,0 +1,74 @@
+package fuel
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/teamjorge/ibt/headers"
+)
+
+func TestTargetFromSession(t *testing.T) {
+	tests := []struct {
+		sub    headers.Sessions
+		target Target
+		ok     bool
+	}{
+		{sub: headers.Sessions{SessionLaps: "20", SessionTime: "unlimited"}, target: Target{Laps: 15.75}, ok: true},
+		{sub: headers.Sessions{SessionLaps: "unlimited", SessionTime: "3600.0000 sec"}, target: Target{Time: 2400, Pct: 0.25}, ok: true},
+		{sub: headers.Sessions{SessionLaps: "unlimited", SessionTime: "unlimited"}},
+	}
+
+	for _, test := range tests {
+		target, ok := TargetFromSession(test.sub, 5, 0.25, 2400)
+		if ok != test.ok || target != test.target {
+			t.Errorf("expected target %+v for %+v. received %+v", test.target, test.sub, target)
+		}
+	}
+}
+
+func TestStrategyPlan(t *testing.T) {
+	t.Run("test Plan() lap-limited race", func(t *testing.T) {
+		plan, err := Strategy{Fuel: 20, PerLap: 2}.Plan(Target{Laps: 8})
+		if err != nil {
+			t.Errorf("expected Plan() to run without err. received error: %v", err)
+		}
+
+		if plan.Laps != 8 || plan.FuelRequired != 16 || plan.FuelToAdd != 0 || plan.Stops != 0 {
+			t.Errorf("expected no stops to finish %d laps. received %+v", 8, plan)
+		}
+	})
+
+	t.Run("test Plan() time-limited race", func(t *testing.T) {
+		plan, err := Strategy{Fuel: 10, PerLap: 3, LapTime: 100, Capacity: 50}.Plan(Target{Time: 3600, Pct: 0.5})
+		if err != nil {
+			t.Errorf("expected Plan() to run without err. received error: %v", err)
+		}
+
+		// The time expires halfway through lap 37, which is completed
+		if plan.Laps != 36.5 || math.Abs(plan.FuelRequired-109.5) > 0.0001 || plan.Stops != 2 || math.Abs(plan.FuelPerStop-49.75) > 0.0001 {
+			t.Errorf("expected %d stops of %.2f liters to finish %.1f laps. received %+v", 2, 49.75, 36.5, plan)
+		}
+	})
+
+	t.Run("test Plan() without a capacity", func(t *testing.T) {
+		plan, _ := Strategy{Fuel: 10, PerLap: 3, Margin: 2}.Plan(Target{Laps: 40})
+		if plan.Stops != 1 || plan.FuelToAdd != 112 || plan.FuelPerStop != 112 {
+			t.Errorf("expected a single stop of %d liters. received %+v", 112, plan)
+		}
+	})
+
+	t.Run("test Plan() errors", func(t *testing.T) {
+		if _, err := (Strategy{Fuel: 10}).Plan(Target{Laps: 10}); !errors.Is(err, ErrNoUsage) {
+			t.Errorf("expected error %v. received %v", ErrNoUsage, err)
+		}
+
+		if _, err := (Strategy{Fuel: 10, PerLap: 2}).Plan(Target{Time: 600}); !errors.Is(err, ErrNoTarget) {
+			t.Errorf("expected error %v without a lap time. received %v", ErrNoTarget, err)
+		}
+
+		if _, err := (Strategy{Fuel: 10, PerLap: 2, LapTime: 60}).Plan(Target{}); !errors.Is(err, ErrNoTarget) {
+			t.Errorf("expected error %v without a target. received %v", ErrNoTarget, err)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package headers
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Conversion factors of distance units to meters
+var distanceUnits = map[string]float64{
+	"m":  1,
+	"km": 1000,
+	"mi": 1609.344,
+	"ft": 0.3048,
+}
+
+// Conversion factors of angle units to radians
+var angleUnits = map[string]float64{
+	"rad": 1,
+	"deg": math.Pi / 180,
+}
+
+// ParseUnitValue splits a session value such as "4.28 km" into its number and unit.
+//
+// The unit will be empty when the value does not contain one.
+func ParseUnitValue(value string) (float64, string, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || len(fields) > 2 {
+		return 0, "", fmt.Errorf("invalid session value %q", value)
+	}
+
+	number, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid session value %q: %w", value, err)
+	}
+
+	if len(fields) == 1 {
+		return number, "", nil
+	}
+
+	return number, fields[1], nil
+}
+
+// ParseDistance converts a session value such as "4.28 km" or "2.66 mi" to meters.
+func ParseDistance(value string) (float64, error) {
+	number, unit, err := ParseUnitValue(value)
+	if err != nil {
+		return 0, err
+	}
+
+	factor, ok := distanceUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown distance unit %q in %q", unit, value)
+	}
+
+	return number * factor, nil
+}
+
+// ParseAngle converts a session value such as "1.5876 rad" or "90 deg" to radians.
+func ParseAngle(value string) (float64, error) {
+	number, unit, err := ParseUnitValue(value)
+	if err != nil {
+		return 0, err
+	}
+
+	factor, ok := angleUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown angle unit %q in %q", unit, value)
+	}
+
+	return number * factor, nil
+}
+
+// TrackLengthMeters is the length of the track in meters
+func (w WeekendInfo) TrackLengthMeters() (float64, error) { return ParseDistance(w.TrackLength) }
+
+// TrackNorthOffsetRadians is the offset in radians between north and the yaw of the track.
+//
+// The heading of a car clockwise from north is the TrackNorthOffset minus its Yaw.
+func (w WeekendInfo) TrackNorthOffsetRadians() (float64, error) {
+	return ParseAngle(w.TrackNorthOffset)
+}
+
+// TrackCoordinates are the latitude and longitude of the track in decimal degrees. The unit of these
+// values is reported incorrectly as meters by iRacing, so it is ignored.
+func (w WeekendInfo) TrackCoordinates() (float64, float64, error) {
+	lat, _, err := ParseUnitValue(w.TrackLatitude)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	lon, _, err := ParseUnitValue(w.TrackLongitude)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return lat, lon, nil
+}
+
+// Laps of the sub-session. False is returned when the number of laps is unlimited.
+func (s Sessions) Laps() (int, bool) {
+	laps, err := strconv.Atoi(strings.TrimSpace(s.SessionLaps))
+	if err != nil || laps <= 0 {
+		return 0, false
+	}
+
+	return laps, true
+}
+
+// Duration of the sub-session in seconds, from a value such as "3600.0000 sec". False is returned when
+// the duration is unlimited.
+func (s Sessions) Duration() (float64, bool) {
+	number, unit, err := ParseUnitValue(s.SessionTime)
+	if err != nil || number <= 0 || (unit != "sec" && unit != "") {
+		return 0, false
+	}
+
+	return number, true
+}
+
+// SubSession returns the sub-session with the given SessionNum
+func (s *Session) SubSession(num int) (Sessions, bool) {
+	for _, sub := range s.SessionInfo.Sessions {
+		if sub.SessionNum == num {
+			return sub, true
+		}
+	}
+
+	return Sessions{}, false
+}
@@ -0,0 +1,157 @@
+package headers
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseUnitValue(t *testing.T) {
+	tests := []struct {
+		value  string
+		number float64
+		unit   string
+		err    bool
+	}{
+		{value: "4.28 km", number: 4.28, unit: "km"},
+		{value: "1.5876 rad", number: 1.5876, unit: "rad"},
+		{value: " 9 ", number: 9},
+		{value: "-12.5 C", number: -12.5, unit: "C"},
+		{value: "unlimited", err: true},
+		{value: "", err: true},
+		{value: "4.28 km extra", err: true},
+	}
+
+	for _, test := range tests {
+		number, unit, err := ParseUnitValue(test.value)
+		if test.err {
+			if err == nil {
+				t.Errorf("expected ParseUnitValue(%q) to return an error", test.value)
+			}
+			continue
+		}
+
+		if err != nil || number != test.number || unit != test.unit {
+			t.Errorf("expected ParseUnitValue(%q) to return %v %q. received %v %q (%v)", test.value, test.number, test.unit, number, unit, err)
+		}
+	}
+}
+
+func TestParseDistance(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected float64
+		err      bool
+	}{
+		{value: "4.28 km", expected: 4280},
+		{value: "2.5 mi", expected: 4023.36},
+		{value: "677.30 m", expected: 677.3},
+		{value: "4.28 rad", err: true},
+		{value: "4.28", err: true},
+	}
+
+	for _, test := range tests {
+		distance, err := ParseDistance(test.value)
+		if test.err {
+			if err == nil {
+				t.Errorf("expected ParseDistance(%q) to return an error", test.value)
+			}
+			continue
+		}
+
+		if err != nil || math.Abs(distance-test.expected) > 0.0001 {
+			t.Errorf("expected ParseDistance(%q) to return %v. received %v (%v)", test.value, test.expected, distance, err)
+		}
+	}
+}
+
+func TestParseAngle(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected float64
+		err      bool
+	}{
+		{value: "1.5876 rad", expected: 1.5876},
+		{value: "-90 deg", expected: -math.Pi / 2},
+		{value: "1.5876 km", err: true},
+		{value: "1.5876", err: true},
+	}
+
+	for _, test := range tests {
+		angle, err := ParseAngle(test.value)
+		if test.err {
+			if err == nil {
+				t.Errorf("expected ParseAngle(%q) to return an error", test.value)
+			}
+			continue
+		}
+
+		if err != nil || math.Abs(angle-test.expected) > 0.0001 {
+			t.Errorf("expected ParseAngle(%q) to return %v. received %v (%v)", test.value, test.expected, angle, err)
+		}
+	}
+}
+
+func TestTrackLengthMeters(t *testing.T) {
+	length, err := expectedSessionInfo.WeekendInfo.TrackLengthMeters()
+	if err != nil || math.Abs(length-4280) > 0.0001 {
+		t.Errorf("expected track length of %d meters. received %v (%v)", 4280, length, err)
+	}
+}
+
+func TestTrackNorthOffsetRadians(t *testing.T) {
+	offset, err := expectedSessionInfo.WeekendInfo.TrackNorthOffsetRadians()
+	if err != nil || offset != 1.5876 {
+		t.Errorf("expected a north offset of %.4f radians. received %v (%v)", 1.5876, offset, err)
+	}
+}
+
+func TestTrackCoordinates(t *testing.T) {
+	lat, lon, err := expectedSessionInfo.WeekendInfo.TrackCoordinates()
+	if err != nil || lat != 47.220305 || lon != 14.766722 {
+		t.Errorf("expected coordinates of %f, %f. received %f, %f (%v)", 47.220305, 14.766722, lat, lon, err)
+	}
+
+	if _, _, err := (WeekendInfo{TrackLatitude: "47.2 m"}).TrackCoordinates(); err == nil {
+		t.Error("expected TrackCoordinates() to return an error without a longitude")
+	}
+}
+
+func TestSessionsLimits(t *testing.T) {
+	tests := []struct {
+		session  Sessions
+		laps     int
+		duration float64
+	}{
+		{session: Sessions{SessionLaps: "30", SessionTime: "unlimited"}, laps: 30},
+		{session: Sessions{SessionLaps: "unlimited", SessionTime: "3600.0000 sec"}, duration: 3600},
+		{session: Sessions{SessionLaps: "unlimited", SessionTime: "unlimited"}},
+		{session: Sessions{SessionLaps: "0", SessionTime: "10 min"}},
+	}
+
+	for _, test := range tests {
+		laps, hasLaps := test.session.Laps()
+		if laps != test.laps || hasLaps != (test.laps > 0) {
+			t.Errorf("expected %d laps for %q. received %d (%v)", test.laps, test.session.SessionLaps, laps, hasLaps)
+		}
+
+		duration, hasDuration := test.session.Duration()
+		if duration != test.duration || hasDuration != (test.duration > 0) {
+			t.Errorf("expected a duration of %.0f for %q. received %f (%v)", test.duration, test.session.SessionTime, duration, hasDuration)
+		}
+	}
+}
+
+func TestSessionSubSession(t *testing.T) {
+	session := &Session{SessionInfo: SessionInfo{Sessions: []Sessions{
+		{SessionNum: 0, SessionType: "Practice"},
+		{SessionNum: 2, SessionType: "Race"},
+	}}}
+
+	if sub, ok := session.SubSession(2); !ok || sub.SessionType != "Race" {
+		t.Errorf("expected sub-session %d to be the race. received %+v", 2, sub)
+	}
+
+	if _, ok := session.SubSession(1); ok {
+		t.Errorf("expected sub-session %d not to exist", 1)
+	}
+}
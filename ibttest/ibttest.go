@@ -0,0 +1,49 @@
+// Package ibttest provides utilities for testing processors with ticks created by the test.
+package ibttest
+
+import (
+	"testing"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+)
+
+// Process the given ticks as a single stub, notifying the processor in the same way as ibt.Process.
+//
+// Processors implementing ibt.StubStarter and ibt.StubEnder are notified at the start and end of the stub,
+// while processors implementing ibt.ContextProcessor receive the index and session time of each tick. The
+// test fails immediately when the processor returns an error.
+func Process(t testing.TB, proc ibt.Processor, stub ibt.StubInfo, ticks []ibt.Tick, session *headers.Session) {
+	t.Helper()
+
+	if starter, ok := proc.(ibt.StubStarter); ok {
+		if err := starter.StartStub(stub); err != nil {
+			t.Fatalf("failed to start stub %d: %v", stub.Index, err)
+		}
+	}
+
+	contextProc, hasContext := proc.(ibt.ContextProcessor)
+
+	for idx, tick := range ticks {
+		hasNext := idx < len(ticks)-1
+
+		var err error
+		if hasContext {
+			tickCtx := ibt.ContextFromTick(tick)
+			tickCtx.Index, tickCtx.Stub = idx, stub
+			err = contextProc.ProcessContext(tick, tickCtx, hasNext, session)
+		} else {
+			err = proc.Process(tick, hasNext, session)
+		}
+
+		if err != nil {
+			t.Fatalf("failed to process tick %d of stub %d: %v", idx, stub.Index, err)
+		}
+	}
+
+	if ender, ok := proc.(ibt.StubEnder); ok {
+		if err := ender.EndStub(stub); err != nil {
+			t.Fatalf("failed to end stub %d: %v", stub.Index, err)
+		}
+	}
+}
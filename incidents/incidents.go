@@ -0,0 +1,374 @@
+// Package incidents detects incidents, off-track excursions and spins.
+//
+// A Detector emits an Event for every increase of PlayerCarMyIncidentCount, every time the car leaves the
+// track according to PlayerTrackSurface and every spin. Spins are detected when the heading of the car
+// rotates by more than a quarter turn while the yaw rate remains high, and the car points away from its
+// direction of travel according to VelocityX and VelocityY. Each Event carries the ticks
+// surrounding it, so a session can be reviewed from a list of events rather than the whole file.
+package incidents
+
+import (
+	"math"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/utilities/fifo"
+)
+
+const (
+	// Default number of ticks retained before and after each event
+	defaultWindow int = 120
+	// Default yaw rate in rad/s above which the car may be spinning
+	defaultSpinYawRate float64 = 1.5
+	// Default rotation in radians of the heading during which the yaw rate remains high for a spin
+	defaultSpinAngle float64 = math.Pi / 2
+	// Default minimum speed in m/s of the car for a spin
+	defaultSpinSpeed float64 = 5
+	// Default slip angle in radians between the heading and the direction of travel of the car for a spin
+	defaultSpinSlip float64 = math.Pi / 4
+	// PlayerTrackSurface of a car that is off track. Lower values indicate that the car is not in the world.
+	surfaceOffTrack int = 0
+)
+
+func init() {
+	ibt.Register("incidents", func(params ibt.Params) (ibt.Processor, error) {
+		var opts Options
+		if err := params.Decode(&opts); err != nil {
+			return nil, err
+		}
+
+		return NewDetector(opts), nil
+	})
+}
+
+// Kind of an Event.
+type Kind string
+
+const (
+	// Incident is an increase of the incident count of the driver
+	Incident Kind = "incident"
+	// OffTrack is an excursion of the car off the track
+	OffTrack Kind = "off-track"
+	// Spin is a rotation of the car away from its direction of travel
+	Spin Kind = "spin"
+)
+
+// Options configures the detection of events.
+type Options struct {
+	// Before and After are the number of ticks retained before and after each event. Both default to 120.
+	Before int `yaml:"before"`
+	After  int `yaml:"after"`
+	// SpinYawRate is the yaw rate in rad/s above which the car may be spinning. Defaults to 1.5.
+	SpinYawRate float64 `yaml:"spin_yaw_rate"`
+	// SpinAngle is the rotation in radians of the heading of the car, while the yaw rate remains above the
+	// SpinYawRate, for a spin. Defaults to a quarter turn.
+	SpinAngle float64 `yaml:"spin_angle"`
+	// SpinSpeed is the minimum speed in m/s at which a spin starts. Defaults to 5.
+	SpinSpeed float64 `yaml:"spin_speed"`
+	// SpinSlip is the slip angle in radians between the heading and the direction of travel of the car that
+	// needs to be reached during a spin. This distinguishes spins from tight corners. Defaults to 45°.
+	SpinSlip float64 `yaml:"spin_slip"`
+}
+
+// Event is a single incident, off-track excursion or spin.
+type Event struct {
+	Kind Kind
+	// Stub is the index of the stub within the group being processed
+	Stub int
+	// Filename of the stub
+	Filename string
+	// Lap, Pct and SessionTime of the tick at which the event was detected
+	Lap         int
+	Pct         float64
+	SessionTime float64
+	// Speed in m/s when the event was detected. For a spin, this is the speed at the start of the rotation.
+	Speed float64
+	// Points is the increase in the incident count of an Incident
+	Points int
+	// Duration in seconds of an OffTrack excursion or Spin
+	Duration float64
+	// Rotation in radians of the heading during a Spin, where a positive rotation is counter-clockwise
+	Rotation float64
+	// Slip is the largest slip angle in radians between the heading and the direction of travel during a Spin
+	Slip float64
+	// Samples are the ticks surrounding the event, containing the variables of the Whitelist
+	Samples []ibt.Tick
+	// Trigger is the index within Samples of the tick at which the event was detected
+	Trigger int
+}
+
+// Result of the events detected by a Detector.
+type Result struct {
+	// Events in the order they were detected
+	Events []Event
+	// Counts of the events by kind, and the total incident points
+	Incidents int
+	Points    int
+	OffTracks int
+	Spins     int
+}
+
+// Detector is a processor that detects incidents, off-track excursions and spins.
+type Detector struct {
+	opts   Options
+	events []*Event
+
+	stub ibt.StubInfo
+	// history of the ticks preceding the current tick
+	history *fifo.Simple[ibt.Tick]
+	// open events that are still receiving the ticks following them
+	open     []*Event
+	offTrack *Event
+	spin     *spinState
+	prev     eventTick
+	hasPrev  bool
+}
+
+// spinState is a rotation of the car in progress that may become a spin
+type spinState struct {
+	event    *Event
+	start    eventTick
+	rotation float64
+	slip     float64
+}
+
+// eventTick is the part of a tick used for detecting events
+type eventTick struct {
+	lap         int
+	pct         float64
+	sessionTime float64
+	speed       float64
+	incidents   int
+	surface     int
+	yaw         float64
+	yawRate     float64
+	// slip is the angle in radians between the heading and the direction of travel of the car
+	slip float64
+}
+
+// NewDetector creates an event Detector with the given options.
+func NewDetector(opts Options) *Detector {
+	if opts.Before <= 0 {
+		opts.Before = defaultWindow
+	}
+	if opts.After <= 0 {
+		opts.After = defaultWindow
+	}
+	if opts.SpinYawRate <= 0 {
+		opts.SpinYawRate = defaultSpinYawRate
+	}
+	if opts.SpinAngle <= 0 {
+		opts.SpinAngle = defaultSpinAngle
+	}
+	if opts.SpinSpeed <= 0 {
+		opts.SpinSpeed = defaultSpinSpeed
+	}
+	if opts.SpinSlip <= 0 {
+		opts.SpinSlip = defaultSpinSlip
+	}
+
+	return &Detector{opts: opts, history: fifo.NewSimple[ibt.Tick](opts.Before)}
+}
+
+// Whitelist of the variables required for detecting events
+func (d *Detector) Whitelist() []string {
+	return []string{
+		"SessionTime", "Lap", "LapDistPct", "Speed", "PlayerCarMyIncidentCount", "PlayerTrackSurface", "Yaw", "YawRate",
+		"VelocityX", "VelocityY",
+	}
+}
+
+// StartStub completes the events of the previous stub
+func (d *Detector) StartStub(stub ibt.StubInfo) error {
+	d.end()
+	d.stub = stub
+	d.hasPrev = false
+
+	return nil
+}
+
+// Process the tick without a TickContext. Events are located with the SessionTime of the tick, which is
+// part of the Whitelist.
+func (d *Detector) Process(input ibt.Tick, hasNext bool, session *headers.Session) error {
+	return d.ProcessContext(input, ibt.ContextFromTick(input), hasNext, session)
+}
+
+// ProcessContext detects the events of the tick, including the preceding ticks in their samples
+func (d *Detector) ProcessContext(input ibt.Tick, tickCtx ibt.TickContext, hasNext bool, session *headers.Session) error {
+	lap, err := ibt.GetTickValue[int](input, "Lap")
+	if err != nil {
+		return err
+	}
+
+	pct, err := ibt.GetTickValue[float32](input, "LapDistPct")
+	if err != nil {
+		return err
+	}
+
+	// Each kind of event is only detected when its variables are available
+	speed, _ := ibt.GetTickValue[float32](input, "Speed")
+	incidents, _ := ibt.GetTickValue[int](input, "PlayerCarMyIncidentCount")
+	surface, surfaceErr := ibt.GetTickValue[int](input, "PlayerTrackSurface")
+	yaw, yawErr := ibt.GetTickValue[float32](input, "Yaw")
+	yawRate, _ := ibt.GetTickValue[float32](input, "YawRate")
+	vx, vxErr := ibt.GetTickValue[float32](input, "VelocityX")
+	vy, vyErr := ibt.GetTickValue[float32](input, "VelocityY")
+
+	tick := eventTick{
+		lap:         lap,
+		pct:         float64(pct),
+		sessionTime: tickCtx.SessionTime,
+		speed:       float64(speed),
+		incidents:   incidents,
+		surface:     surface,
+		yaw:         float64(yaw),
+		yawRate:     float64(yawRate),
+		// VelocityX is forwards and VelocityY to the left of the car
+		slip: math.Atan2(float64(vy), float64(vx)),
+	}
+
+	// Ticks are added to the open events before detecting new events, which include the tick as their trigger
+	d.follow(input)
+
+	if d.hasPrev {
+		if tick.incidents > d.prev.incidents {
+			event := d.emit(Incident, tick, input)
+			event.Points = tick.incidents - d.prev.incidents
+		}
+
+		if surfaceErr == nil {
+			d.detectOffTrack(tick, input)
+		}
+
+		if yawErr == nil && vxErr == nil && vyErr == nil {
+			d.detectSpin(tick, input)
+		}
+	}
+
+	d.history.Add(input)
+	d.prev = tick
+	d.hasPrev = true
+
+	if !hasNext {
+		d.end()
+	}
+
+	return nil
+}
+
+// detectOffTrack starts an off-track excursion when the car leaves the surface of the track, or pit road,
+// and ends it when it returns
+func (d *Detector) detectOffTrack(tick eventTick, input ibt.Tick) {
+	switch {
+	case d.offTrack == nil && tick.surface == surfaceOffTrack && d.prev.surface > surfaceOffTrack:
+		d.offTrack = d.emit(OffTrack, tick, input)
+	case d.offTrack != nil:
+		d.offTrack.Duration = tick.sessionTime - d.offTrack.SessionTime
+		if tick.surface != surfaceOffTrack {
+			d.offTrack = nil
+		}
+	}
+}
+
+// detectSpin accumulates the rotation of the heading while the yaw rate is high, emitting a spin once the
+// rotation reaches the SpinAngle and the car has slid beyond the SpinSlip. A car rotating through a tight
+// corner keeps pointing in its direction of travel, so it is not spinning.
+func (d *Detector) detectSpin(tick eventTick, input ibt.Tick) {
+	if math.Abs(tick.yawRate) < d.opts.SpinYawRate {
+		d.spin = nil
+		return
+	}
+
+	if d.spin == nil {
+		if tick.speed < d.opts.SpinSpeed {
+			return
+		}
+		d.spin = &spinState{start: tick}
+		return
+	}
+
+	// The heading wraps around at ±π
+	delta := tick.yaw - d.prev.yaw
+	delta -= 2 * math.Pi * math.Round(delta/(2*math.Pi))
+	d.spin.rotation += delta
+	d.spin.slip = math.Max(d.spin.slip, math.Abs(tick.slip))
+
+	if d.spin.event == nil && math.Abs(d.spin.rotation) >= d.opts.SpinAngle && d.spin.slip >= d.opts.SpinSlip {
+		d.spin.event = d.emit(Spin, tick, input)
+		d.spin.event.Speed = d.spin.start.speed
+	}
+
+	if event := d.spin.event; event != nil {
+		event.Duration = tick.sessionTime - d.spin.start.sessionTime
+		event.Rotation = d.spin.rotation
+		event.Slip = d.spin.slip
+	}
+}
+
+// emit a new event of the given kind at the tick, with the preceding ticks as its samples
+func (d *Detector) emit(kind Kind, tick eventTick, input ibt.Tick) *Event {
+	event := &Event{
+		Kind:        kind,
+		Stub:        d.stub.Index,
+		Filename:    d.stub.Filename,
+		Lap:         tick.lap,
+		Pct:         tick.pct,
+		SessionTime: tick.sessionTime,
+		Speed:       tick.speed,
+		Samples:     make([]ibt.Tick, 0, d.history.Len()+1+d.opts.After),
+	}
+
+	for idx := 0; idx < d.history.Len(); idx++ {
+		event.Samples = append(event.Samples, d.history.Get(idx))
+	}
+	event.Trigger = len(event.Samples)
+	event.Samples = append(event.Samples, input)
+
+	d.events = append(d.events, event)
+	d.open = append(d.open, event)
+
+	return event
+}
+
+// follow adds the tick to the samples of the open events, closing those that received all of their ticks
+func (d *Detector) follow(input ibt.Tick) {
+	open := d.open[:0]
+
+	for _, event := range d.open {
+		event.Samples = append(event.Samples, input)
+		if len(event.Samples)-event.Trigger-1 < d.opts.After {
+			open = append(open, event)
+		}
+	}
+
+	d.open = open
+}
+
+// end the events of the current stub, which will not receive any further ticks
+func (d *Detector) end() {
+	d.history.Reset()
+	d.open = d.open[:0]
+	d.offTrack = nil
+	d.spin = nil
+}
+
+// Result of the events detected so far
+func (d *Detector) Result() Result {
+	result := Result{Events: make([]Event, 0, len(d.events))}
+
+	for _, event := range d.events {
+		result.Events = append(result.Events, *event)
+
+		switch event.Kind {
+		case Incident:
+			result.Incidents++
+			result.Points += event.Points
+		case OffTrack:
+			result.OffTracks++
+		case Spin:
+			result.Spins++
+		}
+	}
+
+	return result
+}
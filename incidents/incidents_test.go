@@ -0,0 +1,222 @@
+package incidents
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/ibttest"
+)
+
+// testTicks creates the ticks of a car driving at 50 m/s for 40 seconds.
+//
+// The incident count increases by 2 at 10 seconds and by 4 at 31 seconds. The car is off track from 20
+// until 23 seconds. It takes a long corner from 5 until 8 seconds and spins from 30 until 31.5 seconds,
+// sliding in its original direction of travel throughout the spin.
+func testTicks() []ibt.Tick {
+	ticks := make([]ibt.Tick, 0)
+	yaw, spin := 0.0, 0.0
+
+	for idx := 0; idx < 40*60; idx++ {
+		t := float64(idx) / 60
+
+		incidents := 0
+		switch {
+		case t >= 31:
+			incidents = 6
+		case t >= 10:
+			incidents = 2
+		}
+
+		surface := 3
+		if t >= 20 && t < 23 {
+			surface = 0
+		}
+
+		yawRate := 0.0
+		switch {
+		case t >= 5 && t < 8:
+			yawRate = 1
+		case t >= 30 && t < 31.5:
+			yawRate = 3
+		}
+
+		yaw += yawRate / 60
+		yaw -= 2 * math.Pi * math.Round(yaw/(2*math.Pi))
+		if t >= 30 && t < 31.5 {
+			spin += yawRate / 60
+		}
+
+		ticks = append(ticks, ibt.Tick{
+			"SessionTime":              t,
+			"Lap":                      1,
+			"LapDistPct":               float32(t / 60),
+			"Speed":                    float32(50),
+			"PlayerCarMyIncidentCount": incidents,
+			"PlayerTrackSurface":       surface,
+			"Yaw":                      float32(yaw),
+			"YawRate":                  float32(yawRate),
+			"VelocityX":                float32(50 * math.Cos(spin)),
+			"VelocityY":                float32(-50 * math.Sin(spin)),
+		})
+	}
+
+	return ticks
+}
+
+func TestDetector(t *testing.T) {
+	t.Run("test Detector events", func(t *testing.T) {
+		detector := NewDetector(Options{})
+		ibttest.Process(t, detector, ibt.StubInfo{Index: 0, Filename: "test.ibt"}, testTicks(), nil)
+
+		result := detector.Result()
+		if result.Incidents != 2 || result.Points != 6 || result.OffTracks != 1 || result.Spins != 1 {
+			t.Errorf("expected %d incidents of %d points, %d off track and %d spin. received %+v", 2, 6, 1, 1, result)
+		}
+
+		expected := []struct {
+			kind        Kind
+			sessionTime float64
+			points      int
+			duration    float64
+		}{
+			{Incident, 10, 2, 0},
+			{OffTrack, 20, 0, 3},
+			{Spin, 30.52, 0, 1.48},
+			{Incident, 31, 4, 0},
+		}
+
+		if len(result.Events) != len(expected) {
+			t.Errorf("expected %d events. received %d", len(expected), len(result.Events))
+			return
+		}
+
+		for idx, want := range expected {
+			event := result.Events[idx]
+			if event.Kind != want.kind || math.Abs(event.SessionTime-want.sessionTime) > 0.02 {
+				t.Errorf("expected a %s event at %.2f. received a %s event at %f", want.kind, want.sessionTime, event.Kind, event.SessionTime)
+			}
+			if event.Points != want.points || math.Abs(event.Duration-want.duration) > 0.02 {
+				t.Errorf("expected %d points and a duration of %.2f for the %s event. received %d and %f", want.points, want.duration, want.kind, event.Points, event.Duration)
+			}
+			if event.Lap != 1 || math.Abs(event.Pct-event.SessionTime/60) > 0.001 || event.Speed != 50 || event.Filename != "test.ibt" {
+				t.Errorf("expected the position of the %s event. received %+v", want.kind, event)
+			}
+		}
+
+		if spin := result.Events[2]; math.Abs(spin.Rotation-4.45) > 0.01 || math.Abs(spin.Slip-math.Pi) > 0.05 {
+			t.Errorf("expected a rotation of %.2f with a slip of %.2f. received %f and %f", 4.45, math.Pi, spin.Rotation, spin.Slip)
+		}
+	})
+
+	t.Run("test Detector hairpin", func(t *testing.T) {
+		// A hairpin taken at 15 m/s with a radius of 10 meters rotates the car by 180° at 1.5 rad/s, while the
+		// car keeps pointing within a few degrees of its direction of travel
+		ticks := make([]ibt.Tick, 0)
+		yaw := 0.0
+		for idx := 0; idx < 4*60; idx++ {
+			yawRate := 0.0
+			if t := float64(idx) / 60; t >= 1 && t < 1+math.Pi/1.5 {
+				yawRate = 1.5
+			}
+			yaw += yawRate / 60
+
+			ticks = append(ticks, ibt.Tick{
+				"SessionTime": float64(idx) / 60,
+				"Lap":         1,
+				"LapDistPct":  float32(idx) / 6000,
+				"Speed":       float32(15),
+				"Yaw":         float32(math.Remainder(yaw, 2*math.Pi)),
+				"YawRate":     float32(yawRate),
+				"VelocityX":   float32(15 * math.Cos(0.05)),
+				"VelocityY":   float32(-15 * math.Sin(0.05)),
+			})
+		}
+
+		detector := NewDetector(Options{})
+		ibttest.Process(t, detector, ibt.StubInfo{Index: 0, Filename: "test.ibt"}, ticks, nil)
+
+		if result := detector.Result(); result.Spins != 0 {
+			t.Errorf("expected no spins in a hairpin. received %+v", result.Events)
+		}
+	})
+
+	t.Run("test Detector samples", func(t *testing.T) {
+		detector := NewDetector(Options{Before: 30, After: 60})
+		ibttest.Process(t, detector, ibt.StubInfo{Index: 0, Filename: "test.ibt"}, testTicks(), nil)
+
+		for _, event := range detector.Result().Events {
+			if len(event.Samples) != 91 || event.Trigger != 30 {
+				t.Errorf("expected %d samples with the trigger at %d. received %d with the trigger at %d", 91, 30, len(event.Samples), event.Trigger)
+				continue
+			}
+
+			if sessionTime := event.Samples[event.Trigger]["SessionTime"].(float64); sessionTime != event.SessionTime {
+				t.Errorf("expected the trigger at %f. received %f", event.SessionTime, sessionTime)
+			}
+			for idx := 1; idx < len(event.Samples); idx++ {
+				before, after := event.Samples[idx-1]["SessionTime"].(float64), event.Samples[idx]["SessionTime"].(float64)
+				if math.Abs(after-before-1.0/60) > 0.0001 {
+					t.Errorf("expected consecutive samples. received %f and %f", before, after)
+					break
+				}
+			}
+		}
+	})
+
+	t.Run("test Detector across stubs", func(t *testing.T) {
+		ticks := testTicks()
+		detector := NewDetector(Options{})
+		ibttest.Process(t, detector, ibt.StubInfo{Index: 0, Filename: "first.ibt"}, ticks[:10*60+30], nil)
+		ibttest.Process(t, detector, ibt.StubInfo{Index: 1, Filename: "second.ibt"}, ticks[10*60+30:], nil)
+
+		// The incident count of the second stub is not compared to the first
+		events := detector.Result().Events
+		if len(events) != 4 || events[0].Filename != "first.ibt" || events[1].Filename != "second.ibt" {
+			t.Errorf("expected %d events across both stubs. received %+v", 4, events)
+			return
+		}
+		if len(events[0].Samples) != 120+1+29 {
+			t.Errorf("expected the samples of the first event to end with its stub. received %d", len(events[0].Samples))
+		}
+	})
+
+	t.Run("test Detector missing variables", func(t *testing.T) {
+		if err := NewDetector(Options{}).Process(ibt.Tick{"Lap": 1}, true, nil); err == nil {
+			t.Error("expected Process() to return an error when LapDistPct is missing")
+		}
+	})
+
+	t.Run("test Detector from registry", func(t *testing.T) {
+		processor, err := ibt.DefaultRegistry.NewProcessor("incidents", ibt.Params{"before": 10, "spin_yaw_rate": 2})
+		if err != nil {
+			t.Errorf("expected NewProcessor() to run without err. received error: %v", err)
+			return
+		}
+
+		detector := processor.(*Detector)
+		if detector.opts.Before != 10 || detector.opts.After != 120 || detector.opts.SpinYawRate != 2 {
+			t.Errorf("expected the options of the params. received %+v", detector.opts)
+		}
+	})
+
+	t.Run("test Detector with ibt.Process", func(t *testing.T) {
+		stubs, err := ibt.ParseStubs("../.testing/valid_test_file.ibt")
+		if err != nil {
+			t.Errorf("failed to parse stubs for testing file - %v", err)
+			return
+		}
+		defer stubs.Close()
+
+		detector := NewDetector(Options{})
+		if err := ibt.Process(context.Background(), stubs, detector); err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		// The car remains in the pits throughout the testing file
+		if result := detector.Result(); len(result.Events) != 0 {
+			t.Errorf("expected no events. received %+v", result)
+		}
+	})
+}
@@ -0,0 +1,407 @@
+// Package inputs measures the quality of the inputs of a driver.
+//
+// An Analyzer reports the time spent overlapping the throttle and brake, coasting without either pedal,
+// the rate at which the steering is reversed, how much the throttle is modulated when exiting corners and
+// how the clutch is used. Metrics are reported per lap, and per corner when a corners.TrackModel is
+// available.
+package inputs
+
+import (
+	"errors"
+	"math"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/corners"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/laps"
+)
+
+const (
+	// Default minimum throttle, between 0 and 1, for the throttle to be applied
+	defaultThrottle float64 = 0.05
+	// Default minimum brake pressure, between 0 and 1, for the brakes to be applied
+	defaultBrake float64 = 0.05
+	// Default minimum clutch pedal travel, between 0 and 1, for the clutch to be in use
+	defaultClutch float64 = 0.05
+	// Default minimum change in steering wheel angle in radians that is counted as a reversal
+	defaultReversalAngle float64 = 0.035
+	// Default minimum reduction of the throttle, between 0 and 1, that is counted as a lift
+	defaultLift float64 = 0.1
+)
+
+func init() {
+	ibt.Register("inputs", func(params ibt.Params) (ibt.Processor, error) {
+		var opts Options
+		if err := params.Decode(&opts); err != nil {
+			return nil, err
+		}
+
+		// The corners are loaded from the store, as no model can be provided through the params
+		if opts.Models == "" {
+			return nil, errors.New("a models directory is required for reporting metrics per corner")
+		}
+
+		return NewAnalyzer(corners.TrackModel{}, opts), nil
+	})
+}
+
+// Options configures the thresholds of the input metrics.
+type Options struct {
+	// Throttle is the minimum throttle, between 0 and 1, for the throttle to be applied. Defaults to 0.05.
+	Throttle float64 `yaml:"throttle"`
+	// Brake is the minimum brake pressure, between 0 and 1, for the brakes to be applied. Defaults to 0.05.
+	Brake float64 `yaml:"brake"`
+	// Clutch is the minimum clutch pedal travel, between 0 and 1, for the clutch to be in use. As the Clutch
+	// variable is 1 when the clutch is fully engaged, the clutch is in use at or below 1 - Clutch. Defaults
+	// to 0.05.
+	Clutch float64 `yaml:"clutch"`
+	// ReversalAngle is the minimum change in steering wheel angle in radians, in the opposite direction of
+	// the previous change, that is counted as a reversal. Defaults to 0.035.
+	ReversalAngle float64 `yaml:"reversal_angle"`
+	// Lift is the minimum reduction of the throttle, between 0 and 1, that is counted as a lift while
+	// exiting a corner. Defaults to 0.1.
+	Lift float64 `yaml:"lift"`
+	// Models is the directory of a corners.ModelStore. When set, the model of the track of the session is
+	// loaded from the store if no model was provided, failing when no model was stored for the track. This
+	// is required when the Analyzer is created from the registry.
+	Models string `yaml:"models"`
+}
+
+// Metrics of the inputs over a part of the telemetry.
+type Metrics struct {
+	// Time in seconds over which the metrics were measured
+	Time float64
+	// OverlapTime is the number of seconds both the throttle and brakes were applied
+	OverlapTime float64
+	// CoastingTime is the number of seconds neither the throttle nor brakes were applied
+	CoastingTime float64
+	// SteeringReversals is the number of times the direction of steering was reversed
+	SteeringReversals int
+	// ExitTime is the number of seconds spent between the apex and exit of corners
+	ExitTime float64
+	// ExitModulation is the sum of all reductions of the throttle between the apex and exit of corners.
+	// This is 0 when the throttle is only ever increased on exit.
+	ExitModulation float64
+	// ExitLifts is the number of times the throttle was lifted between the apex and exit of corners
+	ExitLifts int
+	// ClutchTime is the number of seconds the clutch was in use
+	ClutchTime float64
+	// ClutchUses is the number of times the clutch was used
+	ClutchUses int
+}
+
+// OverlapPct is the fraction of the time both the throttle and brakes were applied
+func (m Metrics) OverlapPct() float64 { return ratio(m.OverlapTime, m.Time) }
+
+// CoastingPct is the fraction of the time neither the throttle nor brakes were applied
+func (m Metrics) CoastingPct() float64 { return ratio(m.CoastingTime, m.Time) }
+
+// ReversalRate is the number of steering reversals per minute
+func (m Metrics) ReversalRate() float64 { return ratio(float64(m.SteeringReversals)*60, m.Time) }
+
+// Add the other metrics to these metrics
+func (m *Metrics) Add(other Metrics) {
+	m.Time += other.Time
+	m.OverlapTime += other.OverlapTime
+	m.CoastingTime += other.CoastingTime
+	m.SteeringReversals += other.SteeringReversals
+	m.ExitTime += other.ExitTime
+	m.ExitModulation += other.ExitModulation
+	m.ExitLifts += other.ExitLifts
+	m.ClutchTime += other.ClutchTime
+	m.ClutchUses += other.ClutchUses
+}
+
+// LapInputs are the metrics of a single lap.
+type LapInputs struct {
+	// Stub is the index of the stub within the group being processed
+	Stub int
+	// Filename of the stub
+	Filename string
+	// Lap number, as reported by the Lap variable
+	Lap int
+	// Metrics of the entire lap
+	Metrics Metrics
+	// Corners contains the metrics between the entry and exit of each corner, keyed by corner number
+	Corners map[int]Metrics
+}
+
+// Result of the inputs processed by an Analyzer.
+type Result struct {
+	// Laps in the order they were driven
+	Laps []LapInputs
+	// Total metrics of all laps
+	Total Metrics
+	// Corners contains the metrics of each corner across all laps, keyed by corner number
+	Corners map[int]Metrics
+}
+
+// Analyzer is a processor that measures the quality of the inputs of the driver.
+type Analyzer struct {
+	opts  Options
+	model corners.TrackModel
+	store *corners.ModelStore
+	laps  []LapInputs
+
+	stub    ibt.StubInfo
+	current *LapInputs
+	prev    inputTick
+	hasPrev bool
+
+	// extreme steering wheel angle reached in the current direction of steering, which is 1 when the
+	// angle is increasing, -1 when decreasing and 0 when unknown
+	extreme   float64
+	direction float64
+	// peak throttle in the current corner exit since the last lift
+	exitPeak float64
+}
+
+// inputTick is the part of a tick used for measuring inputs
+type inputTick struct {
+	lap         int
+	pct         float64
+	sessionTime float64
+	throttle    float64
+	brake       float64
+	clutch      float64
+	steering    float64
+}
+
+// NewAnalyzer creates an Analyzer reporting metrics for the corners of the given model.
+//
+// Metrics are not reported per corner when the model does not contain any corners and no Models
+// directory was configured.
+func NewAnalyzer(model corners.TrackModel, opts Options) *Analyzer {
+	if opts.Throttle <= 0 {
+		opts.Throttle = defaultThrottle
+	}
+	if opts.Brake <= 0 {
+		opts.Brake = defaultBrake
+	}
+	if opts.Clutch <= 0 {
+		opts.Clutch = defaultClutch
+	}
+	if opts.ReversalAngle <= 0 {
+		opts.ReversalAngle = defaultReversalAngle
+	}
+	if opts.Lift <= 0 {
+		opts.Lift = defaultLift
+	}
+
+	a := &Analyzer{opts: opts, model: model}
+	if len(model.Corners) == 0 && opts.Models != "" {
+		a.store = corners.NewModelStore(opts.Models)
+	}
+
+	return a
+}
+
+// Whitelist of the variables required for measuring inputs
+func (a *Analyzer) Whitelist() []string {
+	return []string{"Lap", "LapDistPct", "Throttle", "Brake", "Clutch", "SteeringWheelAngle"}
+}
+
+// StartStub completes the lap in progress of the previous stub
+func (a *Analyzer) StartStub(stub ibt.StubInfo) error {
+	a.end()
+	a.stub = stub
+	a.hasPrev = false
+
+	return nil
+}
+
+// Process the tick without a TickContext. The time spent on each input is measured from the SessionTime of
+// the tick, so no time is recorded when it is missing.
+func (a *Analyzer) Process(input ibt.Tick, hasNext bool, session *headers.Session) error {
+	return a.ProcessContext(input, ibt.ContextFromTick(input), hasNext, session)
+}
+
+// ProcessContext adds the inputs of the tick to the metrics of its lap and corner
+func (a *Analyzer) ProcessContext(input ibt.Tick, tickCtx ibt.TickContext, hasNext bool, session *headers.Session) error {
+	lap, err := ibt.GetTickValue[int](input, "Lap")
+	if err != nil {
+		return err
+	}
+
+	pct, err := ibt.GetTickValue[float32](input, "LapDistPct")
+	if err != nil {
+		return err
+	}
+
+	throttle, err := ibt.GetTickValue[float32](input, "Throttle")
+	if err != nil {
+		return err
+	}
+
+	brake, err := ibt.GetTickValue[float32](input, "Brake")
+	if err != nil {
+		return err
+	}
+
+	steering, err := ibt.GetTickValue[float32](input, "SteeringWheelAngle")
+	if err != nil {
+		return err
+	}
+
+	// Not every car reports the clutch, in which case it is always engaged
+	clutch, err := ibt.GetTickValue[float32](input, "Clutch")
+	if err != nil {
+		clutch = 1
+	}
+
+	if a.store != nil && session != nil {
+		model, err := corners.ModelFor(a.store, session)
+		if err != nil {
+			return err
+		}
+		a.model, a.store = model, nil
+	}
+
+	tick := inputTick{
+		lap:         lap,
+		pct:         float64(pct),
+		sessionTime: tickCtx.SessionTime,
+		throttle:    float64(throttle),
+		brake:       float64(brake),
+		clutch:      float64(clutch),
+		steering:    float64(steering),
+	}
+
+	if a.current == nil || a.current.Lap != tick.lap {
+		a.end()
+		a.current = &LapInputs{
+			Stub:     a.stub.Index,
+			Filename: a.stub.Filename,
+			Lap:      tick.lap,
+			Corners:  make(map[int]Metrics),
+		}
+	}
+
+	// Intervals during which the car was relocated are not measured
+	if a.hasPrev && (tick.lap == a.prev.lap+1 || math.Abs(tick.pct-a.prev.pct) < laps.MaxPctStep) {
+		a.measure(tick, tick.sessionTime-a.prev.sessionTime)
+	} else {
+		a.extreme, a.direction, a.exitPeak = tick.steering, 0, tick.throttle
+	}
+
+	a.prev = tick
+	a.hasPrev = true
+
+	if !hasNext {
+		a.end()
+	}
+
+	return nil
+}
+
+// measure the inputs of the tick, which were held for dt seconds
+func (a *Analyzer) measure(tick inputTick, dt float64) {
+	var m Metrics
+
+	m.Time = dt
+
+	throttle, brake := tick.throttle >= a.opts.Throttle, tick.brake >= a.opts.Brake
+	switch {
+	case throttle && brake:
+		m.OverlapTime = dt
+	case !throttle && !brake:
+		m.CoastingTime = dt
+	}
+
+	if a.clutched(tick) {
+		m.ClutchTime = dt
+		if !a.clutched(a.prev) {
+			m.ClutchUses = 1
+		}
+	}
+
+	if a.reversed(tick.steering) {
+		m.SteeringReversals = 1
+	}
+
+	corner, inCorner := a.model.Corner(tick.pct)
+	if inCorner && corner.PastApex(tick.pct) {
+		if !corner.PastApex(a.prev.pct) {
+			a.exitPeak = a.prev.throttle
+		}
+
+		m.ExitTime = dt
+		m.ExitModulation = math.Max(0, a.prev.throttle-tick.throttle)
+
+		a.exitPeak = math.Max(a.exitPeak, tick.throttle)
+		if a.exitPeak-tick.throttle >= a.opts.Lift {
+			m.ExitLifts = 1
+			a.exitPeak = tick.throttle
+		}
+	}
+
+	a.current.Metrics.Add(m)
+
+	if inCorner {
+		metrics := a.current.Corners[corner.Number]
+		metrics.Add(m)
+		a.current.Corners[corner.Number] = metrics
+	}
+}
+
+// clutched indicates whether the clutch was in use during the given tick, where a clutch of 1 is fully
+// engaged
+func (a *Analyzer) clutched(tick inputTick) bool { return tick.clutch <= 1-a.opts.Clutch }
+
+// reversed indicates whether the steering changed direction by at least the ReversalAngle since the
+// extreme angle reached in the previous direction
+func (a *Analyzer) reversed(steering float64) bool {
+	delta := steering - a.extreme
+
+	switch {
+	case a.direction == 0:
+		// The first change of the steering sets its direction without reversing it
+		if math.Abs(delta) >= a.opts.ReversalAngle {
+			a.direction, a.extreme = math.Copysign(1, delta), steering
+		}
+		return false
+	case delta*a.direction > 0:
+		a.extreme = steering
+		return false
+	case math.Abs(delta) < a.opts.ReversalAngle:
+		return false
+	}
+
+	a.direction, a.extreme = -a.direction, steering
+
+	return true
+}
+
+// end the current lap
+func (a *Analyzer) end() {
+	if a.current != nil {
+		a.laps = append(a.laps, *a.current)
+		a.current = nil
+	}
+}
+
+// Result of the laps completed so far
+func (a *Analyzer) Result() Result {
+	result := Result{Laps: a.laps, Corners: make(map[int]Metrics)}
+
+	for _, lap := range a.laps {
+		result.Total.Add(lap.Metrics)
+
+		for number, lapMetrics := range lap.Corners {
+			metrics := result.Corners[number]
+			metrics.Add(lapMetrics)
+			result.Corners[number] = metrics
+		}
+	}
+
+	return result
+}
+
+// ratio of a to b, which is 0 when b is 0
+func ratio(a, b float64) float64 {
+	if b <= 0 {
+		return 0
+	}
+
+	return a / b
+}
@@ -0,0 +1,203 @@
+package inputs
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/corners"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/ibttest"
+)
+
+var testModel = corners.TrackModel{
+	Corners: []corners.Corner{{Number: 1, Entry: 0.23, Apex: 0.27, Exit: 0.35, Direction: corners.Left}},
+}
+
+// testInputs returns the throttle, brake, clutch and steering at the given position of the lap.
+//
+// The driver overlaps the pedals from 0.19 to 0.2, brakes until 0.25 and coasts until 0.27. The throttle is
+// increased until 0.35, apart from a lift of 0.3 between 0.3 and 0.31. The steering is reversed 4 times in
+// the corner and the clutch is pressed halfway from 0.5 to 0.51, while it is fully engaged otherwise.
+func testInputs(pct float64) (throttle, brake, clutch, steering float64) {
+	throttle, clutch = 1, 1
+
+	switch {
+	case pct >= 0.19 && pct < 0.25:
+		brake = 0.8
+		if pct >= 0.2 {
+			throttle = 0
+		}
+	case pct >= 0.25 && pct < 0.27:
+		throttle = 0
+	case pct >= 0.27 && pct < 0.35:
+		throttle = (pct - 0.27) / 0.08
+		if pct >= 0.3 && pct < 0.31 {
+			throttle -= 0.3
+		}
+	case pct >= 0.5 && pct < 0.51:
+		clutch = 0.5
+	}
+
+	if pct >= 0.23 && pct < 0.35 {
+		steering = 0.2 * math.Sin(2*math.Pi*(pct-0.23)/0.06)
+	}
+
+	return throttle, brake, clutch, steering
+}
+
+// testTicks creates the ticks of the given number of 60 second laps
+func testTicks(laps int, withClutch bool) []ibt.Tick {
+	ticks := make([]ibt.Tick, 0)
+
+	for idx := 0; idx < laps*3600; idx++ {
+		pos := float64(idx) / 3600
+		pct := pos - math.Floor(pos)
+		throttle, brake, clutch, steering := testInputs(pct)
+
+		tick := ibt.Tick{
+			"SessionTime":        float64(idx) / 60,
+			"Lap":                1 + int(math.Floor(pos)),
+			"LapDistPct":         float32(pct),
+			"Throttle":           float32(throttle),
+			"Brake":              float32(brake),
+			"SteeringWheelAngle": float32(steering),
+		}
+		if withClutch {
+			tick["Clutch"] = float32(clutch)
+		}
+
+		ticks = append(ticks, tick)
+	}
+
+	return ticks
+}
+
+var testStub = ibt.StubInfo{Index: 0, Filename: "test.ibt"}
+
+func TestAnalyzer(t *testing.T) {
+	t.Run("test Analyzer laps", func(t *testing.T) {
+		analyzer := NewAnalyzer(testModel, Options{})
+		ibttest.Process(t, analyzer, testStub, testTicks(2, true), nil)
+
+		result := analyzer.Result()
+		if len(result.Laps) != 2 || result.Laps[1].Lap != 2 || result.Laps[1].Filename != "test.ibt" {
+			t.Errorf("expected %d laps. received %+v", 2, result.Laps)
+			return
+		}
+
+		m := result.Laps[1].Metrics
+		if math.Abs(m.Time-60) > 0.02 || math.Abs(m.OverlapTime-0.6) > 0.02 || math.Abs(m.CoastingTime-1.44) > 0.05 {
+			t.Errorf("expected %.1f seconds of overlap and %.2f of coasting in %d seconds. received %+v", 0.6, 1.44, 60, m)
+		}
+
+		if math.Abs(m.OverlapPct()-0.01) > 0.001 || math.Abs(m.CoastingPct()-0.024) > 0.001 {
+			t.Errorf("expected %.2f overlap and %.3f coasting. received %f and %f", 0.01, 0.024, m.OverlapPct(), m.CoastingPct())
+		}
+
+		if m.SteeringReversals != 4 || math.Abs(m.ReversalRate()-4) > 0.01 {
+			t.Errorf("expected %d steering reversals per minute. received %d at %f", 4, m.SteeringReversals, m.ReversalRate())
+		}
+
+		if math.Abs(m.ExitTime-4.8) > 0.03 || math.Abs(m.ExitModulation-0.3) > 0.01 || m.ExitLifts != 1 {
+			t.Errorf("expected a single lift of %.1f in %.1f seconds of corner exit. received %+v", 0.3, 4.8, m)
+		}
+
+		if math.Abs(m.ClutchTime-0.6) > 0.02 || m.ClutchUses != 1 {
+			t.Errorf("expected the clutch to be used once for %.1f seconds. received %+v", 0.6, m)
+		}
+
+		corner := result.Laps[1].Corners[1]
+		if math.Abs(corner.Time-7.2) > 0.02 || corner.SteeringReversals != 4 || corner.ExitLifts != 1 || corner.OverlapTime != 0 {
+			t.Errorf("expected the corner to take %.1f seconds with %d reversals. received %+v", 7.2, 4, corner)
+		}
+
+		if result.Total.SteeringReversals != 8 || math.Abs(result.Total.Time-120) > 0.05 || result.Corners[1].SteeringReversals != 8 {
+			t.Errorf("expected the total of both laps. received %+v and %+v", result.Total, result.Corners[1])
+		}
+	})
+
+	t.Run("test Analyzer without a model or clutch", func(t *testing.T) {
+		analyzer := NewAnalyzer(corners.TrackModel{}, Options{})
+		ibttest.Process(t, analyzer, testStub, testTicks(1, false), nil)
+
+		m := analyzer.Result().Laps[0].Metrics
+		if len(analyzer.Result().Corners) != 0 || m.ExitTime != 0 || m.ClutchTime != 0 || m.SteeringReversals != 4 {
+			t.Errorf("expected no corner or clutch metrics. received %+v", m)
+		}
+	})
+
+	t.Run("test Analyzer Metrics Add()", func(t *testing.T) {
+		m := Metrics{Time: 10, OverlapTime: 1, SteeringReversals: 2, ClutchUses: 1}
+		m.Add(Metrics{Time: 20, CoastingTime: 3, SteeringReversals: 1, ExitLifts: 2})
+
+		if m.Time != 30 || m.OverlapTime != 1 || m.CoastingTime != 3 || m.SteeringReversals != 3 || m.ExitLifts != 2 || m.ClutchUses != 1 {
+			t.Errorf("expected the metrics to be summed. received %+v", m)
+		}
+
+		if m.ReversalRate() != 6 || (Metrics{}).ReversalRate() != 0 {
+			t.Errorf("expected a reversal rate of %d per minute. received %f", 6, m.ReversalRate())
+		}
+	})
+
+	t.Run("test Analyzer thresholds from registry", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := corners.NewModelStore(dir).Save(testModel); err != nil {
+			t.Fatal(err)
+		}
+
+		processor, err := ibt.DefaultRegistry.NewProcessor("inputs", ibt.Params{"reversal_angle": 0.5, "brake": 0.9, "models": dir})
+		if err != nil {
+			t.Errorf("expected NewProcessor() to run without err. received error: %v", err)
+			return
+		}
+
+		analyzer := processor.(*Analyzer)
+		ibttest.Process(t, analyzer, testStub, testTicks(1, true), &headers.Session{})
+
+		lap := analyzer.Result().Laps[0]
+		if m := lap.Metrics; m.SteeringReversals != 0 || m.OverlapTime != 0 {
+			t.Errorf("expected no reversals or overlap with the configured thresholds. received %+v", m)
+		}
+		if _, ok := lap.Corners[1]; !ok {
+			t.Errorf("expected the metrics of the corner of the stored model. received %+v", lap.Corners)
+		}
+	})
+
+	t.Run("test Analyzer registry requires models", func(t *testing.T) {
+		if _, err := ibt.DefaultRegistry.NewProcessor("inputs", ibt.Params{}); err == nil {
+			t.Error("expected NewProcessor() to return an error without a models directory")
+		}
+	})
+
+	t.Run("test Analyzer missing variables", func(t *testing.T) {
+		if err := NewAnalyzer(testModel, Options{}).Process(ibt.Tick{"Lap": 1, "LapDistPct": float32(0.1), "Throttle": float32(1)}, true, nil); err == nil {
+			t.Error("expected Process() to return an error when Brake is missing")
+		}
+	})
+
+	t.Run("test Analyzer with ibt.Process", func(t *testing.T) {
+		stubs, err := ibt.ParseStubs("../.testing/valid_test_file.ibt")
+		if err != nil {
+			t.Errorf("failed to parse stubs for testing file - %v", err)
+			return
+		}
+		defer stubs.Close()
+
+		analyzer := NewAnalyzer(corners.TrackModel{}, Options{})
+		if err := ibt.Process(context.Background(), stubs, analyzer); err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		result := analyzer.Result()
+		if len(result.Laps) != 1 || result.Laps[0].Lap != 9 || result.Total.Time <= 0 {
+			t.Errorf("expected the metrics of lap %d. received %+v", 9, result)
+		}
+
+		// The car waits in the pits with the clutch pressed from the start of the file
+		if total := result.Total; total.ClutchTime != total.Time || total.ClutchUses != 0 {
+			t.Errorf("expected the clutch to be held throughout without a new use. received %+v", total)
+		}
+	})
+}
@@ -0,0 +1,302 @@
+package ibt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Grouping modes of a JobConfig
+const (
+	// GroupBySession groups stubs belonging to the same session with StubGroup.Group. This is the default.
+	GroupBySession string = "session"
+	// GroupByFile processes every stub as its own group.
+	GroupByFile string = "file"
+	// GroupNone processes all stubs as a single group.
+	GroupNone string = "none"
+)
+
+// JobConfig describes a processing job.
+//
+// Configs are written in YAML. As JSON is a subset of YAML, JSON configs are accepted as well, for example:
+//
+//	name: track-temps
+//	input: ./telemetry/*.ibt
+//	group: session
+//	workers: 4
+//	error_policy: skip-tick
+//	processors:
+//	  - name: track-temp
+//	    params:
+//	      unit: celsius
+//	outputs:
+//	  - name: stdout
+type JobConfig struct {
+	// Name of the job
+	Name string `yaml:"name"`
+	// Input is one or more file patterns of the ibt files to process
+	Input Globs `yaml:"input"`
+	// Group determines how the stubs are grouped. One of session, file or none.
+	Group string `yaml:"group"`
+	// Workers is the maximum number of groups processed concurrently
+	Workers int `yaml:"workers"`
+	// ErrorPolicy is the string representation of the ErrorPolicy used for processing
+	ErrorPolicy string `yaml:"error_policy"`
+	// Processors created for every group, referred to by their registered name
+	Processors []ComponentConfig `yaml:"processors"`
+	// Outputs receiving the result of every group, referred to by their registered name
+	Outputs []ComponentConfig `yaml:"outputs"`
+}
+
+// ComponentConfig refers to a registered processor or output along with its parameters.
+type ComponentConfig struct {
+	Name   string `yaml:"name"`
+	Params Params `yaml:"params"`
+}
+
+// Globs is a list of file patterns that can be configured as a single pattern or a list of patterns.
+type Globs []string
+
+// UnmarshalYAML accepts either a single pattern or a list of patterns
+func (g *Globs) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*g = Globs{value.Value}
+		return nil
+	}
+
+	var patterns []string
+	if err := value.Decode(&patterns); err != nil {
+		return err
+	}
+	*g = patterns
+
+	return nil
+}
+
+// Files matching the patterns. Files matched by multiple patterns are only included once.
+func (g Globs) Files() ([]string, error) {
+	files := make([]string, 0)
+	seen := make(map[string]struct{})
+
+	for _, pattern := range g {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("could not glob input %s: %w", pattern, err)
+		}
+
+		for _, match := range matches {
+			if _, ok := seen[match]; !ok {
+				seen[match] = struct{}{}
+				files = append(files, match)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// ParseJobConfig parses a YAML or JSON job config.
+//
+// Unknown fields are reported as errors to catch misspelled options.
+func ParseJobConfig(data []byte) (JobConfig, error) {
+	var cfg JobConfig
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("failed to parse job config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Job is a validated JobConfig that can be run.
+type Job struct {
+	Config JobConfig
+
+	registry *Registry
+	policy   ErrorPolicy
+}
+
+// LoadJob reads the job config at the given path and creates a Job using DefaultRegistry.
+func LoadJob(path string) (*Job, error) { return DefaultRegistry.LoadJob(path) }
+
+// LoadJob reads the job config at the given path and creates a Job using the registry.
+func (r *Registry) LoadJob(path string) (*Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job config %s: %w", path, err)
+	}
+
+	cfg, err := ParseJobConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.NewJob(cfg)
+}
+
+// NewJob validates the given config and creates a Job using the registry.
+//
+// All processors and outputs must be registered before the Job is created.
+func (r *Registry) NewJob(cfg JobConfig) (*Job, error) {
+	if len(cfg.Input) == 0 {
+		return nil, errors.New("job config requires at least one input")
+	}
+
+	switch cfg.Group {
+	case "":
+		cfg.Group = GroupBySession
+	case GroupBySession, GroupByFile, GroupNone:
+	default:
+		return nil, fmt.Errorf("unknown group %q", cfg.Group)
+	}
+
+	policy, err := ParseErrorPolicy(cfg.ErrorPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Processors) == 0 {
+		return nil, errors.New("job config requires at least one processor")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, proc := range cfg.Processors {
+		if _, ok := r.processors[proc.Name]; !ok {
+			return nil, fmt.Errorf("processor %s is not registered", proc.Name)
+		}
+	}
+
+	for _, output := range cfg.Outputs {
+		if _, ok := r.outputs[output.Name]; !ok {
+			return nil, fmt.Errorf("output %s is not registered", output.Name)
+		}
+	}
+
+	return &Job{Config: cfg, registry: r, policy: policy}, nil
+}
+
+// Run the job by processing the groups of the input files with ProcessGroups.
+//
+// New processors are created for every group and closed after the group's result was written when
+// they implement io.Closer. Outputs are created once and receive the
+// result of every group in order. The returned error joins any processing and output errors.
+func (j *Job) Run(ctx context.Context) ([]GroupResult, error) {
+	files, err := j.Config.Input.Files()
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files found for input %v", j.Config.Input)
+	}
+
+	stubs, err := ParseStubs(files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stubs for %v: %w", files, err)
+	}
+
+	groups := j.group(stubs)
+	defer CloseAllStubs(groups)
+
+	outputs, err := j.outputs()
+	if err != nil {
+		return nil, err
+	}
+
+	outputErrs := make([]error, 0)
+
+	results, err := ProcessGroups(ctx, groups, j.processors, GroupOptions{
+		Workers: j.Config.Workers,
+		Options: func(groupIdx int) ProcessOptions { return ProcessOptions{ErrorPolicy: j.policy} },
+		OnResult: func(result GroupResult) {
+			for idx, output := range outputs {
+				if err := output.Write(result); err != nil {
+					outputErrs = append(outputErrs, fmt.Errorf("output %s failed for group %d: %w", j.Config.Outputs[idx].Name, result.Index, err))
+				}
+			}
+
+			// Processors holding resources, such as external commands, are closed once their results were written
+			for _, proc := range result.Processors {
+				if closer, ok := proc.(io.Closer); ok {
+					closer.Close()
+				}
+			}
+		},
+	})
+
+	for idx, output := range outputs {
+		if err := closeOutput(output); err != nil {
+			outputErrs = append(outputErrs, fmt.Errorf("failed to close output %s: %w", j.Config.Outputs[idx].Name, err))
+		}
+	}
+
+	return results, errors.Join(append([]error{err}, outputErrs...)...)
+}
+
+// processors is the ProcessorFactory creating the configured processors for every group
+func (j *Job) processors(groupIdx int, group StubGroup) ([]Processor, error) {
+	processors := make([]Processor, 0, len(j.Config.Processors))
+
+	for _, procCfg := range j.Config.Processors {
+		proc, err := j.registry.NewProcessor(procCfg.Name, procCfg.Params)
+		if err != nil {
+			return nil, err
+		}
+		processors = append(processors, proc)
+	}
+
+	return processors, nil
+}
+
+// outputs creates the configured outputs. Outputs that were already created are closed when one fails.
+func (j *Job) outputs() ([]Output, error) {
+	outputs := make([]Output, 0, len(j.Config.Outputs))
+
+	for _, outputCfg := range j.Config.Outputs {
+		output, err := j.registry.NewOutput(outputCfg.Name, outputCfg.Params)
+		if err != nil {
+			for _, created := range outputs {
+				closeOutput(created)
+			}
+			return nil, err
+		}
+		outputs = append(outputs, output)
+	}
+
+	return outputs, nil
+}
+
+// closeOutput closes the output if it implements io.Closer
+func closeOutput(output Output) error {
+	if closer, ok := output.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// group the stubs according to the configured grouping
+func (j *Job) group(stubs StubGroup) []StubGroup {
+	switch j.Config.Group {
+	case GroupByFile:
+		groups := make([]StubGroup, len(stubs))
+		for idx, stub := range stubs {
+			groups[idx] = StubGroup{stub}
+		}
+		return groups
+	case GroupNone:
+		return []StubGroup{stubs}
+	}
+
+	return stubs.Group()
+}
@@ -0,0 +1,218 @@
+package ibt
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testOutput struct {
+	results []GroupResult
+	closed  bool
+	err     error
+}
+
+func (t *testOutput) Write(result GroupResult) error {
+	t.results = append(t.results, result)
+
+	return t.err
+}
+
+func (t *testOutput) Close() error {
+	t.closed = true
+
+	return nil
+}
+
+func newTestJobRegistry(output *testOutput) *Registry {
+	registry := NewRegistry()
+
+	registry.Register("vars", func(params Params) (Processor, error) {
+		var cfg struct {
+			Vars []string `yaml:"vars"`
+		}
+		if err := params.Decode(&cfg); err != nil {
+			return nil, err
+		}
+
+		return &testProcessor{whitelist: cfg.Vars}, nil
+	})
+	registry.RegisterOutput("memory", func(params Params) (Output, error) { return output, nil })
+
+	return registry
+}
+
+func TestParseJobConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected JobConfig
+		err      bool
+	}{
+		{
+			name: "yaml",
+			config: `
+name: speeds
+input: .testing/*.ibt
+workers: 2
+error_policy: skip-tick
+processors:
+  - name: vars
+    params:
+      vars: [Speed]
+`,
+			expected: JobConfig{Name: "speeds", Input: Globs{".testing/*.ibt"}, Workers: 2, ErrorPolicy: "skip-tick"},
+		},
+		{
+			name:     "json",
+			config:   `{"name": "speeds", "input": ["a.ibt", "b.ibt"], "group": "file", "processors": [{"name": "vars"}]}`,
+			expected: JobConfig{Name: "speeds", Input: Globs{"a.ibt", "b.ibt"}, Group: GroupByFile},
+		},
+		{
+			name:   "unknown field",
+			config: `{"name": "speeds", "inputs": ["a.ibt"]}`,
+			err:    true,
+		},
+	}
+
+	for _, test := range tests {
+		cfg, err := ParseJobConfig([]byte(test.config))
+		if test.err {
+			if err == nil {
+				t.Errorf("expected ParseJobConfig() to return an error for %s config", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("expected ParseJobConfig() to run without err for %s config. received error: %v", test.name, err)
+			continue
+		}
+
+		if cfg.Name != test.expected.Name || cfg.Group != test.expected.Group || cfg.Workers != test.expected.Workers ||
+			cfg.ErrorPolicy != test.expected.ErrorPolicy || len(cfg.Input) != len(test.expected.Input) || cfg.Input[0] != test.expected.Input[0] {
+			t.Errorf("expected %s config to be parsed as %+v. received %+v", test.name, test.expected, cfg)
+		}
+
+		if len(cfg.Processors) != 1 || cfg.Processors[0].Name != "vars" {
+			t.Errorf("expected %s config to contain processor vars. received %+v", test.name, cfg.Processors)
+		}
+	}
+}
+
+func TestNewJob(t *testing.T) {
+	registry := newTestJobRegistry(&testOutput{})
+
+	valid := func() JobConfig {
+		return JobConfig{
+			Input:      Globs{".testing/valid_test_file.ibt"},
+			Processors: []ComponentConfig{{Name: "vars"}},
+			Outputs:    []ComponentConfig{{Name: "memory"}},
+		}
+	}
+
+	job, err := registry.NewJob(valid())
+	if err != nil {
+		t.Errorf("expected NewJob() to run without err. received error: %v", err)
+	} else if job.Config.Group != GroupBySession || job.policy != FailFast {
+		t.Errorf("expected job to default to group %s and policy %s. received %s and %s", GroupBySession, FailFast, job.Config.Group, job.policy)
+	}
+
+	invalid := map[string]func(cfg *JobConfig){
+		"no input":             func(cfg *JobConfig) { cfg.Input = nil },
+		"unknown group":        func(cfg *JobConfig) { cfg.Group = "lap" },
+		"unknown error policy": func(cfg *JobConfig) { cfg.ErrorPolicy = "ignore" },
+		"no processors":        func(cfg *JobConfig) { cfg.Processors = nil },
+		"unknown processor":    func(cfg *JobConfig) { cfg.Processors[0].Name = "missing" },
+		"unknown output":       func(cfg *JobConfig) { cfg.Outputs[0].Name = "missing" },
+	}
+
+	for name, modify := range invalid {
+		cfg := valid()
+		modify(&cfg)
+
+		if _, err := registry.NewJob(cfg); err == nil {
+			t.Errorf("expected NewJob() to return an error for %s", name)
+		}
+	}
+}
+
+func TestJobRun(t *testing.T) {
+	t.Run("test Job Run() from config file", func(t *testing.T) {
+		output := &testOutput{}
+		registry := newTestJobRegistry(output)
+
+		path := filepath.Join(t.TempDir(), "job.yaml")
+		config := `
+name: speeds
+input:
+  - .testing/valid_test_file.ibt
+  - .testing/valid_*.ibt
+group: file
+processors:
+  - name: vars
+    params:
+      vars: [Speed, Lap]
+outputs:
+  - name: memory
+`
+		if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+			t.Errorf("failed to write job config - %v", err)
+			return
+		}
+
+		job, err := registry.LoadJob(path)
+		if err != nil {
+			t.Errorf("expected LoadJob() to run without err. received error: %v", err)
+			return
+		}
+
+		results, err := job.Run(context.Background())
+		if err != nil {
+			t.Errorf("expected Run() to run without err. received error: %v", err)
+		}
+
+		if len(results) != 1 || len(output.results) != 1 {
+			t.Errorf("expected the input file to be processed once. received %d results and %d outputs", len(results), len(output.results))
+			return
+		}
+
+		proc := results[0].Processors[0].(*testProcessor)
+		if len(proc.results) != 389 || len(proc.results[0]) != 2 {
+			t.Errorf("expected %d ticks with the configured vars. received %d ticks", 389, len(proc.results))
+		}
+
+		if !output.closed {
+			t.Error("expected output to be closed once the job completed")
+		}
+	})
+
+	t.Run("test Job Run() output error", func(t *testing.T) {
+		outputErr := errors.New("unit test output error")
+		registry := newTestJobRegistry(&testOutput{err: outputErr})
+
+		job, _ := registry.NewJob(JobConfig{
+			Input:      Globs{".testing/valid_test_file.ibt"},
+			Processors: []ComponentConfig{{Name: "vars", Params: Params{"vars": []string{"Speed"}}}},
+			Outputs:    []ComponentConfig{{Name: "memory"}},
+		})
+
+		if _, err := job.Run(context.Background()); !errors.Is(err, outputErr) {
+			t.Errorf("expected Run() to return the output error. received: %v", err)
+		}
+	})
+
+	t.Run("test Job Run() without matching files", func(t *testing.T) {
+		registry := newTestJobRegistry(&testOutput{})
+
+		job, _ := registry.NewJob(JobConfig{
+			Input:      Globs{".testing/*.missing"},
+			Processors: []ComponentConfig{{Name: "vars"}},
+		})
+
+		if _, err := job.Run(context.Background()); err == nil {
+			t.Error("expected Run() to return an error when no files match the input")
+		}
+	})
+}
@@ -0,0 +1,307 @@
+// Package laps segments telemetry into structured lap records.
+//
+// A Segmenter processes the ticks of one or more stubs and creates a Lap for every lap number it
+// encounters. Start and end times are interpolated to the moment LapDistPct crosses the start/finish
+// line, resulting in lap times that are accurate beyond the tick rate of the telemetry.
+package laps
+
+import (
+	"context"
+	"math"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/metric"
+)
+
+const (
+	// Default minimum fraction of the lap distance that needs to be covered for a full lap
+	defaultMinCoverage float64 = 0.97
+	// Default maximum difference in seconds between the measured and reported lap time of a valid lap
+	defaultTimeTolerance float64 = 0.1
+)
+
+// MaxPctStep is the largest change in LapDistPct between consecutive ticks that is considered to be driven.
+// Larger changes are caused by crossing the line or relocating the car, such as a tow.
+const MaxPctStep = 0.5
+
+func init() {
+	ibt.Register("laps", func(params ibt.Params) (ibt.Processor, error) {
+		var opts Options
+		if err := params.Decode(&opts); err != nil {
+			return nil, err
+		}
+
+		return NewSegmenter(opts), nil
+	})
+}
+
+// Lap is a single lap of a stub.
+type Lap struct {
+	// Number of the lap, as reported by the Lap variable
+	Number int
+	// Stub is the index of the stub within the group being processed
+	Stub int
+	// Filename of the stub
+	Filename string
+	// SessionNum of the sub-session in which the lap started
+	SessionNum int
+	// StartTick and EndTick are the indexes of the first and last tick of the lap within the stub
+	StartTick int
+	EndTick   int
+	// StartTime and EndTime are the session times at the start and end of the lap. These are interpolated
+	// to the crossing of the start/finish line when available.
+	StartTime float64
+	EndTime   float64
+	// Time is the measured duration of the lap
+	Time metric.LapTime
+	// ReportedTime is the LapLastLapTime reported by iRacing for the lap. This is 0 when the lap time was
+	// not reported before the end of the stub.
+	ReportedTime metric.LapTime
+	// OutLap indicates that the lap started on pit road
+	OutLap bool
+	// InLap indicates that the lap ended on pit road
+	InLap bool
+	// Coverage is the fraction of the lap distance that was driven, based on LapDistPct
+	Coverage float64
+	// Full indicates that the coverage of the lap is at least the configured MinCoverage
+	Full bool
+	// Valid indicates a full lap that is neither an in or out lap, with a measured time matching the
+	// reported time, if available
+	Valid bool
+}
+
+// Options configures the segmentation of laps.
+type Options struct {
+	// MinCoverage is the minimum fraction of the lap distance that needs to be driven for a full lap.
+	// Defaults to 0.97.
+	MinCoverage float64 `yaml:"min_coverage"`
+	// TimeTolerance is the maximum difference in seconds between the measured and reported time of a
+	// valid lap. Defaults to 0.1.
+	TimeTolerance float64 `yaml:"time_tolerance"`
+}
+
+// Segmenter is a processor that segments the ticks of each stub into laps.
+type Segmenter struct {
+	opts Options
+	laps []Lap
+
+	stub    ibt.StubInfo
+	current *Lap
+	prev    lapTick
+	// index of the previous lap awaiting its reported lap time
+	awaiting int
+	reported float32
+	// stale is the reported lap time at the end of the awaiting lap, which still belongs to an earlier lap
+	stale float32
+}
+
+// lapTick is the part of a tick used for segmentation
+type lapTick struct {
+	idx         int
+	lap         int
+	pct         float32
+	sessionTime float64
+	sessionNum  int
+	onPitRoad   bool
+	lastLapTime float32
+}
+
+// NewSegmenter creates a lap Segmenter with the given options.
+func NewSegmenter(opts Options) *Segmenter {
+	if opts.MinCoverage <= 0 {
+		opts.MinCoverage = defaultMinCoverage
+	}
+	if opts.TimeTolerance <= 0 {
+		opts.TimeTolerance = defaultTimeTolerance
+	}
+
+	return &Segmenter{opts: opts, awaiting: -1}
+}
+
+// FromStubs segments the given stubs into laps.
+func FromStubs(ctx context.Context, stubs ibt.StubGroup, opts Options) ([]Lap, error) {
+	segmenter := NewSegmenter(opts)
+
+	if err := ibt.Process(ctx, stubs, segmenter); err != nil {
+		return nil, err
+	}
+
+	return segmenter.Result(), nil
+}
+
+// Whitelist of the variables required for segmentation
+func (s *Segmenter) Whitelist() []string {
+	return []string{"Lap", "LapDistPct", "OnPitRoad", "LapLastLapTime", "SessionNum"}
+}
+
+// StartStub resets the segmentation for a new stub
+func (s *Segmenter) StartStub(stub ibt.StubInfo) error {
+	s.end()
+	s.stub = stub
+
+	return nil
+}
+
+// EndStub completes the last lap of the stub
+func (s *Segmenter) EndStub(stub ibt.StubInfo) error {
+	s.end()
+
+	return nil
+}
+
+// Process the tick without a TickContext. Ticks are indexed from the previous tick of the lap and laps are
+// timed with the SessionTime of the tick, which must be included for the lap times to be known.
+func (s *Segmenter) Process(input ibt.Tick, hasNext bool, session *headers.Session) error {
+	tickCtx := ibt.ContextFromTick(input)
+	if s.current != nil {
+		tickCtx.Index = s.prev.idx + 1
+	}
+
+	return s.ProcessContext(input, tickCtx, hasNext, session)
+}
+
+// ProcessContext adds the tick to the current lap, starting a new lap when the lap number changes
+func (s *Segmenter) ProcessContext(input ibt.Tick, tickCtx ibt.TickContext, hasNext bool, session *headers.Session) error {
+	lap, err := ibt.GetTickValue[int](input, "Lap")
+	if err != nil {
+		return err
+	}
+
+	pct, err := ibt.GetTickValue[float32](input, "LapDistPct")
+	if err != nil {
+		return err
+	}
+
+	// Pit road, reported lap times and the sub-session are not required for segmentation
+	onPitRoad, _ := ibt.GetTickValue[bool](input, "OnPitRoad")
+	lastLapTime, _ := ibt.GetTickValue[float32](input, "LapLastLapTime")
+	sessionNum, _ := ibt.GetTickValue[int](input, "SessionNum")
+
+	tick := lapTick{
+		idx:         tickCtx.Index,
+		lap:         lap,
+		pct:         pct,
+		sessionTime: tickCtx.SessionTime,
+		sessionNum:  sessionNum,
+		onPitRoad:   onPitRoad,
+		lastLapTime: lastLapTime,
+	}
+
+	switch {
+	case s.current == nil:
+		s.start(tick, tick.sessionTime, 0)
+	case lap != s.current.Number:
+		s.next(tick)
+	default:
+		if step := tick.pct - s.prev.pct; step > 0 && step < MaxPctStep {
+			s.current.Coverage += float64(step)
+		}
+	}
+
+	if s.awaiting >= 0 && tick.lastLapTime > 0 && tick.lastLapTime != s.stale {
+		s.reported = tick.lastLapTime
+	}
+
+	s.prev = tick
+
+	if !hasNext {
+		s.end()
+	}
+
+	return nil
+}
+
+// next completes the current lap and starts the lap of the given tick
+func (s *Segmenter) next(tick lapTick) {
+	// The time at which the line was crossed is interpolated between the ticks on either side of it
+	if tick.lap == s.current.Number+1 && s.prev.pct-tick.pct > MaxPctStep {
+		before := 1 - float64(s.prev.pct)
+		after := float64(tick.pct)
+
+		crossing := s.prev.sessionTime
+		if before+after > 0 {
+			crossing += (tick.sessionTime - s.prev.sessionTime) * before / (before + after)
+		}
+
+		s.current.Coverage += before
+		s.complete(crossing)
+		s.start(tick, crossing, after)
+
+		return
+	}
+
+	s.complete(s.prev.sessionTime)
+	s.start(tick, tick.sessionTime, 0)
+}
+
+// start a new lap at the given tick
+func (s *Segmenter) start(tick lapTick, startTime, coverage float64) {
+	s.current = &Lap{
+		Number:     tick.lap,
+		Stub:       s.stub.Index,
+		Filename:   s.stub.Filename,
+		SessionNum: tick.sessionNum,
+		StartTick:  tick.idx,
+		StartTime:  startTime,
+		OutLap:     tick.onPitRoad,
+		Coverage:   coverage,
+	}
+}
+
+// complete the current lap at the given time
+func (s *Segmenter) complete(endTime float64) {
+	s.assignReported()
+
+	lap := s.current
+	lap.EndTick = s.prev.idx
+	lap.EndTime = endTime
+	lap.Time = metric.LapTime(endTime - lap.StartTime)
+	lap.InLap = s.prev.onPitRoad
+	lap.Coverage = math.Min(lap.Coverage, 1)
+	lap.Full = lap.Coverage >= s.opts.MinCoverage
+	s.validate(lap)
+
+	s.laps = append(s.laps, *lap)
+	s.current = nil
+
+	// The reported time of the lap only becomes available during the following lap. Until then, the
+	// time of an earlier lap is reported.
+	s.awaiting = len(s.laps) - 1
+	s.reported = 0
+	s.stale = s.prev.lastLapTime
+}
+
+// end the current stub by completing the current lap
+func (s *Segmenter) end() {
+	if s.current != nil {
+		s.complete(s.prev.sessionTime)
+	}
+
+	// Laps that were completed at the end of a stub will not have their reported time
+	s.assignReported()
+	s.awaiting = -1
+}
+
+// assignReported assigns the reported lap time to the lap awaiting it
+func (s *Segmenter) assignReported() {
+	if s.awaiting < 0 || s.reported <= 0 {
+		return
+	}
+
+	lap := &s.laps[s.awaiting]
+	lap.ReportedTime = metric.LapTime(s.reported)
+	s.validate(lap)
+	s.awaiting = -1
+}
+
+// validate determines whether the lap is valid
+func (s *Segmenter) validate(lap *Lap) {
+	lap.Valid = lap.Full && !lap.InLap && !lap.OutLap
+	if lap.ReportedTime > 0 && math.Abs(float64(lap.Time-lap.ReportedTime)) > s.opts.TimeTolerance {
+		lap.Valid = false
+	}
+}
+
+// Result returns the laps of all stubs processed so far, in the order they were driven
+func (s *Segmenter) Result() []Lap { return s.laps }
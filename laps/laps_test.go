@@ -0,0 +1,172 @@
+package laps
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/ibttest"
+)
+
+// testTicks creates the ticks of a car driving a 10 second lap at a constant speed, starting just before
+// the start/finish line of lap 1. The car starts and ends on pit road.
+func testTicks(duration float64, reported func(t float64) float32) []ibt.Tick {
+	ticks := make([]ibt.Tick, 0)
+
+	for idx := 0; float64(idx)/60 <= duration; idx++ {
+		t := float64(idx) / 60
+		pos := 0.905 + t/10
+
+		ticks = append(ticks, ibt.Tick{
+			"SessionTime":    100 + t,
+			"Lap":            1 + int(math.Floor(pos)),
+			"LapDistPct":     float32(pos - math.Floor(pos)),
+			"OnPitRoad":      t < 0.5 || pos > 3.3,
+			"LapLastLapTime": reported(t),
+		})
+	}
+
+	return ticks
+}
+
+// testReported reports a lap time half a second after each crossing of the line
+func testReported(t float64) float32 {
+	switch {
+	case t > 21.45:
+		return 10.3
+	case t > 11.45:
+		return 10
+	case t > 1.45:
+		return 4.2
+	}
+
+	return -1
+}
+
+func TestSegmenter(t *testing.T) {
+	t.Run("test Segmenter laps", func(t *testing.T) {
+		segmenter := NewSegmenter(Options{})
+		ibttest.Process(t, segmenter, ibt.StubInfo{Index: 0, Filename: "test.ibt"}, testTicks(25, testReported), nil)
+
+		laps := segmenter.Result()
+		if len(laps) != 4 {
+			t.Errorf("expected %d laps. received %d", 4, len(laps))
+			return
+		}
+
+		for idx, lap := range laps {
+			if lap.Number != idx+1 || lap.Filename != "test.ibt" {
+				t.Errorf("expected lap %d of test.ibt. received lap %d of %s", idx+1, lap.Number, lap.Filename)
+			}
+			if idx > 0 && lap.StartTick != laps[idx-1].EndTick+1 {
+				t.Errorf("expected lap %d to start after tick %d. received %d", lap.Number, laps[idx-1].EndTick, lap.StartTick)
+			}
+			if idx > 0 && lap.StartTime != laps[idx-1].EndTime {
+				t.Errorf("expected lap %d to start at the end of the previous lap. received %f and %f", lap.Number, lap.StartTime, laps[idx-1].EndTime)
+			}
+		}
+
+		outLap := laps[0]
+		if !outLap.OutLap || outLap.InLap || outLap.Full || outLap.Valid || math.Abs(outLap.EndTime-100.95) > 0.001 {
+			t.Errorf("expected lap 1 to be a partial out lap ending at %.2f. received %+v", 100.95, outLap)
+		}
+
+		full := laps[1]
+		if math.Abs(float64(full.Time)-10) > 0.001 || full.ReportedTime != 10 || !full.Full || !full.Valid || full.Coverage < 0.999 {
+			t.Errorf("expected lap 2 to be a valid 10 second lap. received %+v", full)
+		}
+
+		mismatch := laps[2]
+		if mismatch.ReportedTime != 10.3 || !mismatch.Full || mismatch.Valid {
+			t.Errorf("expected lap 3 to be invalid due to its reported time. received %+v", mismatch)
+		}
+
+		inLap := laps[3]
+		if !inLap.InLap || inLap.Full || inLap.ReportedTime != 0 || inLap.EndTick != 1500 {
+			t.Errorf("expected lap 4 to be a partial in lap ending at tick %d. received %+v", 1500, inLap)
+		}
+	})
+
+	t.Run("test Segmenter reported time of the previous lap", func(t *testing.T) {
+		segmenter := NewSegmenter(Options{})
+
+		// The reported time is never updated after the out lap
+		reported := func(t float64) float32 {
+			if t > 1.45 {
+				return 0.95
+			}
+			return -1
+		}
+		ibttest.Process(t, segmenter, ibt.StubInfo{}, testTicks(25, reported), nil)
+
+		laps := segmenter.Result()
+		if laps[0].ReportedTime != 0.95 || laps[1].ReportedTime != 0 || laps[2].ReportedTime != 0 {
+			t.Errorf("expected only lap 1 to have a reported time. received %+v", laps)
+		}
+
+		if !laps[1].Valid {
+			t.Errorf("expected lap 2 to be valid without a reported time. received %+v", laps[1])
+		}
+	})
+
+	t.Run("test Segmenter lap change without crossing", func(t *testing.T) {
+		segmenter := NewSegmenter(Options{})
+
+		ticks := []ibt.Tick{
+			{"Lap": 5, "LapDistPct": float32(0.2), "SessionTime": 10.0},
+			{"Lap": 5, "LapDistPct": float32(0.3), "SessionTime": 11.0},
+			// Relocating the car to the pits results in a new lap
+			{"Lap": 6, "LapDistPct": float32(0.9), "SessionTime": 12.0, "OnPitRoad": true},
+		}
+		ibttest.Process(t, segmenter, ibt.StubInfo{}, ticks, nil)
+
+		laps := segmenter.Result()
+		if len(laps) != 2 || laps[0].EndTime != 11 || laps[1].StartTime != 12 || !laps[1].OutLap {
+			t.Errorf("expected the lap to end at the last tick before the lap change. received %+v", laps)
+		}
+
+		if math.Abs(laps[0].Coverage-0.1) > 0.0001 {
+			t.Errorf("expected coverage of %.1f. received %f", 0.1, laps[0].Coverage)
+		}
+	})
+
+	t.Run("test Segmenter missing variables", func(t *testing.T) {
+		if err := NewSegmenter(Options{}).Process(ibt.Tick{"Lap": 1}, true, nil); err == nil {
+			t.Error("expected Process() to return an error when LapDistPct is missing")
+		}
+	})
+}
+
+func TestFromStubs(t *testing.T) {
+	stubs, err := ibt.ParseStubs("../.testing/valid_test_file.ibt")
+	if err != nil {
+		t.Errorf("failed to parse stubs for testing file - %v", err)
+		return
+	}
+	defer stubs.Close()
+
+	laps, err := FromStubs(context.Background(), stubs, Options{})
+	if err != nil {
+		t.Errorf("expected FromStubs() to run without err. received error: %v", err)
+		return
+	}
+
+	if len(laps) != 1 {
+		t.Errorf("expected %d lap. received %d", 1, len(laps))
+		return
+	}
+
+	lap := laps[0]
+	if lap.Number != 9 || lap.StartTick != 0 || lap.EndTick != 388 || lap.Full || lap.Valid {
+		t.Errorf("expected a single partial lap 9 from tick 0 to %d. received %+v", 388, lap)
+	}
+
+	if !lap.OutLap || !lap.InLap {
+		t.Errorf("expected lap spent in the pits to be an in and out lap. received %+v", lap)
+	}
+
+	if math.Abs(lap.StartTime-932.0) > 0.1 || lap.Time <= 6 {
+		t.Errorf("expected lap to start at the start of the stub. received %+v", lap)
+	}
+}
@@ -0,0 +1,299 @@
+package laps
+
+import (
+	"sort"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/metric"
+)
+
+func init() {
+	ibt.Register("sectors", func(params ibt.Params) (ibt.Processor, error) { return NewSectorTimer(), nil })
+}
+
+// LapSectors are the sector times of a single lap.
+type LapSectors struct {
+	// Number of the lap, as reported by the Lap variable
+	Number int
+	// Stub is the index of the stub within the group being processed
+	Stub int
+	// Filename of the stub
+	Filename string
+	// Times of each sector. A time of 0 indicates that the sector was not completed during the lap or
+	// that it was driven on pit road.
+	Times []metric.LapTime
+}
+
+// Complete indicates whether every sector of the lap was timed
+func (l LapSectors) Complete() bool {
+	for _, t := range l.Times {
+		if t <= 0 {
+			return false
+		}
+	}
+
+	return len(l.Times) > 0
+}
+
+// StubSectors are the best sector times of a single stub.
+type StubSectors struct {
+	Stub     int
+	Filename string
+	// Best time of each sector
+	Best []metric.LapTime
+	// Optimal lap time, which is the sum of the best sector times. This is 0 unless every sector was timed.
+	Optimal metric.LapTime
+}
+
+// SectorResult contains the sector times of all laps processed by a SectorTimer.
+type SectorResult struct {
+	// Starts are the SectorStartPct of each sector
+	Starts []float64
+	// Laps contains the sector times of each lap, in the order they were driven
+	Laps []LapSectors
+	// Stubs contains the best sector times of each stub
+	Stubs []StubSectors
+	// Best time of each sector across all stubs
+	Best []metric.LapTime
+	// Optimal lap time across all stubs, which is the sum of the best sector times. This is 0 unless every
+	// sector was timed.
+	Optimal metric.LapTime
+}
+
+// SectorTimer is a processor that times the sectors of every lap.
+//
+// Sectors are read from the SplitTimeInfo of the session. The time at which a sector starts is
+// interpolated between the ticks on either side of its SectorStartPct using LapDistPct and SessionTime.
+// Sectors during which the car was on pit road are not timed.
+type SectorTimer struct {
+	starts []float64
+	laps   []LapSectors
+	stubs  []ibt.StubInfo
+
+	stub    ibt.StubInfo
+	current []float64
+	prev    lapTick
+	hasPrev bool
+
+	// sector that was started last, along with when and during which lap
+	sector     int
+	sectorTime float64
+	sectorLap  int
+	pitted     bool
+	lapIndexes map[lapKey]int
+}
+
+// lapKey identifies a lap within a group of stubs
+type lapKey struct {
+	stub int
+	lap  int
+}
+
+// NewSectorTimer creates a new SectorTimer.
+func NewSectorTimer() *SectorTimer {
+	return &SectorTimer{sector: -1, lapIndexes: make(map[lapKey]int)}
+}
+
+// Whitelist of the variables required for timing sectors
+func (s *SectorTimer) Whitelist() []string { return []string{"Lap", "LapDistPct", "OnPitRoad"} }
+
+// StartStub resets the timing for a new stub
+func (s *SectorTimer) StartStub(stub ibt.StubInfo) error {
+	s.stub = stub
+	s.stubs = append(s.stubs, stub)
+	s.current = nil
+	s.hasPrev = false
+	s.sector = -1
+
+	return nil
+}
+
+// Process the tick without a TickContext, timing the sectors with the SessionTime of the tick
+func (s *SectorTimer) Process(input ibt.Tick, hasNext bool, session *headers.Session) error {
+	return s.ProcessContext(input, ibt.ContextFromTick(input), hasNext, session)
+}
+
+// ProcessContext times any sectors that were completed since the previous tick
+func (s *SectorTimer) ProcessContext(input ibt.Tick, tickCtx ibt.TickContext, hasNext bool, session *headers.Session) error {
+	lap, err := ibt.GetTickValue[int](input, "Lap")
+	if err != nil {
+		return err
+	}
+
+	pct, err := ibt.GetTickValue[float32](input, "LapDistPct")
+	if err != nil {
+		return err
+	}
+
+	onPitRoad, _ := ibt.GetTickValue[bool](input, "OnPitRoad")
+
+	// Processors used outside of ibt.Process may not be notified of the start of a stub
+	if len(s.stubs) == 0 {
+		s.StartStub(ibt.StubInfo{})
+	}
+
+	if s.current == nil {
+		s.current = sectorStarts(session)
+		if s.starts == nil {
+			s.starts = s.current
+		}
+	}
+
+	tick := lapTick{lap: lap, pct: pct, sessionTime: tickCtx.SessionTime, onPitRoad: onPitRoad}
+
+	if s.hasPrev {
+		s.cross(s.prev, tick)
+	}
+
+	if tick.onPitRoad {
+		s.pitted = true
+	}
+
+	s.prev = tick
+	s.hasPrev = true
+
+	return nil
+}
+
+// cross starts each sector whose start lies between the given ticks
+func (s *SectorTimer) cross(from, to lapTick) {
+	step := to.pct - from.pct
+	dt := to.sessionTime - from.sessionTime
+
+	switch {
+	case step > 0 && step < MaxPctStep:
+		for idx, start := range s.current {
+			if start > float64(from.pct) && start <= float64(to.pct) {
+				fraction := (start - float64(from.pct)) / float64(step)
+				s.start(idx, from.sessionTime+dt*fraction, to.lap, to.onPitRoad)
+			}
+		}
+	case step < -MaxPctStep:
+		// The start/finish line was crossed, which may include the starts of sectors on either side of it
+		distance := 1 - float64(from.pct) + float64(to.pct)
+
+		for idx, start := range s.current {
+			if idx > 0 && start > float64(from.pct) {
+				s.start(idx, from.sessionTime+dt*(start-float64(from.pct))/distance, from.lap, from.onPitRoad)
+			}
+		}
+
+		s.start(0, from.sessionTime+dt*(1-float64(from.pct))/distance, to.lap, to.onPitRoad)
+
+		for idx, start := range s.current {
+			if idx > 0 && start <= float64(to.pct) {
+				s.start(idx, from.sessionTime+dt*(1-float64(from.pct)+start)/distance, to.lap, to.onPitRoad)
+			}
+		}
+	case step >= MaxPctStep:
+		// The car was relocated, so the sector that was started can no longer be timed
+		s.sector = -1
+	}
+}
+
+// start the sector of the given lap at the given time, completing the previous sector if it was driven entirely
+func (s *SectorTimer) start(sector int, sessionTime float64, lap int, onPitRoad bool) {
+	if s.sector >= 0 && sector == (s.sector+1)%len(s.current) && !s.pitted {
+		sectors := s.lap(s.sectorLap)
+		sectors.Times[s.sector] = metric.LapTime(sessionTime - s.sectorTime)
+	}
+
+	s.sector = sector
+	s.sectorTime = sessionTime
+	s.sectorLap = lap
+	s.pitted = onPitRoad
+}
+
+// lap returns the sector times of the given lap of the current stub, creating them if needed
+func (s *SectorTimer) lap(number int) *LapSectors {
+	key := lapKey{stub: s.stub.Index, lap: number}
+
+	idx, ok := s.lapIndexes[key]
+	if !ok {
+		idx = len(s.laps)
+		s.lapIndexes[key] = idx
+		s.laps = append(s.laps, LapSectors{
+			Number:   number,
+			Stub:     s.stub.Index,
+			Filename: s.stub.Filename,
+			Times:    make([]metric.LapTime, len(s.current)),
+		})
+	}
+
+	return &s.laps[idx]
+}
+
+// Result of the sectors timed so far
+func (s *SectorTimer) Result() SectorResult {
+	result := SectorResult{Starts: s.starts, Laps: s.laps}
+
+	for _, stub := range s.stubs {
+		laps := make([]LapSectors, 0)
+		for _, lap := range s.laps {
+			if lap.Stub == stub.Index {
+				laps = append(laps, lap)
+			}
+		}
+
+		best := bestSectors(laps, len(s.starts))
+		result.Stubs = append(result.Stubs, StubSectors{
+			Stub:     stub.Index,
+			Filename: stub.Filename,
+			Best:     best,
+			Optimal:  optimal(best),
+		})
+	}
+
+	result.Best = bestSectors(s.laps, len(s.starts))
+	result.Optimal = optimal(result.Best)
+
+	return result
+}
+
+// sectorStarts of the session, sorted by their SectorStartPct. A single sector is used when the
+// session does not contain any sectors.
+func sectorStarts(session *headers.Session) []float64 {
+	starts := []float64{0}
+
+	if session != nil {
+		for _, sector := range session.SplitTimeInfo.Sectors {
+			if sector.SectorStartPct > 0 && sector.SectorStartPct < 1 {
+				starts = append(starts, sector.SectorStartPct)
+			}
+		}
+	}
+
+	sort.Float64s(starts)
+
+	return starts
+}
+
+// bestSectors is the fastest time of each sector of the given laps
+func bestSectors(laps []LapSectors, sectors int) []metric.LapTime {
+	best := make([]metric.LapTime, sectors)
+
+	for _, lap := range laps {
+		for idx, t := range lap.Times {
+			if idx < sectors && t > 0 && (best[idx] == 0 || t < best[idx]) {
+				best[idx] = t
+			}
+		}
+	}
+
+	return best
+}
+
+// optimal lap time from the given best sector times. This is 0 unless every sector was timed.
+func optimal(best []metric.LapTime) metric.LapTime {
+	var total metric.LapTime
+
+	for _, t := range best {
+		if t <= 0 {
+			return 0
+		}
+		total += t
+	}
+
+	return total
+}
@@ -0,0 +1,162 @@
+package laps
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/ibttest"
+	"github.com/teamjorge/ibt/metric"
+)
+
+// sectorTicks creates the ticks of a car driving each sector in the given number of seconds.
+//
+// The car starts at 0.9 of lap 1 and drives the remainder of that lap in a second.
+func sectorTicks(starts []float64, durations [][]float64) []ibt.Tick {
+	// Positions and times of each sector start, including the end of the final sector
+	positions, times := []float64{0.9}, []float64{0}
+	for lapIdx, lap := range durations {
+		for sectorIdx, duration := range lap {
+			end := float64(lapIdx+1) + 1
+			if sectorIdx < len(starts)-1 {
+				end = float64(lapIdx+1) + starts[sectorIdx+1]
+			}
+			positions = append(positions, end)
+			times = append(times, times[len(times)-1]+duration)
+		}
+	}
+	positions = append([]float64{positions[0]}, append([]float64{1}, positions[1:]...)...)
+	times = append([]float64{0}, append([]float64{1}, times[1:]...)...)
+	for idx := 2; idx < len(times); idx++ {
+		times[idx]++
+	}
+
+	ticks := make([]ibt.Tick, 0)
+	segment := 0
+	for idx := 0; ; idx++ {
+		t := float64(idx) / 60
+		for segment < len(times)-1 && t > times[segment+1] {
+			segment++
+		}
+		if segment == len(times)-1 {
+			break
+		}
+
+		fraction := (t - times[segment]) / (times[segment+1] - times[segment])
+		pos := positions[segment] + (positions[segment+1]-positions[segment])*fraction
+
+		ticks = append(ticks, ibt.Tick{
+			"SessionTime": 50 + t,
+			"Lap":         1 + int(math.Floor(pos)),
+			"LapDistPct":  float32(pos - math.Floor(pos)),
+			"OnPitRoad":   false,
+		})
+	}
+
+	return ticks
+}
+
+func equalTimes(a, b []metric.LapTime) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for idx := range a {
+		if math.Abs(float64(a[idx]-b[idx])) > 0.001 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestSectorTimer(t *testing.T) {
+	session := &headers.Session{SplitTimeInfo: headers.SplitTimeInfo{Sectors: []headers.Sectors{
+		{SectorNum: 0, SectorStartPct: 0},
+		{SectorNum: 1, SectorStartPct: 0.3},
+		{SectorNum: 2, SectorStartPct: 0.6},
+	}}}
+	starts := []float64{0, 0.3, 0.6}
+
+	t.Run("test SectorTimer laps and best sectors", func(t *testing.T) {
+		timer := NewSectorTimer()
+
+		ibttest.Process(t, timer, ibt.StubInfo{Index: 0, Filename: "first.ibt"}, sectorTicks(starts, [][]float64{{3, 3, 4}, {3.5, 2.5, 3.5}}), session)
+		ibttest.Process(t, timer, ibt.StubInfo{Index: 1, Filename: "second.ibt"}, sectorTicks(starts, [][]float64{{3.2, 2.8, 3.4}}), session)
+
+		result := timer.Result()
+
+		if len(result.Starts) != 3 || result.Starts[1] != 0.3 {
+			t.Errorf("expected sector starts %v. received %v", starts, result.Starts)
+		}
+
+		if len(result.Laps) != 3 {
+			t.Errorf("expected sector times for %d laps. received %+v", 3, result.Laps)
+			return
+		}
+
+		expected := [][]metric.LapTime{{3, 3, 4}, {3.5, 2.5, 3.5}, {3.2, 2.8, 3.4}}
+		for idx, lap := range result.Laps {
+			if !equalTimes(lap.Times, expected[idx]) || !lap.Complete() || lap.Number != 2+idx%2 {
+				t.Errorf("expected lap %d to have sector times %v. received %+v", idx, expected[idx], lap)
+			}
+		}
+
+		if len(result.Stubs) != 2 || !equalTimes(result.Stubs[0].Best, []metric.LapTime{3, 2.5, 3.5}) ||
+			math.Abs(float64(result.Stubs[0].Optimal)-9) > 0.001 || result.Stubs[1].Filename != "second.ibt" {
+			t.Errorf("expected the best sectors of each stub. received %+v", result.Stubs)
+		}
+
+		if !equalTimes(result.Best, []metric.LapTime{3, 2.5, 3.4}) || math.Abs(float64(result.Optimal)-8.9) > 0.001 {
+			t.Errorf("expected best sectors %v and optimal lap %.1f. received %v and %v", []float64{3, 2.5, 3.4}, 8.9, result.Best, result.Optimal)
+		}
+	})
+
+	t.Run("test SectorTimer pit road", func(t *testing.T) {
+		timer := NewSectorTimer()
+
+		ticks := sectorTicks(starts, [][]float64{{3, 3, 4}})
+		for _, tick := range ticks {
+			if tick["LapDistPct"].(float32) > 0.4 && tick["LapDistPct"].(float32) < 0.5 {
+				tick["OnPitRoad"] = true
+			}
+		}
+		ibttest.Process(t, timer, ibt.StubInfo{}, ticks, session)
+
+		result := timer.Result()
+		if len(result.Laps) != 1 || result.Laps[0].Times[1] != 0 || result.Laps[0].Complete() || result.Optimal != 0 {
+			t.Errorf("expected the sector driven on pit road not to be timed. received %+v", result)
+		}
+	})
+
+	t.Run("test SectorTimer without sectors", func(t *testing.T) {
+		timer := NewSectorTimer()
+		ibttest.Process(t, timer, ibt.StubInfo{}, sectorTicks([]float64{0}, [][]float64{{10}}), nil)
+
+		result := timer.Result()
+		if len(result.Laps) != 1 || !equalTimes(result.Laps[0].Times, []metric.LapTime{10}) || math.Abs(float64(result.Optimal)-10) > 0.001 {
+			t.Errorf("expected the whole lap to be a single sector. received %+v", result)
+		}
+	})
+
+	t.Run("test SectorTimer with ibt.Process", func(t *testing.T) {
+		stubs, err := ibt.ParseStubs("../.testing/valid_test_file.ibt")
+		if err != nil {
+			t.Errorf("failed to parse stubs for testing file - %v", err)
+			return
+		}
+		defer stubs.Close()
+
+		timer := NewSectorTimer()
+		if err := ibt.Process(context.Background(), stubs, timer); err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		result := timer.Result()
+		if len(result.Starts) != 3 || result.Starts[2] != 0.668198 || len(result.Laps) != 0 {
+			t.Errorf("expected the sectors of the session without any timed laps. received %+v", result)
+		}
+	})
+}
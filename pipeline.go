@@ -0,0 +1,368 @@
+package ibt
+
+import (
+	"errors"
+
+	"github.com/teamjorge/ibt/headers"
+)
+
+// TickPredicate determines whether a tick should continue through a pipeline.
+type TickPredicate func(input Tick, session *headers.Session) bool
+
+// TickMapper transforms a tick as it passes through a pipeline.
+//
+// The given tick is a copy and can be modified freely.
+type TickMapper func(input Tick, session *headers.Session) Tick
+
+// TickReducer combines consecutive ticks into a single tick.
+type TickReducer func(ticks []Tick) Tick
+
+// PipelineBuilder composes stages that ticks pass through before reaching processors.
+//
+// A pipeline is created with Pipeline() and completed with To(), for example:
+//
+//	ibt.Pipeline().OnTrack().Laps(5, 10).Reduce(6).To(processors...)
+type PipelineBuilder struct {
+	stages    []pipelineStage
+	whitelist []string
+}
+
+// Pipeline creates a new PipelineBuilder without any stages.
+func Pipeline() *PipelineBuilder { return new(PipelineBuilder) }
+
+// Filter only passes on ticks matching the given predicate.
+//
+// vars are the telemetry variables required by the predicate. They are not derived from the predicate, so
+// any variable it reads that is not listed, or required by a processor, will be missing from the tick.
+func (b *PipelineBuilder) Filter(pred TickPredicate, vars ...string) *PipelineBuilder {
+	b.whitelist = append(b.whitelist, vars...)
+	b.stages = append(b.stages, &filterStage{pred: pred})
+
+	return b
+}
+
+// Map transforms each tick with the given function.
+//
+// vars are the telemetry variables required by the function. As with Filter, any variable it reads that is
+// not listed, or required by a processor, will be missing from the tick.
+func (b *PipelineBuilder) Map(fn TickMapper, vars ...string) *PipelineBuilder {
+	b.whitelist = append(b.whitelist, vars...)
+	b.stages = append(b.stages, &mapStage{fn: fn})
+
+	return b
+}
+
+// Reduce combines every n consecutive ticks into a single tick by averaging their values with MeanTicks.
+//
+// The ticks are reduced in tumbling windows that do not overlap, lowering the rate of the ticks received by
+// the processors of the pipeline by a factor of n. Implement WindowProcessor to receive a sliding window of
+// the ticks preceding each tick instead.
+func (b *PipelineBuilder) Reduce(n int) *PipelineBuilder { return b.ReduceFunc(n, MeanTicks) }
+
+// ReduceFunc combines every n consecutive ticks into a single tick with the given reducer, in the same
+// tumbling windows as Reduce.
+//
+// Any remaining ticks will be reduced once the last tick of a stub has been received.
+// vars are the telemetry variables required by the reducer.
+func (b *PipelineBuilder) ReduceFunc(n int, reduce TickReducer, vars ...string) *PipelineBuilder {
+	if n <= 0 {
+		n = 1
+	}
+
+	b.whitelist = append(b.whitelist, vars...)
+	b.stages = append(b.stages, &reduceStage{size: n, reduce: reduce, buf: make([]Tick, 0, n)})
+
+	return b
+}
+
+// OnTrack only passes on ticks where the car is on track.
+func (b *PipelineBuilder) OnTrack() *PipelineBuilder {
+	return b.Filter(func(input Tick, session *headers.Session) bool {
+		onTrack, err := GetTickValue[bool](input, "IsOnTrack")
+		return err == nil && onTrack
+	}, "IsOnTrack")
+}
+
+// Laps only passes on ticks where the lap is between from and to (inclusive).
+func (b *PipelineBuilder) Laps(from, to int) *PipelineBuilder {
+	return b.Filter(func(input Tick, session *headers.Session) bool {
+		lap, err := GetTickValue[int](input, "Lap")
+		return err == nil && lap >= from && lap <= to
+	}, "Lap")
+}
+
+// SessionTypes only passes on ticks from sessions of the given types, such as "Race" or "Practice".
+func (b *PipelineBuilder) SessionTypes(sessionTypes ...string) *PipelineBuilder {
+	return b.Filter(func(input Tick, session *headers.Session) bool {
+		sessionNum, err := GetTickValue[int](input, "SessionNum")
+		if err != nil || session == nil {
+			return false
+		}
+
+		for _, s := range session.SessionInfo.Sessions {
+			if s.SessionNum != sessionNum {
+				continue
+			}
+			for _, sessionType := range sessionTypes {
+				if s.SessionType == sessionType {
+					return true
+				}
+			}
+		}
+
+		return false
+	}, "SessionNum")
+}
+
+// To completes the pipeline by sending the resulting ticks to the given processors.
+//
+// The returned Processor can be used with Process like any other. Its whitelist contains the
+// variables required by every stage and processor. Each processor of the pipeline receives all of
+// these variables, along with any added by Map stages. Lifecycle hooks and tick contexts are passed
+// on to the processors of the pipeline. The returned Processor only implements StubStarter and StubEnder
+// when one of the processors does, which avoids parsing the car setup of each stub when it is not needed.
+//
+// Processors will not receive a tick with hasNext set to false when the final tick of a stub was
+// filtered out. Implement StubEnder to reliably detect the end of a stub.
+func (b *PipelineBuilder) To(processors ...Processor) Processor {
+	p := &pipelineProcessor{
+		stages:     b.stages,
+		processors: processors,
+		handles:    newHandles(processors...),
+		whitelist:  b.whitelist,
+	}
+
+	for _, proc := range processors {
+		_, isStarter := proc.(StubStarter)
+		_, isEnder := proc.(StubEnder)
+		if isStarter || isEnder {
+			return &stubPipelineProcessor{p}
+		}
+	}
+
+	return p
+}
+
+// stagedTick is a tick passing through a pipeline
+type stagedTick struct {
+	tick Tick
+	// from is the index of the first tick of the stub the tick was derived from
+	from int
+}
+
+// pipelineStage is a single step of a pipeline.
+type pipelineStage interface {
+	// push a tick into the stage and return the ticks to pass on
+	push(input stagedTick, session *headers.Session) []stagedTick
+	// flush any ticks held by the stage once the end of a stub is reached
+	flush() []stagedTick
+	// reset the state of the stage for a new stub
+	reset()
+}
+
+type filterStage struct{ pred TickPredicate }
+
+func (s *filterStage) push(input stagedTick, session *headers.Session) []stagedTick {
+	if s.pred(input.tick, session) {
+		return []stagedTick{input}
+	}
+
+	return nil
+}
+
+func (s *filterStage) flush() []stagedTick { return nil }
+
+func (s *filterStage) reset() {}
+
+type mapStage struct{ fn TickMapper }
+
+func (s *mapStage) push(input stagedTick, session *headers.Session) []stagedTick {
+	// The tick may be shared with other processors, so only a copy is modified
+	return []stagedTick{{tick: s.fn(input.tick.Copy(), session), from: input.from}}
+}
+
+func (s *mapStage) flush() []stagedTick { return nil }
+
+func (s *mapStage) reset() {}
+
+type reduceStage struct {
+	size   int
+	reduce TickReducer
+	buf    []Tick
+	// from is the index of the first tick of the stub contributing to the buffered ticks
+	from int
+}
+
+func (s *reduceStage) push(input stagedTick, session *headers.Session) []stagedTick {
+	if len(s.buf) == 0 {
+		s.from = input.from
+	}
+
+	s.buf = append(s.buf, input.tick)
+	if len(s.buf) < s.size {
+		return nil
+	}
+
+	return s.flush()
+}
+
+func (s *reduceStage) flush() []stagedTick {
+	if len(s.buf) == 0 {
+		return nil
+	}
+
+	reduced := s.reduce(s.buf)
+	s.buf = s.buf[:0]
+
+	return []stagedTick{{tick: reduced, from: s.from}}
+}
+
+func (s *reduceStage) reset() { s.buf = s.buf[:0] }
+
+// pipelineProcessor passes ticks through the stages of a pipeline before processing them.
+type pipelineProcessor struct {
+	stages     []pipelineStage
+	processors []Processor
+	handles    []*handle
+	whitelist  []string
+}
+
+// Whitelist combines the variables required by the stages and processors of the pipeline
+func (p *pipelineProcessor) Whitelist() []string {
+	whitelist := append([]string{}, p.whitelist...)
+
+	for _, proc := range p.processors {
+		procWhitelist := proc.Whitelist()
+		if len(procWhitelist) == 0 {
+			return []string{"*"}
+		}
+		whitelist = append(whitelist, procWhitelist...)
+	}
+
+	return whitelist
+}
+
+// Process the tick without a TickContext, passing on the session time of the tick to the processors
+func (p *pipelineProcessor) Process(input Tick, hasNext bool, session *headers.Session) error {
+	return p.ProcessContext(input, ContextFromTick(input), hasNext, session)
+}
+
+// ProcessContext passes the tick through each stage and processes the resulting ticks
+func (p *pipelineProcessor) ProcessContext(input Tick, tickCtx TickContext, hasNext bool, session *headers.Session) error {
+	ticks := []stagedTick{{tick: input, from: tickCtx.Index}}
+
+	for _, stage := range p.stages {
+		next := make([]stagedTick, 0, len(ticks))
+		for _, tick := range ticks {
+			next = append(next, stage.push(tick, session)...)
+		}
+		if !hasNext {
+			next = append(next, stage.flush()...)
+		}
+		ticks = next
+	}
+
+	for idx, tick := range ticks {
+		// Only the final tick of a stub should indicate that no more ticks are available
+		tickHasNext := hasNext || idx < len(ticks)-1
+
+		for _, h := range p.handles {
+			if err := h.deliver(tick.tick, tick.from, tickCtx, tickHasNext, session); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resetStub resets the stages and handles of the pipeline for a new stub
+func (p *pipelineProcessor) resetStub(stub StubInfo) {
+	for _, stage := range p.stages {
+		stage.reset()
+	}
+
+	for _, h := range p.handles {
+		h.startStub(stub)
+	}
+}
+
+// stubPipelineProcessor is a pipeline with processors implementing StubStarter or StubEnder.
+type stubPipelineProcessor struct{ *pipelineProcessor }
+
+// StartStub notifies the processors of the pipeline
+func (p *stubPipelineProcessor) StartStub(stub StubInfo) error {
+	for _, proc := range p.processors {
+		if starter, ok := proc.(StubStarter); ok {
+			if err := starter.StartStub(stub); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// EndStub notifies the processors of the pipeline
+func (p *stubPipelineProcessor) EndStub(stub StubInfo) error {
+	errs := make([]error, 0)
+
+	for _, proc := range p.processors {
+		if ender, ok := proc.(StubEnder); ok {
+			if err := ender.EndStub(stub); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// undelivered is the index of the first tick of the stub held by the stages or processors of the pipeline
+func (p *pipelineProcessor) undelivered() (int, bool) {
+	from, ok := 0, false
+
+	for _, stage := range p.stages {
+		if reduce, isReduce := stage.(*reduceStage); isReduce && len(reduce.buf) > 0 && (!ok || reduce.from < from) {
+			from, ok = reduce.from, true
+		}
+	}
+
+	for _, h := range p.handles {
+		if handleFrom, held := h.undelivered(); held && (!ok || handleFrom < from) {
+			from, ok = handleFrom, true
+		}
+	}
+
+	return from, ok
+}
+
+// Flush the processors of the pipeline
+func (p *pipelineProcessor) Flush() error {
+	errs := make([]error, 0)
+
+	for _, proc := range p.processors {
+		if flusher, ok := proc.(Flusher); ok {
+			if err := flusher.Flush(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Finish notifies the processors of the pipeline
+func (p *pipelineProcessor) Finish() error {
+	errs := make([]error, 0)
+
+	for _, proc := range p.processors {
+		if finisher, ok := proc.(Finisher); ok {
+			if err := finisher.Finish(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,184 @@
+package ibt
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/teamjorge/ibt/headers"
+)
+
+type testHasNextProcessor struct {
+	testProcessor
+	hasNext []bool
+}
+
+func (t *testHasNextProcessor) Process(input Tick, hasNext bool, session *headers.Session) error {
+	t.hasNext = append(t.hasNext, hasNext)
+
+	return t.testProcessor.Process(input, hasNext, session)
+}
+
+func TestPipeline(t *testing.T) {
+	f, err := os.Open(".testing/valid_test_file.ibt")
+	if err != nil {
+		t.Errorf("failed to open testing file - %v", err)
+		return
+	}
+	defer f.Close()
+
+	testHeaders, err := headers.ParseHeaders(f)
+	if err != nil {
+		t.Errorf("failed to parse header for testing file - %v", err)
+		return
+	}
+
+	stubs := StubGroup{
+		{filepath: ".testing/valid_test_file.ibt", header: testHeaders, r: f},
+	}
+
+	t.Run("test pipeline Whitelist()", func(t *testing.T) {
+		pipeline := Pipeline().OnTrack().Laps(1, 2).To(&testProcessor{whitelist: []string{"Speed"}})
+
+		whitelist := pipeline.Whitelist()
+		sort.Strings(whitelist)
+
+		expected := []string{"IsOnTrack", "Lap", "Speed"}
+		if !reflect.DeepEqual(whitelist, expected) {
+			t.Errorf("expected pipeline whitelist to be %v. received %v", expected, whitelist)
+		}
+
+		pipeline = Pipeline().OnTrack().To(&testProcessor{whitelist: []string{"Speed"}}, &testProcessor{})
+		if !reflect.DeepEqual(pipeline.Whitelist(), []string{"*"}) {
+			t.Errorf("expected pipeline whitelist to be * when a processor requires all variables. received %v", pipeline.Whitelist())
+		}
+	})
+
+	t.Run("test pipeline filter with Process()", func(t *testing.T) {
+		included := testProcessor{whitelist: []string{"Speed"}}
+		excluded := testProcessor{whitelist: []string{"Speed"}}
+
+		err := Process(context.Background(), stubs,
+			Pipeline().OnTrack().Laps(9, 9).To(&included),
+			Pipeline().Laps(1, 8).To(&excluded),
+		)
+		if err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		if len(included.results) != 389 {
+			t.Errorf("expected all %d ticks of lap 9 to pass the filter. received %d", 389, len(included.results))
+		}
+
+		if _, ok := included.results[0]["Lap"]; !ok {
+			t.Error("expected the variables of the pipeline stages to be passed to its processors")
+		}
+
+		if len(excluded.results) != 0 {
+			t.Errorf("expected no ticks to pass the filter. received %d", len(excluded.results))
+		}
+	})
+
+	t.Run("test pipeline map and reduce with Process()", func(t *testing.T) {
+		proc := testHasNextProcessor{testProcessor: testProcessor{whitelist: []string{"Speed"}}}
+
+		double := func(input Tick, session *headers.Session) Tick {
+			input["Speed"] = input["Speed"].(float32) * 2
+			return input
+		}
+
+		sibling := testProcessor{whitelist: []string{"Speed"}}
+
+		err := Process(context.Background(), stubs, Pipeline().Map(double).Reduce(60).To(&proc), &sibling)
+		if err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		if len(proc.results) != 7 {
+			t.Errorf("expected 389 ticks to be reduced to %d windows. received %d", 7, len(proc.results))
+			return
+		}
+
+		expectedHasNext := []bool{true, true, true, true, true, true, false}
+		if !reflect.DeepEqual(proc.hasNext, expectedHasNext) {
+			t.Errorf("expected only the final window to indicate the end of the stub. received %v", proc.hasNext)
+		}
+
+		// The sibling processor receives the same ticks, which should not be modified by the map stage
+		var sum float32
+		for _, tick := range sibling.results[:60] {
+			sum += tick["Speed"].(float32)
+		}
+
+		expectedSpeed := sum / 60 * 2
+		if diff := proc.results[0]["Speed"].(float32) - expectedSpeed; diff > 0.0001 || diff < -0.0001 {
+			t.Errorf("expected first window to contain the mean doubled speed %f. received %f", expectedSpeed, proc.results[0]["Speed"])
+		}
+	})
+
+	t.Run("test pipeline lifecycle hooks", func(t *testing.T) {
+		proc := testHookProcessor{testProcessor: testProcessor{whitelist: []string{"Speed"}}}
+
+		if err := Process(context.Background(), stubs, Pipeline().OnTrack().To(&proc)); err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		expectedEvents := []string{"start", "tick", "end", "finish"}
+		if !reflect.DeepEqual(proc.events, expectedEvents) {
+			t.Errorf("expected hook events to be %v. received %v", expectedEvents, proc.events)
+		}
+	})
+
+	t.Run("test pipeline lifecycle hooks only when required", func(t *testing.T) {
+		pipeline := Pipeline().Reduce(2).To(&testProcessor{whitelist: []string{"Speed"}})
+
+		_, isStarter := pipeline.(StubStarter)
+		_, isEnder := pipeline.(StubEnder)
+		if isStarter || isEnder {
+			t.Errorf("expected a pipeline without lifecycle hooks. received %T", pipeline)
+		}
+		if info := newStubInfo(0, stubs[0], pipeline); info.CarSetup != nil {
+			t.Errorf("expected the car setup not to be parsed. received %+v", info.CarSetup)
+		}
+
+		if _, ok := Pipeline().To(&testHookProcessor{}).(StubStarter); !ok {
+			t.Error("expected a pipeline with a StubStarter processor to implement StubStarter")
+		}
+
+		// The stages are still reset at the start of each stub
+		h := newHandles(pipeline)[0]
+		if err := h.deliver(Tick{"Speed": float32(1)}, 0, TickContext{}, true, nil); err != nil {
+			t.Errorf("expected deliver() to run without err. received error: %v", err)
+		}
+		if _, held := h.undelivered(); !held {
+			t.Error("expected the reduce stage to hold the tick")
+		}
+
+		h.startStub(StubInfo{Index: 1})
+		if _, held := h.undelivered(); held {
+			t.Error("expected the reduce stage to be reset for the next stub")
+		}
+	})
+
+	t.Run("test pipeline session types", func(t *testing.T) {
+		session := &headers.Session{SessionInfo: headers.SessionInfo{Sessions: []headers.Sessions{
+			{SessionNum: 0, SessionType: "Practice"},
+			{SessionNum: 1, SessionType: "Race"},
+		}}}
+
+		proc := testProcessor{}
+		pipeline := Pipeline().SessionTypes("Race").To(&proc)
+
+		for _, sessionNum := range []int{0, 1, 1, 0} {
+			if err := pipeline.Process(Tick{"SessionNum": sessionNum}, true, session); err != nil {
+				t.Errorf("expected pipeline to process without err. received error: %v", err)
+			}
+		}
+
+		if len(proc.results) != 2 {
+			t.Errorf("expected %d race ticks to pass the filter. received %d", 2, len(proc.results))
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package ibt
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorPolicy determines how processing continues once a processor returns an error.
+type ErrorPolicy int
+
+const (
+	// FailFast stops processing and returns the first error that occurs. This is the default policy.
+	FailFast ErrorPolicy = iota
+	// SkipTick records the error and skips the remaining processors for the current tick.
+	SkipTick
+	// SkipStub records the error and skips the remaining ticks of the current stub.
+	SkipStub
+	// CollectAll records the error and continues processing as if no error occurred.
+	CollectAll
+)
+
+// String representation of the ErrorPolicy
+func (p ErrorPolicy) String() string {
+	switch p {
+	case FailFast:
+		return "fail-fast"
+	case SkipTick:
+		return "skip-tick"
+	case SkipStub:
+		return "skip-stub"
+	case CollectAll:
+		return "collect-all"
+	}
+
+	return fmt.Sprintf("ErrorPolicy(%d)", int(p))
+}
+
+// ParseErrorPolicy returns the ErrorPolicy matching the given string representation.
+//
+// An empty string results in the default FailFast policy.
+func ParseErrorPolicy(s string) (ErrorPolicy, error) {
+	if s == "" {
+		return FailFast, nil
+	}
+
+	for _, p := range []ErrorPolicy{FailFast, SkipTick, SkipStub, CollectAll} {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+
+	return FailFast, fmt.Errorf("unknown error policy %q", s)
+}
+
+// ProcessError is an error that occurred while processing a stub, along with where it occurred.
+type ProcessError struct {
+	// Filename of the stub being processed
+	Filename string
+	// Tick is the index of the tick within the stub. A value of -1 indicates that the error
+	// occurred outside of tick processing, such as in a lifecycle hook.
+	Tick int
+	// SessionTime of the tick being processed. This will be 0 when not available.
+	SessionTime float64
+	// Processor that returned the error
+	Processor Processor
+	// Err is the underlying error returned by the processor
+	Err error
+}
+
+// Error message including the location where the error occurred
+func (e *ProcessError) Error() string {
+	if e.Tick < 0 {
+		return fmt.Sprintf("processor %T failed for %s: %v", e.Processor, e.Filename, e.Err)
+	}
+
+	return fmt.Sprintf("processor %T failed at tick %d (session time %.3f) of %s: %v",
+		e.Processor, e.Tick, e.SessionTime, e.Filename, e.Err)
+}
+
+// Unwrap the underlying processor error
+func (e *ProcessError) Unwrap() error { return e.Err }
+
+// ProcessReport summarises the outcome of processing a StubGroup.
+type ProcessReport struct {
+	// Number of stubs that were processed, including those that were partially skipped
+	Stubs int
+	// Number of ticks that were processed
+	Ticks int
+	// Number of stubs that were not fully processed due to an error
+	SkippedStubs int
+	// Number of ticks that were not delivered to all processors due to an error
+	SkippedTicks int
+	// Errors that were recorded while processing
+	Errors []*ProcessError
+	// Number of checkpoints that were saved
+	Checkpoints int
+	// ResumedFrom is the checkpoint from which processing was resumed. Nil when processing started
+	// from the first stub.
+	ResumedFrom *Checkpoint
+	// Stats on the throughput of processing and the time spent by each processor
+	Stats ProcessStats
+}
+
+// Err joins all of the recorded errors. Nil will be returned if no errors were recorded.
+func (r *ProcessReport) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+
+	errs := make([]error, 0, len(r.Errors))
+	for _, err := range r.Errors {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,171 @@
+package ibt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// ProcessorFactory creates fresh processor instances for a single StubGroup.
+//
+// ProcessGroups calls the factory once for every group, ensuring that processors are never
+// shared between groups that are processed concurrently.
+type ProcessorFactory func(groupIdx int, group StubGroup) ([]Processor, error)
+
+// GroupResult is the outcome of processing a single StubGroup with ProcessGroups.
+type GroupResult struct {
+	// Index of the group in the slice provided to ProcessGroups
+	Index int
+	// Group that was processed
+	Group StubGroup
+	// Processors created by the factory for this group. Results can be retrieved from them
+	// once the group has finished processing.
+	Processors []Processor
+	// Report summarising the processing of the group
+	Report *ProcessReport
+	// Err is any error that occurred while creating the processors or processing the group
+	Err error
+}
+
+// GroupOptions configures the behaviour of ProcessGroups.
+type GroupOptions struct {
+	// Workers is the maximum number of groups processed concurrently.
+	//
+	// If Workers is equal to or less than 0, runtime.GOMAXPROCS(0) will be used.
+	Workers int
+	// OnResult is called once for every group after it has been processed.
+	//
+	// Callbacks are made in the order of the groups provided to ProcessGroups, regardless of
+	// the order in which the groups complete. OnResult is never called concurrently.
+	OnResult func(GroupResult)
+	// Options creates the options used when processing the group at the given index.
+	//
+	// A Checkpointer records the progress of a single StubGroup, so every group requires its own. OnProgress
+	// is never called concurrently, even when the same callback is used for every group.
+	Options func(groupIdx int) ProcessOptions
+}
+
+// ProcessGroups processes each of the given groups concurrently on a bounded worker pool.
+//
+// Each group receives its own processors from the factory and is processed with ProcessWithOptions. The
+// results are returned in the same order as the given groups. The returned error joins the errors
+// of all groups that failed.
+//
+// Groups that have not started processing when the context is cancelled will not be processed and
+// their result will contain the context error.
+func ProcessGroups(ctx context.Context, groups []StubGroup, factory ProcessorFactory, opts GroupOptions) ([]GroupResult, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(groups) {
+		workers = len(groups)
+	}
+
+	jobs := make(chan int)
+	done := make(chan GroupResult)
+
+	// Serialises the progress callbacks of groups processed concurrently
+	var progressMu sync.Mutex
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				done <- processGroup(ctx, idx, groups[idx], factory, groupOptions(idx, opts, &progressMu))
+			}
+		}()
+	}
+
+	go func() {
+		for idx := range groups {
+			jobs <- idx
+		}
+		close(jobs)
+		wg.Wait()
+		close(done)
+	}()
+
+	results := make([]GroupResult, len(groups))
+	pending := make(map[int]GroupResult)
+	next := 0
+
+	for result := range done {
+		results[result.Index] = result
+		pending[result.Index] = result
+
+		// Emit the callbacks in order of the groups, holding back results that completed early
+		for {
+			ready, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			if opts.OnResult != nil {
+				opts.OnResult(ready)
+			}
+			next++
+		}
+	}
+
+	errs := make([]error, 0)
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return results, errors.Join(errs...)
+	}
+
+	return results, nil
+}
+
+// groupOptions creates the ProcessOptions of the group at the given index, serialising OnProgress with the
+// given mutex
+func groupOptions(idx int, opts GroupOptions, progressMu *sync.Mutex) ProcessOptions {
+	var groupOpts ProcessOptions
+	if opts.Options != nil {
+		groupOpts = opts.Options(idx)
+	}
+
+	if onProgress := groupOpts.OnProgress; onProgress != nil {
+		groupOpts.OnProgress = func(p Progress) {
+			progressMu.Lock()
+			defer progressMu.Unlock()
+
+			onProgress(p)
+		}
+	}
+
+	return groupOpts
+}
+
+// processGroup creates the processors for a single group and processes it.
+func processGroup(ctx context.Context, idx int, group StubGroup, factory ProcessorFactory, opts ProcessOptions) GroupResult {
+	result := GroupResult{Index: idx, Group: group}
+
+	if err := ctx.Err(); err != nil {
+		result.Err = fmt.Errorf("group %d not processed: %w", idx, err)
+		return result
+	}
+
+	processors, err := factory(idx, group)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to create processors for group %d: %w", idx, err)
+		return result
+	}
+	result.Processors = processors
+
+	result.Report, err = ProcessWithOptions(ctx, group, opts, processors...)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to process group %d: %w", idx, err)
+	}
+
+	return result
+}
@@ -0,0 +1,135 @@
+package ibt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProcessGroups(t *testing.T) {
+	groups := make([]StubGroup, 0)
+	for i := 0; i < 4; i++ {
+		stubs, err := ParseStubs(".testing/valid_test_file.ibt")
+		if err != nil {
+			t.Errorf("failed to parse stubs for testing file - %v", err)
+			return
+		}
+		groups = append(groups, stubs)
+	}
+	defer CloseAllStubs(groups)
+
+	t.Run("test ProcessGroups processes every group", func(t *testing.T) {
+		factory := func(groupIdx int, group StubGroup) ([]Processor, error) {
+			return []Processor{&testProcessor{whitelist: []string{"LapCurrentLapTime"}}}, nil
+		}
+
+		order := make([]int, 0)
+		results, err := ProcessGroups(context.Background(), groups, factory, GroupOptions{
+			Workers:  2,
+			OnResult: func(result GroupResult) { order = append(order, result.Index) },
+		})
+		if err != nil {
+			t.Errorf("expected ProcessGroups() to run without err. received error: %v", err)
+		}
+
+		if len(results) != len(groups) {
+			t.Errorf("expected %d results. received %d", len(groups), len(results))
+		}
+
+		for idx, result := range results {
+			if result.Index != idx {
+				t.Errorf("expected result %d to have index %d. received %d", idx, idx, result.Index)
+			}
+
+			proc := result.Processors[0].(*testProcessor)
+			if len(proc.results) != 389 {
+				t.Errorf("expected group %d to have processed %d ticks. received %d", idx, 389, len(proc.results))
+			}
+		}
+
+		for idx, resultIdx := range order {
+			if idx != resultIdx {
+				t.Errorf("expected callbacks to be made in group order. received %v", order)
+				break
+			}
+		}
+	})
+
+	t.Run("test ProcessGroups options per group", func(t *testing.T) {
+		factory := func(groupIdx int, group StubGroup) ([]Processor, error) {
+			return []Processor{&testProcessor{whitelist: []string{"Speed"}}}, nil
+		}
+
+		dir := t.TempDir()
+		progress := 0
+
+		_, err := ProcessGroups(context.Background(), groups, factory, GroupOptions{
+			Workers: 4,
+			Options: func(groupIdx int) ProcessOptions {
+				return ProcessOptions{
+					Checkpointer: NewFileCheckpointer(filepath.Join(dir, fmt.Sprintf("group-%d.json", groupIdx))),
+					// The callback is shared by every group without synchronisation
+					OnProgress: func(p Progress) { progress++ },
+				}
+			},
+		})
+		if err != nil {
+			t.Errorf("expected ProcessGroups() to run without err. received error: %v", err)
+		}
+
+		for idx := range groups {
+			if _, err := os.Stat(filepath.Join(dir, fmt.Sprintf("group-%d.json", idx))); err != nil {
+				t.Errorf("expected a checkpoint for group %d. received error: %v", idx, err)
+			}
+		}
+		if progress < len(groups) {
+			t.Errorf("expected progress to be reported for every group. received %d calls", progress)
+		}
+	})
+
+	t.Run("test ProcessGroups factory error", func(t *testing.T) {
+		factoryErr := errors.New("factory failed")
+		factory := func(groupIdx int, group StubGroup) ([]Processor, error) {
+			if groupIdx == 1 {
+				return nil, factoryErr
+			}
+			return []Processor{&testProcessor{whitelist: []string{"Speed"}}}, nil
+		}
+
+		results, err := ProcessGroups(context.Background(), groups, factory, GroupOptions{})
+		if !errors.Is(err, factoryErr) {
+			t.Errorf("expected ProcessGroups() to return the factory error. received: %v", err)
+		}
+
+		if results[1].Err == nil {
+			t.Error("expected the result of group 1 to contain an error")
+		}
+
+		if results[0].Err != nil || results[2].Err != nil || results[3].Err != nil {
+			t.Errorf("expected the remaining groups to process without error. received %v", err)
+		}
+	})
+
+	t.Run("test ProcessGroups cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		factory := func(groupIdx int, group StubGroup) ([]Processor, error) {
+			return []Processor{&testProcessor{whitelist: []string{"Speed"}}}, nil
+		}
+
+		results, err := ProcessGroups(ctx, groups, factory, GroupOptions{Workers: 1})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected ProcessGroups() to return a context cancelled error. received: %v", err)
+		}
+
+		for _, result := range results {
+			if result.Err == nil {
+				t.Errorf("expected group %d to contain an error", result.Index)
+			}
+		}
+	})
+}
@@ -2,73 +2,385 @@ package ibt
 
 import (
 	"context"
-	"errors"
+	"slices"
 	"sort"
+	"time"
 
 	"github.com/teamjorge/ibt/headers"
 	"github.com/teamjorge/ibt/utilities"
 )
 
+const (
+	// Name of the telemetry variable containing the seconds since the start of the session
+	sessionTimeVar string = "SessionTime"
+)
+
 type Processor interface {
 	Process(input Tick, hasNext bool, session *headers.Session) error
 	Whitelist() []string
 }
 
+// StubInfo describes the stub whose telemetry is being processed.
+type StubInfo struct {
+	// Index of the stub within the StubGroup being processed
+	Index int
+	// Filename where the stub originated from
+	Filename string
+	// Header that was parsed when the stub was created
+	Header *headers.Header
+	// CarSetup used for the stub
+	CarSetup *CarSetup
+}
+
+// StubStarter is implemented by processors that need to be notified before the first tick of a stub is processed.
+//
+// This is useful for resetting any state that should only apply to a single ibt file.
+type StubStarter interface {
+	StartStub(stub StubInfo) error
+}
+
+// StubEnder is implemented by processors that need to be notified after the last tick of a stub was processed.
+type StubEnder interface {
+	EndStub(stub StubInfo) error
+}
+
+// Finisher is implemented by processors that need to be notified once all stubs have been processed.
+//
+// Finish is called once processing completes, including after errors that were recorded or stubs that were
+// skipped according to the ErrorPolicy. It is not called when processing stopped early, such as after an
+// error with the FailFast policy or a cancelled context.
+type Finisher interface {
+	Finish() error
+}
+
+// ProcessOptions configures the behaviour of ProcessWithOptions.
+type ProcessOptions struct {
+	// ErrorPolicy determines how processing continues when a processor returns an error.
+	ErrorPolicy ErrorPolicy
+	// OnProgress is called periodically with the progress of processing, as well as after each stub.
+	OnProgress func(Progress)
+	// ProgressInterval is the minimum time between calls to OnProgress. Defaults to 1 second.
+	ProgressInterval time.Duration
+	// Checkpointer records the progress of processing. When provided, processing resumes after the
+	// last saved checkpoint and processors implementing Flusher are flushed before every checkpoint.
+	// Once a stub is skipped with the SkipStub policy, no further checkpoints are saved so that the
+	// skipped stub is processed again when resuming.
+	Checkpointer Checkpointer
+	// CheckpointInterval is the number of ticks between checkpoints. A checkpoint is also saved after
+	// each stub. Defaults to 10000.
+	CheckpointInterval int
+}
+
+// Process the telemetry of each stub in the given group with the provided processors.
+//
+// Stubs are processed in chronological order. Processors implementing StubStarter, StubEnder
+// or Finisher will be notified at the start and end of each stub and once all stubs have
+// been processed. Processors implementing ContextProcessor will receive the TickContext of each tick
+// and processors implementing WindowProcessor will receive the ticks preceding each tick. Ticks are
+// decimated for processors implementing RateProcessor.
+//
+// Processing stops at the first error. Use ProcessWithOptions to continue processing when errors occur.
 func Process(ctx context.Context, stubs StubGroup, processors ...Processor) error {
+	_, err := ProcessWithOptions(ctx, stubs, ProcessOptions{}, processors...)
+
+	return err
+}
+
+// ProcessWithOptions processes the telemetry of each stub in the given group with the provided
+// processors and options.
+//
+// The returned report summarises the stubs and ticks that were processed, along with any errors that
+// were recorded according to the ErrorPolicy. An error is only returned when processing could not
+// continue, such as a cancelled context or an error with the FailFast policy.
+func ProcessWithOptions(ctx context.Context, stubs StubGroup, opts ProcessOptions, processors ...Processor) (*ProcessReport, error) {
+	r := newRunner(opts, processors...)
+
+	err := r.run(ctx, stubs)
+	r.report.Stats = r.progress.stats()
+
+	return r.report, err
+}
+
+// runner processes stubs for a single call to ProcessWithOptions.
+type runner struct {
+	opts       ProcessOptions
+	processors []Processor
+	handles    []*handle
+	report     *ProcessReport
+	progress   *progressTracker
+
+	// Number of ticks processed since the last checkpoint
+	sinceCheckpoint int
+	// skipped indicates that a stub was skipped, after which no checkpoints are saved
+	skipped bool
+}
+
+func newRunner(opts ProcessOptions, processors ...Processor) *runner {
+	if opts.CheckpointInterval <= 0 {
+		opts.CheckpointInterval = defaultCheckpointInterval
+	}
+
+	return &runner{
+		opts:       opts,
+		processors: processors,
+		handles:    newHandles(processors...),
+		report:     new(ProcessReport),
+		progress:   newProgressTracker(opts, processors...),
+	}
+}
+
+func (r *runner) run(ctx context.Context, stubs StubGroup) error {
 	sort.Sort(stubs)
 
-	for _, stub := range stubs {
-		if err := process(ctx, stub, processors...); err != nil {
+	from, err := r.resume(stubs)
+	if err != nil {
+		return err
+	}
+
+	r.progress.expect(stubs[from.stub:], from.tick)
+
+	for idx := from.stub; idx < len(stubs); idx++ {
+		startTick := 0
+		if idx == from.stub {
+			startTick = from.tick
+		}
+
+		if err := r.process(ctx, idx, stubs[idx], startTick); err != nil {
 			return err
 		}
 	}
 
+	for _, proc := range r.processors {
+		if finisher, ok := proc.(Finisher); ok {
+			if err := finisher.Finish(); err != nil {
+				if err := r.record(&ProcessError{Tick: -1, Processor: proc, Err: err}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// record the given error in the report.
+//
+// The error is returned when processing should stop according to the ErrorPolicy.
+func (r *runner) record(err *ProcessError) error {
+	if r.opts.ErrorPolicy == FailFast {
+		return err
+	}
+
+	r.report.Errors = append(r.report.Errors, err)
+
 	return nil
 }
 
-func process(ctx context.Context, stub Stub, processors ...Processor) error {
+// process the ticks of a single stub, starting at the given tick index
+func (r *runner) process(ctx context.Context, idx int, stub Stub, from int) error {
 	header := stub.header
+	processors := r.processors
 
 	// Only parse fields that are actually needed by all processors combined
 	whitelist := buildWhitelist(header.VarHeader, processors...)
 
+	// Resolve the whitelist of each processor once, rather than for every tick
+	procWhitelists := make([][]string, len(processors))
+	for i, proc := range processors {
+		procWhitelists[i] = parseAndValidateWhitelist(header.VarHeader, proc)
+	}
+
+	info := newStubInfo(idx, stub, processors...)
+
+	r.report.Stubs++
+	r.progress.startStub(info, from)
+
+	for _, h := range r.handles {
+		h.startStub(info)
+	}
+
+	for _, proc := range processors {
+		if starter, ok := proc.(StubStarter); ok {
+			if err := starter.StartStub(info); err != nil {
+				if err := r.record(&ProcessError{Filename: info.Filename, Tick: -1, Processor: proc, Err: err}); err != nil {
+					return err
+				}
+				if r.opts.ErrorPolicy == SkipStub {
+					return r.skipStub(info)
+				}
+			}
+		}
+	}
+
+	// SessionTime is always parsed to provide the location of ticks and errors
+	parseWhitelist := whitelist
+	internalSessionTime := false
+	if _, ok := header.VarHeader[sessionTimeVar]; ok && !slices.Contains(whitelist, sessionTimeVar) {
+		parseWhitelist = append(parseWhitelist, sessionTimeVar)
+		internalSessionTime = true
+	}
+
 	// Use optimized parser with all our performance improvements
-	parser := NewParser(stub.r, header, whitelist...)
-	for {
+	parser := NewParser(stub.r, header, parseWhitelist...)
+	// The parser position is offset by one from the tick index
+	parser.Seek(from + 1)
+
+	for tickIdx := from; ; tickIdx++ {
 		select {
 		case <-ctx.Done():
-			return errors.New("context cancelled")
+			return ctx.Err()
 		default:
 		}
 
 		tick, hasNext := parser.Next()
-		
+		if tick == nil {
+			break
+		}
+
+		tickCtx := newTickContext(info, tickIdx, r.report.Ticks, tick)
+		// Ensure processors only receive the variables that were whitelisted
+		if internalSessionTime {
+			delete(tick, sessionTimeVar)
+		}
+
+		r.report.Ticks++
+		r.progress.tick(header.TelemetryHeader.BufLen)
+
+		skipStub := false
+
 		// Process all processors with the same tick - avoid redundant filtering
-		for _, proc := range processors {
-			procWhitelist := proc.Whitelist()
-			
-			// If processor needs all fields, use original tick
-			if len(procWhitelist) >= len(whitelist) {
-				if err := proc.Process(tick, hasNext, header.SessionInfo); err != nil {
-					return err
+		for i, proc := range processors {
+			procWhitelist := procWhitelists[i]
+
+			input := tick
+			// Filter tick for this specific processor if it does not require all fields
+			if len(procWhitelist) < len(whitelist) {
+				input = tick.Filter(procWhitelist...)
+			}
+
+			start := time.Now()
+			err := r.handles[i].deliver(input, tickIdx, tickCtx, hasNext, header.SessionInfo)
+			r.progress.timeProcessor(i, start)
+
+			if err != nil {
+				processErr := &ProcessError{
+					Filename:    info.Filename,
+					Tick:        tickIdx,
+					SessionTime: tickCtx.SessionTime,
+					Processor:   proc,
+					Err:         err,
 				}
-			} else {
-				// Filter tick for this specific processor
-				filteredTick := tick.Filter(procWhitelist...)
-				if err := proc.Process(filteredTick, hasNext, header.SessionInfo); err != nil {
+				if err := r.record(processErr); err != nil {
 					return err
 				}
+
+				if r.opts.ErrorPolicy == SkipTick {
+					r.report.SkippedTicks++
+					break
+				}
+				if r.opts.ErrorPolicy == SkipStub {
+					skipStub = true
+					break
+				}
 			}
 		}
 
+		if skipStub {
+			return r.skipStub(info)
+		}
+
 		if !hasNext {
 			break
 		}
+
+		r.sinceCheckpoint++
+		if r.opts.Checkpointer != nil && r.sinceCheckpoint >= r.opts.CheckpointInterval {
+			checkpoint := Checkpoint{Filename: info.Filename, StartDate: header.DiskHeader.StartDate, Tick: tickIdx}
+			// Ticks still held by processors are not included, ensuring they are processed again when resuming
+			if from, ok := r.undelivered(); ok {
+				checkpoint.Tick = from - 1
+			}
+			if err := r.checkpoint(checkpoint); err != nil {
+				return err
+			}
+		}
+	}
+
+	return r.completeStub(info, stub)
+}
+
+// undelivered is the index of the first tick of the current stub held by any processor
+func (r *runner) undelivered() (int, bool) {
+	from, ok := 0, false
+
+	for _, h := range r.handles {
+		if handleFrom, held := h.undelivered(); held && (!ok || handleFrom < from) {
+			from, ok = handleFrom, true
+		}
+	}
+
+	return from, ok
+}
+
+// completeStub ends the given stub and saves a checkpoint indicating that it was processed.
+func (r *runner) completeStub(info StubInfo, stub Stub) error {
+	if err := r.endStub(info); err != nil {
+		return err
+	}
+
+	if r.opts.Checkpointer == nil {
+		return nil
+	}
+
+	return r.checkpoint(Checkpoint{Filename: info.Filename, StartDate: stub.header.DiskHeader.StartDate, Complete: true})
+}
+
+// skipStub ends the given stub after it was skipped due to an error.
+//
+// No further checkpoints are saved once a stub is skipped, so processing resumes no later than the start of
+// the skipped stub and it is processed again.
+func (r *runner) skipStub(info StubInfo) error {
+	r.report.SkippedStubs++
+	r.skipped = true
+
+	return r.endStub(info)
+}
+
+// endStub notifies all StubEnder processors that the given stub has been processed.
+func (r *runner) endStub(info StubInfo) error {
+	r.progress.endStub()
+
+	for _, proc := range r.processors {
+		if ender, ok := proc.(StubEnder); ok {
+			if err := ender.EndStub(info); err != nil {
+				if err := r.record(&ProcessError{Filename: info.Filename, Tick: -1, Processor: proc, Err: err}); err != nil {
+					return err
+				}
+			}
+		}
 	}
 
 	return nil
 }
 
+// newStubInfo creates the StubInfo for the given stub.
+//
+// The car setup is only parsed when one of the processors implements StubStarter or StubEnder.
+func newStubInfo(idx int, stub Stub, processors ...Processor) StubInfo {
+	info := StubInfo{Index: idx, Filename: stub.Filename(), Header: stub.header}
+
+	for _, proc := range processors {
+		_, isStarter := proc.(StubStarter)
+		_, isEnder := proc.(StubEnder)
+		if isStarter || isEnder {
+			info.CarSetup = stub.CarSetup()
+			break
+		}
+	}
+
+	return info
+}
+
 // getcinoketeWhitelist compiles the whitelists from all processors and removes overlap
 func buildWhitelist(vars map[string]headers.VarHeader, processors ...Processor) []string {
 	whitelist := make([]string, 0)
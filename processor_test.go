@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"os"
+	"reflect"
 	"sort"
 	"testing"
 
@@ -33,6 +34,61 @@ func (t *testErrorProcessor) Process(input Tick, hasNext bool, session *headers.
 
 func (t *testErrorProcessor) Whitelist() []string { return []string{"LapCurrentLapTime"} }
 
+type testHookProcessor struct {
+	testProcessor
+	events []string
+	stubs  []StubInfo
+}
+
+func (t *testHookProcessor) Process(input Tick, hasNext bool, session *headers.Session) error {
+	if len(t.results) == 0 {
+		t.events = append(t.events, "tick")
+	}
+
+	return t.testProcessor.Process(input, hasNext, session)
+}
+
+func (t *testHookProcessor) StartStub(stub StubInfo) error {
+	t.events = append(t.events, "start")
+	t.stubs = append(t.stubs, stub)
+	t.results = nil
+
+	return nil
+}
+
+func (t *testHookProcessor) EndStub(stub StubInfo) error {
+	t.events = append(t.events, "end")
+
+	return nil
+}
+
+func (t *testHookProcessor) Finish() error {
+	t.events = append(t.events, "finish")
+
+	return nil
+}
+
+type testFailingHookProcessor struct{ testProcessor }
+
+func (t *testFailingHookProcessor) StartStub(stub StubInfo) error {
+	return errors.New("unit test error")
+}
+
+type testFlakyProcessor struct {
+	testProcessor
+	every int
+	count int
+}
+
+func (t *testFlakyProcessor) Process(input Tick, hasNext bool, session *headers.Session) error {
+	t.count++
+	if t.count%t.every == 0 {
+		return errors.New("unit test error")
+	}
+
+	return t.testProcessor.Process(input, hasNext, session)
+}
+
 func TestProcess(t *testing.T) {
 	f, err := os.Open(".testing/valid_test_file.ibt")
 	if err != nil {
@@ -83,14 +139,175 @@ func TestProcess(t *testing.T) {
 		}
 	})
 
+	t.Run("test Process() lifecycle hooks", func(t *testing.T) {
+		proc := testHookProcessor{testProcessor: testProcessor{whitelist: []string{"Speed"}}}
+
+		if err := Process(context.Background(), append(stubs, stubs[0]), &proc); err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		expectedEvents := []string{"start", "tick", "end", "start", "tick", "end", "finish"}
+		if !reflect.DeepEqual(proc.events, expectedEvents) {
+			t.Errorf("expected hook events to be %v. received %v", expectedEvents, proc.events)
+		}
+
+		if len(proc.stubs) != 2 || proc.stubs[0].Index != 0 || proc.stubs[1].Index != 1 {
+			t.Errorf("expected stub info for 2 stubs with sequential indexes. received %+v", proc.stubs)
+		}
+
+		if proc.stubs[0].Filename != ".testing/valid_test_file.ibt" {
+			t.Errorf("expected stub info filename to be %s. received %s", ".testing/valid_test_file.ibt", proc.stubs[0].Filename)
+		}
+
+		if proc.stubs[0].Header != testHeaders {
+			t.Error("expected stub info to contain the header of the stub")
+		}
+
+		if proc.stubs[0].CarSetup == nil || proc.stubs[0].CarSetup.Name != "ARA_23S1_W13_RBR_R_2.sto" {
+			t.Errorf("expected stub info to contain the parsed car setup. received %+v", proc.stubs[0].CarSetup)
+		}
+	})
+
+	t.Run("test Process() failing start hook", func(t *testing.T) {
+		proc := testFailingHookProcessor{testProcessor{whitelist: []string{"Speed"}}}
+
+		if err := Process(context.Background(), stubs, &proc); err == nil {
+			t.Error("expected Process() to return the StartStub error")
+		}
+
+		if len(proc.results) != 0 {
+			t.Errorf("expected no ticks to be processed after a failed StartStub. received %d", len(proc.results))
+		}
+	})
+
 	t.Run("test process() invalid file", func(t *testing.T) {
 		proc := testProcessor{whitelist: []string{"LapCurrentLapTime"}}
 
 		ctx, cancel := context.WithCancel(context.Background())
 		cancel()
 
-		if err := process(ctx, stubs[0], &proc); err == nil {
-			t.Errorf("expected process() to exit with a context done error")
+		if err := newRunner(ProcessOptions{}, &proc).process(ctx, 0, stubs[0], 0); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected process() to exit with a context done error. received: %v", err)
+		}
+	})
+}
+
+func TestProcessWithOptions(t *testing.T) {
+	f, err := os.Open(".testing/valid_test_file.ibt")
+	if err != nil {
+		t.Errorf("failed to open testing file - %v", err)
+		return
+	}
+	defer f.Close()
+
+	testHeaders, err := headers.ParseHeaders(f)
+	if err != nil {
+		t.Errorf("failed to parse header for testing file - %v", err)
+		return
+	}
+
+	stubs := StubGroup{
+		{filepath: ".testing/valid_test_file.ibt", header: testHeaders, r: f},
+		{filepath: ".testing/valid_test_file.ibt", header: testHeaders, r: f},
+	}
+
+	t.Run("test ProcessWithOptions() fail-fast", func(t *testing.T) {
+		flaky := testFlakyProcessor{testProcessor: testProcessor{whitelist: []string{"Speed"}}, every: 100}
+
+		report, err := ProcessWithOptions(context.Background(), stubs, ProcessOptions{}, &flaky)
+
+		var processErr *ProcessError
+		if !errors.As(err, &processErr) {
+			t.Errorf("expected ProcessWithOptions() to return a ProcessError. received: %v", err)
+			return
+		}
+
+		if processErr.Tick != 99 || processErr.Filename != ".testing/valid_test_file.ibt" || processErr.Processor != &flaky {
+			t.Errorf("expected error to occur at tick %d of %s. received %+v", 99, ".testing/valid_test_file.ibt", processErr)
+		}
+
+		if processErr.SessionTime < 932 || processErr.SessionTime > 939 {
+			t.Errorf("expected error to contain the session time of the tick. received %f", processErr.SessionTime)
+		}
+
+		if len(report.Errors) != 0 {
+			t.Errorf("expected no errors to be collected with fail-fast. received %d", len(report.Errors))
+		}
+	})
+
+	t.Run("test ProcessWithOptions() skip-tick", func(t *testing.T) {
+		flaky := testFlakyProcessor{testProcessor: testProcessor{whitelist: []string{"Speed"}}, every: 100}
+		proc := testProcessor{whitelist: []string{"Speed"}}
+
+		report, err := ProcessWithOptions(context.Background(), stubs, ProcessOptions{ErrorPolicy: SkipTick}, &flaky, &proc)
+		if err != nil {
+			t.Errorf("expected ProcessWithOptions() to run without err. received error: %v", err)
+		}
+
+		// 389 ticks in each of the 2 stubs with an error every 100 ticks
+		if len(report.Errors) != 7 || report.SkippedTicks != 7 {
+			t.Errorf("expected %d errors and skipped ticks. received %d errors and %d skipped ticks", 7, len(report.Errors), report.SkippedTicks)
+		}
+
+		if len(proc.results) != 778-7 {
+			t.Errorf("expected the second processor to skip the failed ticks. received %d ticks", len(proc.results))
+		}
+
+		if report.Ticks != 778 || report.Stubs != 2 {
+			t.Errorf("expected report to contain %d ticks and %d stubs. received %+v", 778, 2, report)
+		}
+
+		if report.Err() == nil {
+			t.Error("expected report to return the joined errors")
+		}
+	})
+
+	t.Run("test ProcessWithOptions() skip-stub", func(t *testing.T) {
+		flaky := testFlakyProcessor{testProcessor: testProcessor{whitelist: []string{"Speed"}}, every: 100}
+
+		report, err := ProcessWithOptions(context.Background(), stubs, ProcessOptions{ErrorPolicy: SkipStub}, &flaky)
+		if err != nil {
+			t.Errorf("expected ProcessWithOptions() to run without err. received error: %v", err)
+		}
+
+		if len(report.Errors) != 2 || report.SkippedStubs != 2 {
+			t.Errorf("expected %d errors and skipped stubs. received %d errors and %d skipped stubs", 2, len(report.Errors), report.SkippedStubs)
+		}
+
+		if report.Errors[1].Tick != 99 {
+			t.Errorf("expected the second stub to fail on tick %d. received %d", 99, report.Errors[1].Tick)
+		}
+	})
+
+	t.Run("test ProcessWithOptions() finish after errors", func(t *testing.T) {
+		for _, policy := range []ErrorPolicy{FailFast, SkipTick, SkipStub, CollectAll} {
+			flaky := testFlakyProcessor{testProcessor: testProcessor{whitelist: []string{"Speed"}}, every: 100}
+			hooks := testHookProcessor{testProcessor: testProcessor{whitelist: []string{"Speed"}}}
+
+			ProcessWithOptions(context.Background(), stubs, ProcessOptions{ErrorPolicy: policy}, &flaky, &hooks)
+
+			finished := len(hooks.events) > 0 && hooks.events[len(hooks.events)-1] == "finish"
+			if finished != (policy != FailFast) {
+				t.Errorf("expected Finish to be called with the %v policy only when processing completed. received events %v", policy, hooks.events)
+			}
+		}
+	})
+
+	t.Run("test ProcessWithOptions() collect-all", func(t *testing.T) {
+		flaky := testFlakyProcessor{testProcessor: testProcessor{whitelist: []string{"Speed"}}, every: 100}
+		proc := testProcessor{whitelist: []string{"Speed"}}
+
+		report, err := ProcessWithOptions(context.Background(), stubs, ProcessOptions{ErrorPolicy: CollectAll}, &flaky, &proc)
+		if err != nil {
+			t.Errorf("expected ProcessWithOptions() to run without err. received error: %v", err)
+		}
+
+		if len(report.Errors) != 7 || report.SkippedTicks != 0 {
+			t.Errorf("expected %d errors and no skipped ticks. received %d errors and %d skipped ticks", 7, len(report.Errors), report.SkippedTicks)
+		}
+
+		if len(proc.results) != 778 {
+			t.Errorf("expected the second processor to receive every tick. received %d ticks", len(proc.results))
 		}
 	})
 }
@@ -0,0 +1,179 @@
+package ibt
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// Default interval between progress reports
+	defaultProgressInterval time.Duration = time.Second
+)
+
+// Progress of a call to ProcessWithOptions.
+type Progress struct {
+	// Index of the stub currently being processed
+	StubIndex int
+	// Number of stubs being processed
+	StubCount int
+	// Filename of the stub currently being processed
+	Filename string
+	// Number of ticks processed for the current stub, including any processed before resuming from a checkpoint
+	Ticks int
+	// Total number of ticks of the current stub, as indicated by DiskHeader.RecordCount
+	TotalTicks int
+	// Number of telemetry bytes read across all stubs
+	BytesRead int64
+	// Number of ticks processed per second across all stubs
+	TicksPerSecond float64
+	// Time elapsed since processing started
+	Elapsed time.Duration
+	// Estimated time remaining to process all stubs
+	ETA time.Duration
+}
+
+// ProcessStats summarises the throughput of a call to ProcessWithOptions.
+type ProcessStats struct {
+	// Total time spent processing
+	Duration time.Duration
+	// Number of telemetry bytes read
+	BytesRead int64
+	// Number of ticks processed per second
+	TicksPerSecond float64
+	// Time spent by each processor, in the order they were provided
+	Processors []ProcessorStats
+}
+
+// ProcessorStats is the time spent by a single processor.
+type ProcessorStats struct {
+	Processor Processor
+	// Name is the type name of the processor
+	Name string
+	// Number of ticks the processor received
+	Ticks int
+	// Total time spent in the processor
+	Time time.Duration
+}
+
+// progressTracker measures throughput and reports progress at a limited rate.
+type progressTracker struct {
+	onProgress func(Progress)
+	interval   time.Duration
+
+	start      time.Time
+	lastReport time.Time
+	totalTicks int
+	ticks      int
+	bytesRead  int64
+	processors []ProcessorStats
+	current    Progress
+}
+
+func newProgressTracker(opts ProcessOptions, processors ...Processor) *progressTracker {
+	p := &progressTracker{onProgress: opts.OnProgress, interval: opts.ProgressInterval}
+	if p.interval <= 0 {
+		p.interval = defaultProgressInterval
+	}
+
+	p.processors = make([]ProcessorStats, len(processors))
+	for i, proc := range processors {
+		p.processors[i] = ProcessorStats{Processor: proc, Name: fmt.Sprintf("%T", proc)}
+	}
+
+	p.start = time.Now()
+	p.lastReport = p.start
+
+	return p
+}
+
+// expect the given stubs to be processed, starting at the given tick of the first stub when resuming. This
+// is used for estimating the time remaining.
+func (p *progressTracker) expect(stubs StubGroup, fromTick int) {
+	for _, stub := range stubs {
+		p.totalTicks += stub.header.DiskHeader.RecordCount
+	}
+	if len(stubs) > 0 {
+		p.totalTicks -= fromTick
+	}
+
+	p.current.StubCount += len(stubs)
+}
+
+// startStub resets the progress of the current stub, of which the ticks before the given tick were already
+// processed before resuming
+func (p *progressTracker) startStub(info StubInfo, fromTick int) {
+	p.current.StubIndex = info.Index
+	p.current.Filename = info.Filename
+	p.current.Ticks = fromTick
+	p.current.TotalTicks = info.Header.DiskHeader.RecordCount
+}
+
+// tick records a single tick of the given size and reports progress if the interval has elapsed
+func (p *progressTracker) tick(size int) {
+	p.ticks++
+	p.bytesRead += int64(size)
+	p.current.Ticks++
+
+	if p.onProgress == nil {
+		return
+	}
+
+	if now := time.Now(); now.Sub(p.lastReport) >= p.interval {
+		p.lastReport = now
+		p.report(now)
+	}
+}
+
+// timeProcessor records the time spent by the processor at the given index
+func (p *progressTracker) timeProcessor(idx int, since time.Time) {
+	p.processors[idx].Ticks++
+	p.processors[idx].Time += time.Since(since)
+}
+
+// endStub reports the progress once the stub has been processed
+func (p *progressTracker) endStub() {
+	// Discount any ticks of the stub that were expected, but not processed
+	if unprocessed := p.current.TotalTicks - p.current.Ticks; unprocessed > 0 {
+		p.totalTicks -= unprocessed
+	}
+
+	if p.onProgress == nil {
+		return
+	}
+
+	p.lastReport = time.Now()
+	p.report(p.lastReport)
+}
+
+func (p *progressTracker) report(now time.Time) {
+	progress := p.current
+	progress.BytesRead = p.bytesRead
+	progress.Elapsed = now.Sub(p.start)
+	progress.TicksPerSecond = ticksPerSecond(p.ticks, progress.Elapsed)
+
+	if remaining := p.totalTicks - p.ticks; remaining > 0 && progress.TicksPerSecond > 0 {
+		progress.ETA = time.Duration(float64(remaining) / progress.TicksPerSecond * float64(time.Second))
+	}
+
+	p.onProgress(progress)
+}
+
+// stats summarising the processing up to now
+func (p *progressTracker) stats() ProcessStats {
+	duration := time.Since(p.start)
+
+	return ProcessStats{
+		Duration:       duration,
+		BytesRead:      p.bytesRead,
+		TicksPerSecond: ticksPerSecond(p.ticks, duration),
+		Processors:     p.processors,
+	}
+}
+
+func ticksPerSecond(ticks int, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+
+	return float64(ticks) / elapsed.Seconds()
+}
@@ -0,0 +1,126 @@
+package ibt
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/teamjorge/ibt/headers"
+)
+
+func TestProcessProgress(t *testing.T) {
+	f, err := os.Open(".testing/valid_test_file.ibt")
+	if err != nil {
+		t.Errorf("failed to open testing file - %v", err)
+		return
+	}
+	defer f.Close()
+
+	testHeaders, err := headers.ParseHeaders(f)
+	if err != nil {
+		t.Errorf("failed to parse header for testing file - %v", err)
+		return
+	}
+
+	stubs := StubGroup{
+		{filepath: ".testing/valid_test_file.ibt", header: testHeaders, r: f},
+		{filepath: ".testing/valid_test_file.ibt", header: testHeaders, r: f},
+	}
+
+	t.Run("test ProcessWithOptions() progress reporting", func(t *testing.T) {
+		proc := testProcessor{whitelist: []string{"Speed"}}
+
+		reports := make([]Progress, 0)
+		opts := ProcessOptions{
+			OnProgress:       func(p Progress) { reports = append(reports, p) },
+			ProgressInterval: time.Nanosecond,
+		}
+
+		report, err := ProcessWithOptions(context.Background(), stubs, opts, &proc)
+		if err != nil {
+			t.Errorf("expected ProcessWithOptions() to run without err. received error: %v", err)
+		}
+
+		if len(reports) < 2 {
+			t.Errorf("expected progress to be reported at least once per stub. received %d reports", len(reports))
+			return
+		}
+
+		last := reports[len(reports)-1]
+		if last.StubIndex != 1 || last.StubCount != 2 || last.Ticks != 389 || last.TotalTicks != 390 {
+			t.Errorf("expected final progress to be for the last tick of the second stub. received %+v", last)
+		}
+
+		if last.BytesRead != int64(2*389*testHeaders.TelemetryHeader.BufLen) {
+			t.Errorf("expected %d bytes to be read. received %d", 2*389*testHeaders.TelemetryHeader.BufLen, last.BytesRead)
+		}
+
+		if last.ETA != 0 {
+			t.Errorf("expected no remaining time once all ticks were processed. received %v", last.ETA)
+		}
+
+		if report.Stats.BytesRead != last.BytesRead || report.Stats.Duration <= 0 || report.Stats.TicksPerSecond <= 0 {
+			t.Errorf("expected stats to summarise processing. received %+v", report.Stats)
+		}
+
+		if len(report.Stats.Processors) != 1 {
+			t.Errorf("expected stats for %d processor. received %d", 1, len(report.Stats.Processors))
+			return
+		}
+
+		procStats := report.Stats.Processors[0]
+		if procStats.Name != "*ibt.testProcessor" || procStats.Ticks != 778 || procStats.Time <= 0 {
+			t.Errorf("expected processor stats for every tick of *ibt.testProcessor. received %+v", procStats)
+		}
+	})
+
+	t.Run("test ProcessWithOptions() progress when resuming", func(t *testing.T) {
+		proc := testProcessor{whitelist: []string{"Speed"}}
+		checkpointer := testCheckpointer{
+			checkpoint: Checkpoint{Filename: ".testing/valid_test_file.ibt", StartDate: testHeaders.DiskHeader.StartDate, Tick: 199},
+			ok:         true,
+		}
+
+		reports := make([]Progress, 0)
+		opts := ProcessOptions{
+			OnProgress:       func(p Progress) { reports = append(reports, p) },
+			ProgressInterval: time.Nanosecond,
+			Checkpointer:     &checkpointer,
+		}
+
+		if _, err := ProcessWithOptions(context.Background(), stubs, opts, &proc); err != nil {
+			t.Errorf("expected ProcessWithOptions() to run without err. received error: %v", err)
+		}
+
+		if len(reports) == 0 || reports[0].Ticks != 201 || reports[0].TotalTicks != 390 {
+			t.Errorf("expected the first report to include the ticks processed before resuming. received %+v", reports)
+			return
+		}
+
+		// The ETA is estimated from the remaining ticks of both stubs, excluding those before the checkpoint
+		first := reports[0]
+		remaining := 2*390 - 200 - 1
+		if ratio := first.ETA.Seconds() / first.Elapsed.Seconds(); ratio < float64(remaining)-1 || ratio > float64(remaining)+1 {
+			t.Errorf("expected the ETA to account for %d remaining ticks. received %v after %v", remaining, first.ETA, first.Elapsed)
+		}
+	})
+
+	t.Run("test ProcessWithOptions() progress rate limit", func(t *testing.T) {
+		proc := testProcessor{whitelist: []string{"Speed"}}
+
+		reports := 0
+		opts := ProcessOptions{
+			OnProgress:       func(p Progress) { reports++ },
+			ProgressInterval: time.Hour,
+		}
+
+		if _, err := ProcessWithOptions(context.Background(), stubs, opts, &proc); err != nil {
+			t.Errorf("expected ProcessWithOptions() to run without err. received error: %v", err)
+		}
+
+		if reports != 2 {
+			t.Errorf("expected progress to only be reported after each stub. received %d reports", reports)
+		}
+	})
+}
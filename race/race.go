@@ -0,0 +1,436 @@
+// Package race rebuilds the history of a race for every car in the field.
+//
+// The CarIdx variables of an ibt file describe every car in the session, not only the car of the driver.
+// A Tracker records the position of each car whenever it completes a lap from CarIdxLap and
+// CarIdxLapDistPct, with the gaps to the leader and to the car ahead from CarIdxF2Time, and the changes of
+// the lead from CarIdxPosition. Cars are joined with the drivers of the session for their names and
+// numbers. The Result contains a position chart of every lap of the race.
+package race
+
+import (
+	"sort"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/laps"
+)
+
+const (
+	// SessionType of race sub-sessions
+	raceSession string = "Race"
+)
+
+func init() {
+	ibt.Register("race", func(params ibt.Params) (ibt.Processor, error) {
+		var opts Options
+		if err := params.Decode(&opts); err != nil {
+			return nil, err
+		}
+
+		return NewTracker(opts), nil
+	})
+}
+
+// Options configures the tracking of a race.
+type Options struct {
+	// AllSessions includes the ticks of sub-sessions other than races, such as practice and qualifying.
+	// Only races are tracked by default when the session info is available.
+	AllSessions bool `yaml:"all_sessions"`
+}
+
+// Lap is the position of a car when it completed a lap.
+type Lap struct {
+	// Number of the lap completed
+	Number int
+	// SessionTime at which the car crossed the line
+	SessionTime float64
+	// Position overall and within the class of the car
+	Position      int
+	ClassPosition int
+	// GapLeader and GapAhead are the gaps in seconds to the leader and to the car one position ahead
+	GapLeader float64
+	GapAhead  float64
+	// LapsDown is the number of laps completed by the leader more than the car
+	LapsDown int
+}
+
+// Car is the race of a single car.
+type Car struct {
+	CarIdx int
+	// Name of the driver, Team and Number of the car, as reported by the session
+	Name    string
+	Team    string
+	Number  string
+	CarName string
+	ClassID int
+	// Player indicates that this is the car of the driver of the ibt file
+	Player bool
+	// StartPosition is the first position reported for the car
+	StartPosition int
+	// Position and ClassPosition are the latest positions reported for the car
+	Position      int
+	ClassPosition int
+	// LapsCompleted is the number of the last lap completed
+	LapsCompleted int
+	// LapsLed is the number of laps completed in the lead
+	LapsLed int
+	// Laps completed in the order they were driven
+	Laps []Lap
+}
+
+// LeadChange is a change of the car in the lead.
+type LeadChange struct {
+	SessionTime float64
+	// Lap being driven by the new leader
+	Lap int
+	// From and To are the CarIdx of the previous and new leader
+	From int
+	To   int
+}
+
+// ChartLap is the order of the field after a single lap.
+type ChartLap struct {
+	Lap int
+	// Order of the CarIdx of the cars that completed the lap, from first to last
+	Order []int
+}
+
+// Result of the race tracked by a Tracker.
+type Result struct {
+	// Cars ordered by their latest position, followed by the cars without a position
+	Cars []Car
+	// Chart of the positions of every lap completed, ordered by lap
+	Chart []ChartLap
+	// LeadChanges in the order they occurred
+	LeadChanges []LeadChange
+}
+
+// Car returns the car with the given CarIdx
+func (r Result) Car(carIdx int) (Car, bool) {
+	for _, car := range r.Cars {
+		if car.CarIdx == carIdx {
+			return car, true
+		}
+	}
+
+	return Car{}, false
+}
+
+// Tracker is a processor that tracks the positions and gaps of every car in the race.
+type Tracker struct {
+	opts        Options
+	cars        map[int]*Car
+	leadChanges []LeadChange
+	leader      int
+	// firstCrossing and lastCrossing are the earliest and latest times at which a lap was completed
+	firstCrossing map[int]float64
+	lastCrossing  map[int]float64
+	// joined is the session whose drivers were joined with the cars
+	joined *headers.Session
+
+	prev    raceTick
+	hasPrev bool
+}
+
+// raceTick is the part of a tick used for tracking a race
+type raceTick struct {
+	sessionTime    float64
+	positions      []int
+	classPositions []int
+	laps           []int
+	pcts           []float32
+	gaps           []float32
+}
+
+// NewTracker creates a race Tracker with the given options.
+func NewTracker(opts Options) *Tracker {
+	return &Tracker{
+		opts:          opts,
+		cars:          make(map[int]*Car),
+		leader:        -1,
+		firstCrossing: make(map[int]float64),
+		lastCrossing:  make(map[int]float64),
+	}
+}
+
+// Whitelist of the variables required for tracking a race
+func (t *Tracker) Whitelist() []string {
+	return []string{
+		"SessionTime", "SessionNum", "CarIdxPosition", "CarIdxClassPosition", "CarIdxLap", "CarIdxLapDistPct", "CarIdxF2Time",
+	}
+}
+
+// StartStub discards the previous tick, so laps are not completed between stubs
+func (t *Tracker) StartStub(stub ibt.StubInfo) error {
+	t.hasPrev = false
+
+	return nil
+}
+
+// Process the tick without a TickContext. Lap crossings and lead changes are timed with the SessionTime of
+// the tick, which is part of the Whitelist.
+func (t *Tracker) Process(input ibt.Tick, hasNext bool, session *headers.Session) error {
+	return t.ProcessContext(input, ibt.ContextFromTick(input), hasNext, session)
+}
+
+// ProcessContext records the laps completed by every car since the previous tick and any change of the lead
+func (t *Tracker) ProcessContext(input ibt.Tick, tickCtx ibt.TickContext, hasNext bool, session *headers.Session) error {
+	positions, err := ibt.GetTickValue[[]int](input, "CarIdxPosition")
+	if err != nil {
+		return err
+	}
+
+	laps, err := ibt.GetTickValue[[]int](input, "CarIdxLap")
+	if err != nil {
+		return err
+	}
+
+	pcts, err := ibt.GetTickValue[[]float32](input, "CarIdxLapDistPct")
+	if err != nil {
+		return err
+	}
+
+	// Class positions and gaps are not available in every session
+	classPositions, _ := ibt.GetTickValue[[]int](input, "CarIdxClassPosition")
+	gaps, _ := ibt.GetTickValue[[]float32](input, "CarIdxF2Time")
+
+	if session != nil {
+		if session != t.joined {
+			t.join(session.DriverInfo)
+			t.joined = session
+		}
+
+		sessionNum, _ := ibt.GetTickValue[int](input, "SessionNum")
+		if sub, ok := session.SubSession(sessionNum); ok && sub.SessionType != raceSession && !t.opts.AllSessions {
+			t.hasPrev = false
+			return nil
+		}
+	}
+
+	tick := raceTick{
+		sessionTime:    tickCtx.SessionTime,
+		positions:      positions,
+		classPositions: classPositions,
+		laps:           laps,
+		pcts:           pcts,
+		gaps:           gaps,
+	}
+
+	for carIdx, position := range tick.positions {
+		if position <= 0 {
+			continue
+		}
+
+		car := t.car(carIdx)
+		if car.StartPosition == 0 {
+			car.StartPosition = position
+		}
+		car.Position = position
+		car.ClassPosition = valueAt(tick.classPositions, carIdx)
+	}
+
+	if t.hasPrev {
+		t.completeLaps(tick)
+	}
+
+	t.updateLeader(tick)
+
+	t.prev = tick
+	t.hasPrev = true
+
+	return nil
+}
+
+// join the cars with the drivers of the session, excluding the pace car and spectators
+func (t *Tracker) join(info headers.DriverInfo) {
+	for _, driver := range info.Drivers {
+		if driver.CarIsPaceCar == 1 || driver.IsSpectator == 1 {
+			continue
+		}
+
+		car := t.car(driver.CarIdx)
+		car.Name = driver.UserName
+		car.Team = driver.TeamName
+		car.Number = driver.CarNumber
+		car.CarName = driver.CarScreenName
+		car.ClassID = driver.CarClassID
+		car.Player = driver.CarIdx == info.DriverCarIdx
+	}
+}
+
+// car returns the car with the given CarIdx, creating it when it is first seen
+func (t *Tracker) car(carIdx int) *Car {
+	car, ok := t.cars[carIdx]
+	if !ok {
+		car = &Car{CarIdx: carIdx}
+		t.cars[carIdx] = car
+	}
+
+	return car
+}
+
+// completeLaps records the position of every car that crossed the line since the previous tick
+func (t *Tracker) completeLaps(tick raceTick) {
+	leaderLaps := 0
+	for carIdx, position := range tick.positions {
+		if position == 1 {
+			leaderLaps = valueAt(tick.laps, carIdx) - 1
+		}
+	}
+
+	for carIdx := range tick.laps {
+		prevLap, prevPct := valueAt(t.prev.laps, carIdx), float64(valueAt(t.prev.pcts, carIdx))
+		lap, pct := tick.laps[carIdx], float64(valueAt(tick.pcts, carIdx))
+
+		// Cars that are not in the world report a position on the track of -1
+		if lap != prevLap+1 || prevPct < 0 || pct < 0 || prevPct-pct <= laps.MaxPctStep {
+			continue
+		}
+
+		// The crossing of the line is interpolated between the ticks on either side of it
+		before, after := 1-prevPct, pct
+		fraction := 0.0
+		if before+after > 0 {
+			fraction = before / (before + after)
+		}
+		crossing := t.prev.sessionTime + (tick.sessionTime-t.prev.sessionTime)*fraction
+
+		// The first crossing starts the race rather than completing a lap
+		completed := lap - 1
+		if completed < 1 {
+			continue
+		}
+
+		record := Lap{
+			Number:        completed,
+			SessionTime:   crossing,
+			Position:      valueAt(tick.positions, carIdx),
+			ClassPosition: valueAt(tick.classPositions, carIdx),
+			LapsDown:      max(leaderLaps-completed, 0),
+		}
+
+		record.GapLeader, record.GapAhead = t.gaps(tick, carIdx, record)
+
+		car := t.car(carIdx)
+		car.LapsCompleted = completed
+		car.Laps = append(car.Laps, record)
+		if record.Position == 1 {
+			car.LapsLed++
+		}
+	}
+}
+
+// gaps of the car to the leader and to the car ahead from CarIdxF2Time. When the gaps are not available,
+// they are the differences between the times at which the lap was completed by the car and by the cars
+// before it.
+func (t *Tracker) gaps(tick raceTick, carIdx int, record Lap) (float64, float64) {
+	first, ok := t.firstCrossing[record.Number]
+	if !ok {
+		first = record.SessionTime
+		t.firstCrossing[record.Number] = first
+	}
+
+	last, ok := t.lastCrossing[record.Number]
+	if !ok {
+		last = record.SessionTime
+	}
+	t.lastCrossing[record.Number] = record.SessionTime
+
+	if record.Position == 1 {
+		return 0, 0
+	}
+
+	gapLeader := float64(valueAt(tick.gaps, carIdx))
+	if gapLeader <= 0 {
+		return record.SessionTime - first, record.SessionTime - last
+	}
+
+	// The gap to the car ahead is the difference of the gaps of both cars to the leader
+	for aheadIdx, position := range tick.positions {
+		if position > 0 && position == record.Position-1 {
+			return gapLeader, gapLeader - float64(valueAt(tick.gaps, aheadIdx))
+		}
+	}
+
+	return gapLeader, record.SessionTime - last
+}
+
+// updateLeader records a change of the lead when a different car is in the first position
+func (t *Tracker) updateLeader(tick raceTick) {
+	for carIdx, position := range tick.positions {
+		if position != 1 || carIdx == t.leader {
+			continue
+		}
+
+		if t.leader >= 0 {
+			t.leadChanges = append(t.leadChanges, LeadChange{
+				SessionTime: tick.sessionTime,
+				Lap:         valueAt(tick.laps, carIdx),
+				From:        t.leader,
+				To:          carIdx,
+			})
+		}
+		t.leader = carIdx
+
+		return
+	}
+}
+
+// valueAt returns the value of the array at the given index, or the zero value when it is not available
+func valueAt[T int | float32](values []T, idx int) T {
+	var def T
+	if idx < 0 || idx >= len(values) {
+		return def
+	}
+
+	return values[idx]
+}
+
+// Result of the race tracked so far
+func (t *Tracker) Result() Result {
+	result := Result{
+		Cars:        make([]Car, 0, len(t.cars)),
+		LeadChanges: append([]LeadChange(nil), t.leadChanges...),
+	}
+
+	// Positions of the cars that completed each lap
+	type entry struct{ carIdx, position int }
+	chart := make(map[int][]entry)
+
+	for carIdx, car := range t.cars {
+		result.Cars = append(result.Cars, *car)
+
+		for _, lap := range car.Laps {
+			if lap.Position > 0 {
+				chart[lap.Number] = append(chart[lap.Number], entry{carIdx, lap.Position})
+			}
+		}
+	}
+
+	sort.Slice(result.Cars, func(i, j int) bool {
+		a, b := result.Cars[i], result.Cars[j]
+		if (a.Position > 0) != (b.Position > 0) {
+			return a.Position > 0
+		}
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+
+		return a.CarIdx < b.CarIdx
+	})
+
+	for number, entries := range chart {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].position < entries[j].position })
+
+		chartLap := ChartLap{Lap: number, Order: make([]int, 0, len(entries))}
+		for _, e := range entries {
+			chartLap.Order = append(chartLap.Order, e.carIdx)
+		}
+
+		result.Chart = append(result.Chart, chartLap)
+	}
+
+	sort.Slice(result.Chart, func(i, j int) bool { return result.Chart[i].Lap < result.Chart[j].Lap })
+
+	return result
+}
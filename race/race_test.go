@@ -0,0 +1,325 @@
+package race
+
+import (
+	"context"
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/ibttest"
+)
+
+// testCar is a car of the field driving at a constant pace
+type testCar struct {
+	carIdx  int
+	start   float64
+	lapTime float64
+}
+
+// testField has the pace car at CarIdx 3, which is not in the world
+var testField = []testCar{
+	{carIdx: 0, start: 0.99, lapTime: 60},
+	{carIdx: 1, start: 0.98, lapTime: 59},
+	{carIdx: 2, start: 0.97, lapTime: 65},
+	{carIdx: 4, start: 0.96, lapTime: 90},
+}
+
+func testSession() *headers.Session {
+	return &headers.Session{
+		DriverInfo: headers.DriverInfo{
+			DriverCarIdx: 2,
+			PaceCarIdx:   3,
+			Drivers: []headers.Drivers{
+				{CarIdx: 0, UserName: "Driver A", CarNumber: "10", TeamName: "Team A", CarClassID: 1},
+				{CarIdx: 1, UserName: "Driver B", CarNumber: "20", TeamName: "Team B", CarClassID: 1},
+				{CarIdx: 2, UserName: "Driver C", CarNumber: "30", TeamName: "Team C", CarClassID: 1},
+				{CarIdx: 3, UserName: "Pace Car", CarIsPaceCar: 1},
+				{CarIdx: 4, UserName: "Driver D", CarNumber: "40", TeamName: "Team D", CarClassID: 2},
+			},
+		},
+		SessionInfo: headers.SessionInfo{Sessions: []headers.Sessions{
+			{SessionNum: 0, SessionType: "Practice"},
+			{SessionNum: 1, SessionType: "Race"},
+		}},
+	}
+}
+
+// testTicks creates the ticks of the field racing for 240 seconds in the given sub-session.
+//
+// Car 1 passes car 0 for the lead after 35.4 seconds, and car 4 is lapped by car 1. The position of each car
+// is updated on every tick, with the gap to the leader estimated from the distance between them.
+func testTicks(sessionNum int, gaps bool) []ibt.Tick {
+	ticks := make([]ibt.Tick, 0)
+
+	for idx := 0; idx <= 240*60; idx++ {
+		t := float64(idx) / 60
+
+		positions := make([]int, 5)
+		classPositions := make([]int, 5)
+		laps := []int{-1, -1, -1, -1, -1}
+		pcts := []float32{-1, -1, -1, -1, -1}
+		f2Times := make([]float32, 5)
+
+		progress := make(map[int]float64)
+		order := make([]int, 0, len(testField))
+		for _, car := range testField {
+			progress[car.carIdx] = car.start + t/car.lapTime
+			order = append(order, car.carIdx)
+		}
+		sort.Slice(order, func(i, j int) bool { return progress[order[i]] > progress[order[j]] })
+
+		classes := make(map[int]int)
+		for position, carIdx := range order {
+			laps[carIdx] = int(progress[carIdx])
+			pcts[carIdx] = float32(progress[carIdx] - math.Floor(progress[carIdx]))
+			positions[carIdx] = position + 1
+
+			class := 1
+			if carIdx == 4 {
+				class = 2
+			}
+			classes[class]++
+			classPositions[carIdx] = classes[class]
+
+			if gaps {
+				f2Times[carIdx] = float32((progress[order[0]] - progress[carIdx]) * 60)
+			}
+		}
+
+		tick := ibt.Tick{
+			"SessionTime":         t,
+			"SessionNum":          sessionNum,
+			"CarIdxPosition":      positions,
+			"CarIdxClassPosition": classPositions,
+			"CarIdxLap":           laps,
+			"CarIdxLapDistPct":    pcts,
+		}
+		if gaps {
+			tick["CarIdxF2Time"] = f2Times
+		}
+
+		ticks = append(ticks, tick)
+	}
+
+	return ticks
+}
+
+// crossing is the time at which the car completes the given lap
+func crossing(carIdx, lap int) float64 {
+	for _, car := range testField {
+		if car.carIdx == carIdx {
+			return (float64(lap+1) - car.start) * car.lapTime
+		}
+	}
+
+	return 0
+}
+
+var testStub = ibt.StubInfo{Index: 0, Filename: "test.ibt"}
+
+func TestTracker(t *testing.T) {
+	t.Run("test Tracker cars", func(t *testing.T) {
+		tracker := NewTracker(Options{})
+		ibttest.Process(t, tracker, testStub, testTicks(1, true), testSession())
+
+		result := tracker.Result()
+
+		expected := []struct {
+			carIdx        int
+			name          string
+			number        string
+			start         int
+			position      int
+			classPosition int
+			lapsCompleted int
+			lapsLed       int
+		}{
+			{1, "Driver B", "20", 2, 1, 1, 4, 4},
+			{0, "Driver A", "10", 1, 2, 2, 3, 0},
+			{2, "Driver C", "30", 3, 3, 3, 3, 0},
+			{4, "Driver D", "40", 4, 4, 1, 2, 0},
+		}
+
+		if len(result.Cars) != len(expected) {
+			t.Errorf("expected %d cars without the pace car. received %+v", len(expected), result.Cars)
+			return
+		}
+
+		for idx, want := range expected {
+			car := result.Cars[idx]
+			if car.CarIdx != want.carIdx || car.Name != want.name || car.Number != want.number {
+				t.Errorf("expected car %d of %s with number %s in position %d. received %+v", want.carIdx, want.name, want.number, idx+1, car)
+			}
+			if car.StartPosition != want.start || car.Position != want.position || car.ClassPosition != want.classPosition {
+				t.Errorf("expected car %d to start %d and finish %d (%d in class). received %d, %d and %d", want.carIdx, want.start, want.position, want.classPosition, car.StartPosition, car.Position, car.ClassPosition)
+			}
+			if car.LapsCompleted != want.lapsCompleted || car.LapsLed != want.lapsLed || len(car.Laps) != want.lapsCompleted {
+				t.Errorf("expected car %d to complete %d laps and lead %d. received %d (%d recorded) and %d", want.carIdx, want.lapsCompleted, want.lapsLed, car.LapsCompleted, len(car.Laps), car.LapsLed)
+			}
+			if car.Player != (want.carIdx == 2) {
+				t.Errorf("expected only car %d to be the player. received %+v", 2, car)
+			}
+		}
+	})
+
+	t.Run("test Tracker laps", func(t *testing.T) {
+		tracker := NewTracker(Options{})
+		ibttest.Process(t, tracker, testStub, testTicks(1, true), testSession())
+
+		result := tracker.Result()
+		car, ok := result.Car(2)
+		if !ok {
+			t.Error("expected the result to contain car 2")
+			return
+		}
+
+		lap := car.Laps[0]
+		if lap.Number != 1 || math.Abs(lap.SessionTime-crossing(2, 1)) > 0.02 || lap.Position != 3 || lap.ClassPosition != 3 {
+			t.Errorf("expected lap %d completed at %f in position %d. received %+v", 1, crossing(2, 1), 3, lap)
+		}
+
+		// The gaps are estimated from the distance to the leader in the ticks
+		if math.Abs(lap.GapLeader-(crossing(2, 1)-crossing(1, 1))) > 0.2 || math.Abs(lap.GapAhead-(crossing(2, 1)-crossing(0, 1))) > 0.2 {
+			t.Errorf("expected gaps of %f and %f. received %f and %f", crossing(2, 1)-crossing(1, 1), crossing(2, 1)-crossing(0, 1), lap.GapLeader, lap.GapAhead)
+		}
+
+		if leader, _ := result.Car(1); leader.Laps[0].GapLeader != 0 || leader.Laps[0].GapAhead != 0 {
+			t.Errorf("expected no gaps for the leader. received %+v", leader.Laps[0])
+		}
+
+		if lapped, _ := result.Car(4); lapped.Laps[0].LapsDown != 0 || lapped.Laps[1].LapsDown != 1 {
+			t.Errorf("expected car %d to be lapped on lap %d. received %+v", 4, 2, lapped.Laps)
+		}
+	})
+
+	t.Run("test Tracker whitelisted ticks in a pipeline", func(t *testing.T) {
+		tracker := NewTracker(Options{})
+		pipeline := ibt.Pipeline().To(tracker)
+
+		// Only the whitelisted variables are passed to Process, without a TickContext
+		ticks, session := testTicks(1, true), testSession()
+		for idx, tick := range ticks {
+			if err := pipeline.Process(tick.Filter(pipeline.Whitelist()...), idx < len(ticks)-1, session); err != nil {
+				t.Errorf("expected Process() to run without err. received error: %v", err)
+				return
+			}
+		}
+
+		result := tracker.Result()
+		if car, ok := result.Car(2); !ok || math.Abs(car.Laps[0].SessionTime-crossing(2, 1)) > 0.02 {
+			t.Errorf("expected lap %d to be completed at %f. received %+v", 1, crossing(2, 1), car.Laps)
+		}
+		if changes := result.LeadChanges; len(changes) != 1 || math.Abs(changes[0].SessionTime-35.4) > 0.02 {
+			t.Errorf("expected the lead to change at %.1f. received %+v", 35.4, changes)
+		}
+	})
+
+	t.Run("test Tracker gaps without F2Time", func(t *testing.T) {
+		tracker := NewTracker(Options{})
+		ibttest.Process(t, tracker, testStub, testTicks(1, false), nil)
+
+		car, _ := tracker.Result().Car(2)
+		lap := car.Laps[1]
+		if math.Abs(lap.GapLeader-(crossing(2, 2)-crossing(1, 2))) > 0.02 || math.Abs(lap.GapAhead-(crossing(2, 2)-crossing(0, 2))) > 0.02 {
+			t.Errorf("expected gaps of %f and %f. received %f and %f", crossing(2, 2)-crossing(1, 2), crossing(2, 2)-crossing(0, 2), lap.GapLeader, lap.GapAhead)
+		}
+	})
+
+	t.Run("test Tracker chart", func(t *testing.T) {
+		tracker := NewTracker(Options{})
+		ibttest.Process(t, tracker, testStub, testTicks(1, true), testSession())
+
+		expected := []ChartLap{
+			{Lap: 1, Order: []int{1, 0, 2, 4}},
+			{Lap: 2, Order: []int{1, 0, 2, 4}},
+			{Lap: 3, Order: []int{1, 0, 2}},
+			{Lap: 4, Order: []int{1}},
+		}
+
+		chart := tracker.Result().Chart
+		if len(chart) != len(expected) {
+			t.Errorf("expected %d laps in the chart. received %+v", len(expected), chart)
+			return
+		}
+
+		for idx, want := range expected {
+			if chart[idx].Lap != want.Lap || len(chart[idx].Order) != len(want.Order) {
+				t.Errorf("expected the order %v of lap %d. received %+v", want.Order, want.Lap, chart[idx])
+				continue
+			}
+			for position := range want.Order {
+				if chart[idx].Order[position] != want.Order[position] {
+					t.Errorf("expected the order %v of lap %d. received %v", want.Order, want.Lap, chart[idx].Order)
+					break
+				}
+			}
+		}
+	})
+
+	t.Run("test Tracker lead changes", func(t *testing.T) {
+		tracker := NewTracker(Options{})
+		ibttest.Process(t, tracker, testStub, testTicks(1, true), testSession())
+
+		changes := tracker.Result().LeadChanges
+		if len(changes) != 1 {
+			t.Errorf("expected %d lead change. received %+v", 1, changes)
+			return
+		}
+
+		if change := changes[0]; change.From != 0 || change.To != 1 || change.Lap != 1 || math.Abs(change.SessionTime-35.4) > 0.02 {
+			t.Errorf("expected car %d to take the lead from car %d at %.1f. received %+v", 1, 0, 35.4, change)
+		}
+	})
+
+	t.Run("test Tracker sessions", func(t *testing.T) {
+		tracker := NewTracker(Options{})
+		ibttest.Process(t, tracker, testStub, testTicks(0, true), testSession())
+
+		// The drivers are joined, but practice is not tracked
+		result := tracker.Result()
+		if len(result.Cars) != 4 || len(result.Chart) != 0 || result.Cars[0].Position != 0 {
+			t.Errorf("expected the cars without any laps. received %+v", result)
+		}
+
+		tracker = NewTracker(Options{AllSessions: true})
+		ibttest.Process(t, tracker, testStub, testTicks(0, true), testSession())
+
+		if result := tracker.Result(); len(result.Chart) != 4 {
+			t.Errorf("expected %d laps in the chart of all sessions. received %d", 4, len(result.Chart))
+		}
+	})
+
+	t.Run("test Tracker missing variables", func(t *testing.T) {
+		if err := NewTracker(Options{}).Process(ibt.Tick{"CarIdxPosition": []int{1}}, true, nil); err == nil {
+			t.Error("expected Process() to return an error when CarIdxLap is missing")
+		}
+	})
+
+	t.Run("test Tracker from registry", func(t *testing.T) {
+		processor, err := ibt.DefaultRegistry.NewProcessor("race", ibt.Params{"all_sessions": true})
+		if err != nil {
+			t.Errorf("expected NewProcessor() to run without err. received error: %v", err)
+			return
+		}
+
+		if tracker := processor.(*Tracker); !tracker.opts.AllSessions {
+			t.Errorf("expected the options of the params. received %+v", tracker.opts)
+		}
+	})
+
+	t.Run("test Tracker with ibt.Process", func(t *testing.T) {
+		stubs, err := ibt.ParseStubs("../.testing/valid_test_file.ibt")
+		if err != nil {
+			t.Errorf("failed to parse stubs for testing file - %v", err)
+			return
+		}
+		defer stubs.Close()
+
+		// The testing file does not contain the CarIdx variables
+		if err := ibt.Process(context.Background(), stubs, NewTracker(Options{})); err == nil {
+			t.Error("expected Process() to return an error without the CarIdx variables")
+		}
+	})
+}
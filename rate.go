@@ -0,0 +1,27 @@
+package ibt
+
+// RateProcessor is a Processor that requires ticks at a lower rate than the telemetry tick rate.
+//
+// Process will decimate the ticks delivered to these processors according to the TickRate of each
+// stub. The final tick of a stub is always delivered. Other processors continue to receive every tick.
+type RateProcessor interface {
+	Processor
+	// Rate is the desired number of ticks per second. For example, a rate of 1 with a TickRate
+	// of 60 will deliver every 60th tick.
+	Rate() float64
+}
+
+// Decimation is the strategy used for reducing the ticks delivered to a RateProcessor.
+type Decimation int
+
+const (
+	// DecimateNth delivers every Nth tick. This is the default strategy.
+	DecimateNth Decimation = iota
+	// DecimateMean delivers the mean of every N ticks, as calculated by MeanTicks.
+	DecimateMean
+)
+
+// Decimator can be implemented by a RateProcessor to select its Decimation strategy.
+type Decimator interface {
+	Decimation() Decimation
+}
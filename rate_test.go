@@ -0,0 +1,110 @@
+package ibt
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/teamjorge/ibt/headers"
+)
+
+type testRateProcessor struct {
+	testHasNextProcessor
+	rate       float64
+	decimation Decimation
+}
+
+func (t *testRateProcessor) Rate() float64 { return t.rate }
+
+func (t *testRateProcessor) Decimation() Decimation { return t.decimation }
+
+func TestRateProcessor(t *testing.T) {
+	f, err := os.Open(".testing/valid_test_file.ibt")
+	if err != nil {
+		t.Errorf("failed to open testing file - %v", err)
+		return
+	}
+	defer f.Close()
+
+	testHeaders, err := headers.ParseHeaders(f)
+	if err != nil {
+		t.Errorf("failed to parse header for testing file - %v", err)
+		return
+	}
+
+	stubs := StubGroup{
+		{filepath: ".testing/valid_test_file.ibt", header: testHeaders, r: f},
+	}
+
+	t.Run("test Process() with every Nth decimation", func(t *testing.T) {
+		proc := testRateProcessor{testHasNextProcessor: testHasNextProcessor{testProcessor: testProcessor{whitelist: []string{"SessionTime"}}}, rate: 1}
+		full := testProcessor{whitelist: []string{"SessionTime"}}
+
+		if err := Process(context.Background(), stubs, &proc, &full); err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		if len(full.results) != 389 {
+			t.Errorf("expected other processors to receive all %d ticks. received %d", 389, len(full.results))
+		}
+
+		// Every 60th tick from the first, along with the final tick
+		if len(proc.results) != 8 {
+			t.Errorf("expected %d decimated ticks. received %d", 8, len(proc.results))
+			return
+		}
+
+		if proc.results[1]["SessionTime"] != full.results[60]["SessionTime"] {
+			t.Errorf("expected the second decimated tick to be tick 60. received %v", proc.results[1])
+		}
+
+		if proc.results[7]["SessionTime"] != full.results[388]["SessionTime"] || proc.hasNext[7] {
+			t.Errorf("expected the final tick to be delivered without a next tick. received %v", proc.results[7])
+		}
+	})
+
+	t.Run("test Process() with mean decimation", func(t *testing.T) {
+		proc := testRateProcessor{testHasNextProcessor: testHasNextProcessor{testProcessor: testProcessor{whitelist: []string{"SessionTime"}}}, rate: 1, decimation: DecimateMean}
+		full := testProcessor{whitelist: []string{"SessionTime"}}
+
+		if err := Process(context.Background(), stubs, &proc, &full); err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		if len(proc.results) != 7 {
+			t.Errorf("expected %d decimated ticks. received %d", 7, len(proc.results))
+			return
+		}
+
+		expected := (full.results[0]["SessionTime"].(float64) + full.results[59]["SessionTime"].(float64)) / 2
+		if diff := proc.results[0]["SessionTime"].(float64) - expected; diff > 0.0001 || diff < -0.0001 {
+			t.Errorf("expected the first decimated tick to be the mean of the first 60 ticks (%f). received %v", expected, proc.results[0]["SessionTime"])
+		}
+
+		if proc.hasNext[6] {
+			t.Error("expected the final decimated tick to be delivered without a next tick")
+		}
+	})
+}
+
+func TestDecimationStep(t *testing.T) {
+	tests := []struct {
+		name     string
+		tickRate int
+		rate     float64
+		want     int
+	}{
+		{name: "1hz from 60hz", tickRate: 60, rate: 1, want: 60},
+		{name: "10hz from 360hz", tickRate: 360, rate: 10, want: 36},
+		{name: "uneven rate", tickRate: 60, rate: 7, want: 9},
+		{name: "higher than tick rate", tickRate: 60, rate: 120, want: 1},
+		{name: "zero rate", tickRate: 60, rate: 0, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decimationStep(tt.tickRate, tt.rate); got != tt.want {
+				t.Errorf("decimationStep() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
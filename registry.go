@@ -0,0 +1,170 @@
+package ibt
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Params are the parameters of a processor or output, as provided by a JobConfig.
+type Params map[string]interface{}
+
+// Decode the parameters into the given value, which is usually a pointer to a struct.
+//
+// Fields are matched using their yaml struct tags.
+func (p Params) Decode(v interface{}) error {
+	raw, err := yaml.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to encode params: %w", err)
+	}
+
+	if err := yaml.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("failed to decode params: %w", err)
+	}
+
+	return nil
+}
+
+// ProcessorConstructor creates a new processor from the given parameters.
+type ProcessorConstructor func(params Params) (Processor, error)
+
+// Output receives the result of each group processed by a Job.
+//
+// Outputs implementing io.Closer will be closed once the Job has completed.
+type Output interface {
+	Write(result GroupResult) error
+}
+
+// OutputConstructor creates a new output from the given parameters.
+type OutputConstructor func(params Params) (Output, error)
+
+// Registry maps names to the constructors of processors and outputs.
+//
+// Packages can register their processors with DefaultRegistry, allowing jobs to refer to them by name.
+type Registry struct {
+	mu         sync.RWMutex
+	processors map[string]ProcessorConstructor
+	outputs    map[string]OutputConstructor
+}
+
+// DefaultRegistry is the Registry used by Register, RegisterOutput and LoadJob.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		processors: make(map[string]ProcessorConstructor),
+		outputs:    make(map[string]OutputConstructor),
+	}
+}
+
+// Register a processor constructor with DefaultRegistry.
+func Register(name string, constructor ProcessorConstructor) {
+	DefaultRegistry.Register(name, constructor)
+}
+
+// RegisterOutput registers an output constructor with DefaultRegistry.
+func RegisterOutput(name string, constructor OutputConstructor) {
+	DefaultRegistry.RegisterOutput(name, constructor)
+}
+
+// Register a processor constructor under the given name.
+//
+// Register panics if the constructor is nil or the name is already registered, as registration
+// usually happens from init functions where errors cannot be handled.
+func (r *Registry) Register(name string, constructor ProcessorConstructor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if constructor == nil {
+		panic(fmt.Sprintf("ibt: processor constructor for %s is nil", name))
+	}
+	if _, ok := r.processors[name]; ok {
+		panic(fmt.Sprintf("ibt: processor %s is already registered", name))
+	}
+
+	r.processors[name] = constructor
+}
+
+// RegisterOutput registers an output constructor under the given name.
+//
+// RegisterOutput panics if the constructor is nil or the name is already registered.
+func (r *Registry) RegisterOutput(name string, constructor OutputConstructor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if constructor == nil {
+		panic(fmt.Sprintf("ibt: output constructor for %s is nil", name))
+	}
+	if _, ok := r.outputs[name]; ok {
+		panic(fmt.Sprintf("ibt: output %s is already registered", name))
+	}
+
+	r.outputs[name] = constructor
+}
+
+// NewProcessor creates the processor registered under the given name.
+func (r *Registry) NewProcessor(name string, params Params) (Processor, error) {
+	r.mu.RLock()
+	constructor, ok := r.processors[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("processor %s is not registered", name)
+	}
+
+	proc, err := constructor(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create processor %s: %w", name, err)
+	}
+
+	return proc, nil
+}
+
+// NewOutput creates the output registered under the given name.
+func (r *Registry) NewOutput(name string, params Params) (Output, error) {
+	r.mu.RLock()
+	constructor, ok := r.outputs[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("output %s is not registered", name)
+	}
+
+	output, err := constructor(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output %s: %w", name, err)
+	}
+
+	return output, nil
+}
+
+// Processors returns the sorted names of all registered processors.
+func (r *Registry) Processors() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.processors))
+	for name := range r.processors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Outputs returns the sorted names of all registered outputs.
+func (r *Registry) Outputs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.outputs))
+	for name := range r.outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,101 @@
+package ibt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParamsDecode(t *testing.T) {
+	params := Params{"vars": []interface{}{"Speed", "Lap"}, "threshold": 100}
+
+	var decoded struct {
+		Vars      []string `yaml:"vars"`
+		Threshold int      `yaml:"threshold"`
+	}
+
+	if err := params.Decode(&decoded); err != nil {
+		t.Errorf("expected Decode() to run without err. received error: %v", err)
+	}
+
+	if !reflect.DeepEqual(decoded.Vars, []string{"Speed", "Lap"}) || decoded.Threshold != 100 {
+		t.Errorf("expected params to be decoded. received %+v", decoded)
+	}
+
+	var invalid struct {
+		Threshold []int `yaml:"threshold"`
+	}
+	if err := params.Decode(&invalid); err == nil {
+		t.Error("expected Decode() to return an error for mismatched types")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("speed", func(params Params) (Processor, error) {
+		return &testProcessor{whitelist: []string{"Speed"}}, nil
+	})
+	registry.Register("broken", func(params Params) (Processor, error) {
+		return nil, errors.New("unit test constructor error")
+	})
+	registry.RegisterOutput("memory", func(params Params) (Output, error) { return &testOutput{}, nil })
+
+	t.Run("test Registry NewProcessor()", func(t *testing.T) {
+		proc, err := registry.NewProcessor("speed", nil)
+		if err != nil {
+			t.Errorf("expected NewProcessor() to run without err. received error: %v", err)
+		}
+
+		if _, ok := proc.(*testProcessor); !ok {
+			t.Errorf("expected a *testProcessor to be created. received %T", proc)
+		}
+
+		if _, err := registry.NewProcessor("missing", nil); err == nil {
+			t.Error("expected NewProcessor() to return an error for an unregistered processor")
+		}
+
+		if _, err := registry.NewProcessor("broken", nil); err == nil {
+			t.Error("expected NewProcessor() to return the constructor error")
+		}
+	})
+
+	t.Run("test Registry NewOutput()", func(t *testing.T) {
+		if _, err := registry.NewOutput("memory", nil); err != nil {
+			t.Errorf("expected NewOutput() to run without err. received error: %v", err)
+		}
+
+		if _, err := registry.NewOutput("missing", nil); err == nil {
+			t.Error("expected NewOutput() to return an error for an unregistered output")
+		}
+	})
+
+	t.Run("test Registry names", func(t *testing.T) {
+		if names := registry.Processors(); !reflect.DeepEqual(names, []string{"broken", "speed"}) {
+			t.Errorf("expected registered processors %v. received %v", []string{"broken", "speed"}, names)
+		}
+
+		if names := registry.Outputs(); !reflect.DeepEqual(names, []string{"memory"}) {
+			t.Errorf("expected registered outputs %v. received %v", []string{"memory"}, names)
+		}
+	})
+
+	t.Run("test Registry Register() duplicate", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected Register() to panic for a duplicate name")
+			}
+		}()
+
+		registry.Register("speed", func(params Params) (Processor, error) { return &testProcessor{}, nil })
+	})
+
+	t.Run("test Registry Register() nil constructor", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected RegisterOutput() to panic for a nil constructor")
+			}
+		}()
+
+		registry.RegisterOutput("nil", nil)
+	})
+}
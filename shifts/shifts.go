@@ -0,0 +1,324 @@
+// Package shifts analyses gear changes against the shift-light RPMs of the car.
+//
+// An Analyzer records every upshift and downshift with the RPM at which it was made and how long it took,
+// along with the time spent in each gear. Shifts are flagged as early, late or over-rev by comparing them to
+// the DriverCarSLShiftRPM and DriverCarRedLine of the session.
+package shifts
+
+import (
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+)
+
+const (
+	// Default number of RPM either side of the shift RPM within which an upshift is on time
+	defaultTolerance float64 = 200
+	// Default number of RPM below the redline at which a downshift over-revs the engine
+	defaultRedlineMargin float64 = 200
+	// Default maximum number of seconds in neutral between two gears for a shift
+	defaultMaxShiftTime float64 = 1
+	// Default number of seconds after a shift during which the peak RPM of the next gear is recorded
+	defaultPeakWindow float64 = 0.5
+)
+
+func init() {
+	ibt.Register("shifts", func(params ibt.Params) (ibt.Processor, error) {
+		var opts Options
+		if err := params.Decode(&opts); err != nil {
+			return nil, err
+		}
+
+		return NewAnalyzer(opts), nil
+	})
+}
+
+// Flag assessing a shift against the shift-light RPMs of the car.
+type Flag string
+
+const (
+	// OnTime is an upshift within the tolerance of the shift RPM, or a downshift that did not over-rev
+	OnTime Flag = ""
+	// Early is an upshift below the shift RPM
+	Early Flag = "early"
+	// Late is an upshift above the shift RPM
+	Late Flag = "late"
+	// OverRev is an upshift at or above the redline, or a downshift after which the engine reached the redline
+	// within the PeakWindow
+	OverRev Flag = "over-rev"
+)
+
+// Options configures the assessment of shifts.
+type Options struct {
+	// Tolerance is the number of RPM either side of the shift RPM within which an upshift is on time.
+	// Defaults to 200.
+	Tolerance float64 `yaml:"tolerance"`
+	// RedlineMargin is the number of RPM below the redline that the engine may reach after a downshift
+	// without over-revving. Defaults to 200.
+	RedlineMargin float64 `yaml:"redline_margin"`
+	// MaxShiftTime is the maximum number of seconds in neutral between two gears for them to be a single
+	// shift. Defaults to 1.
+	MaxShiftTime float64 `yaml:"max_shift_time"`
+	// PeakWindow is the number of seconds after a shift during which the peak RPM of the next gear is
+	// recorded. Downshifts are assessed against this peak. Defaults to 0.5.
+	PeakWindow float64 `yaml:"peak_window"`
+}
+
+// Shift is a single change between forward gears.
+type Shift struct {
+	// Stub is the index of the stub within the group being processed
+	Stub int
+	// Filename of the stub
+	Filename string
+	// Lap during which the shift was made
+	Lap int
+	// Pct is the LapDistPct where the shift was made
+	Pct float64
+	// SessionTime at which the previous gear was last engaged
+	SessionTime float64
+	// From and To are the gears before and after the shift
+	From int
+	To   int
+	// RPM of the engine in the previous gear, just before the shift
+	RPM float64
+	// RPMAfter is the RPM of the engine in the next gear, just after the shift
+	RPMAfter float64
+	// PeakRPM is the highest RPM of the engine in the next gear within the PeakWindow after the shift
+	PeakRPM float64
+	// Duration is the number of seconds between the previous and next gear being engaged
+	Duration float64
+	// Flag assessing the shift. This is always OnTime when the session does not contain the shift RPMs.
+	Flag Flag
+}
+
+// Up indicates whether the shift was to a higher gear
+func (s Shift) Up() bool { return s.To > s.From }
+
+// Result of the shifts processed by an Analyzer.
+type Result struct {
+	// Gears is the number of forward gears of the car, as reported by the session
+	Gears int
+	// ShiftRPM and RedLine of the car, as reported by the session
+	ShiftRPM float64
+	RedLine  float64
+	// Shifts in the order they were made
+	Shifts []Shift
+	// GearTime is the number of seconds spent in each gear, where 0 is neutral and -1 is reverse
+	GearTime map[int]float64
+	// Counts of the shifts by direction and flag
+	Upshifts   int
+	Downshifts int
+	Early      int
+	Late       int
+	OverRev    int
+}
+
+// Analyzer is a processor that analyses every shift between forward gears.
+type Analyzer struct {
+	opts     Options
+	shifts   []Shift
+	gearTime map[int]float64
+	car      headers.DriverInfo
+
+	stub ibt.StubInfo
+	prev gearTick
+	// last is the last tick during which a forward gear was engaged
+	last    gearTick
+	hasPrev bool
+	hasLast bool
+	// peaking indicates that the peak RPM of the last shift is still being recorded
+	peaking bool
+}
+
+// gearTick is the part of a tick used for analysing shifts
+type gearTick struct {
+	lap         int
+	pct         float64
+	sessionTime float64
+	gear        int
+	rpm         float64
+}
+
+// NewAnalyzer creates a shift Analyzer with the given options.
+func NewAnalyzer(opts Options) *Analyzer {
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = defaultTolerance
+	}
+	if opts.RedlineMargin <= 0 {
+		opts.RedlineMargin = defaultRedlineMargin
+	}
+	if opts.MaxShiftTime <= 0 {
+		opts.MaxShiftTime = defaultMaxShiftTime
+	}
+	if opts.PeakWindow <= 0 {
+		opts.PeakWindow = defaultPeakWindow
+	}
+
+	return &Analyzer{opts: opts, gearTime: make(map[int]float64)}
+}
+
+// Whitelist of the variables required for analysing shifts
+func (a *Analyzer) Whitelist() []string { return []string{"Lap", "LapDistPct", "Gear", "RPM"} }
+
+// StartStub resets the gear of the previous stub
+func (a *Analyzer) StartStub(stub ibt.StubInfo) error {
+	a.stub = stub
+	a.hasPrev = false
+	a.hasLast = false
+	a.peaking = false
+
+	return nil
+}
+
+// Process the tick without a TickContext. Shift durations and the time spent in each gear are measured from
+// the SessionTime of the tick.
+func (a *Analyzer) Process(input ibt.Tick, hasNext bool, session *headers.Session) error {
+	return a.ProcessContext(input, ibt.ContextFromTick(input), hasNext, session)
+}
+
+// ProcessContext adds the tick to the time spent in its gear, recording a shift when the forward gear changed
+func (a *Analyzer) ProcessContext(input ibt.Tick, tickCtx ibt.TickContext, hasNext bool, session *headers.Session) error {
+	gear, err := ibt.GetTickValue[int](input, "Gear")
+	if err != nil {
+		return err
+	}
+
+	rpm, err := ibt.GetTickValue[float32](input, "RPM")
+	if err != nil {
+		return err
+	}
+
+	// The position of the shift is not required for analysing it
+	lap, _ := ibt.GetTickValue[int](input, "Lap")
+	pct, _ := ibt.GetTickValue[float32](input, "LapDistPct")
+
+	if a.car.DriverCarGearNumForward == 0 && session != nil {
+		a.car = session.DriverInfo
+	}
+
+	tick := gearTick{lap: lap, pct: float64(pct), sessionTime: tickCtx.SessionTime, gear: gear, rpm: float64(rpm)}
+
+	if a.hasPrev {
+		a.gearTime[a.prev.gear] += tick.sessionTime - a.prev.sessionTime
+	}
+
+	if a.peaking {
+		a.peak(tick)
+	}
+
+	switch {
+	case gear > 0:
+		if a.hasLast && gear != a.last.gear && tick.sessionTime-a.last.sessionTime <= a.opts.MaxShiftTime {
+			a.shift(a.last, tick)
+		}
+		a.last, a.hasLast = tick, true
+	case gear < 0:
+		// Engaging reverse ends any shift in progress
+		a.hasLast = false
+	}
+
+	a.prev, a.hasPrev = tick, true
+
+	return nil
+}
+
+// shift records the shift between the given ticks in the previous and next gear
+func (a *Analyzer) shift(from, to gearTick) {
+	s := Shift{
+		Stub:        a.stub.Index,
+		Filename:    a.stub.Filename,
+		Lap:         from.lap,
+		Pct:         from.pct,
+		SessionTime: from.sessionTime,
+		From:        from.gear,
+		To:          to.gear,
+		RPM:         from.rpm,
+		RPMAfter:    to.rpm,
+		PeakRPM:     to.rpm,
+		Duration:    to.sessionTime - from.sessionTime,
+	}
+
+	s.Flag = a.assess(s)
+	a.shifts = append(a.shifts, s)
+	a.peaking = true
+}
+
+// peak records the RPM of the given tick as the peak of the last shift while it is within the PeakWindow.
+//
+// The shift is assessed again with every new peak, as the engine often only reaches its highest RPM a few
+// ticks after the clutch is engaged.
+func (a *Analyzer) peak(tick gearTick) {
+	s := &a.shifts[len(a.shifts)-1]
+
+	if tick.gear != s.To || tick.sessionTime-s.SessionTime-s.Duration > a.opts.PeakWindow {
+		a.peaking = false
+		return
+	}
+
+	if tick.rpm > s.PeakRPM {
+		s.PeakRPM = tick.rpm
+		s.Flag = a.assess(*s)
+	}
+}
+
+// assess the shift against the shift-light RPMs of the car
+func (a *Analyzer) assess(s Shift) Flag {
+	shiftRPM, redLine := float64(a.car.DriverCarSLShiftRPM), float64(a.car.DriverCarRedLine)
+
+	if !s.Up() {
+		if redLine > 0 && s.PeakRPM >= redLine-a.opts.RedlineMargin {
+			return OverRev
+		}
+		return OnTime
+	}
+
+	switch {
+	case redLine > 0 && s.RPM >= redLine:
+		return OverRev
+	case shiftRPM <= 0:
+		return OnTime
+	case s.RPM < shiftRPM-a.opts.Tolerance:
+		return Early
+	case s.RPM > shiftRPM+a.opts.Tolerance:
+		return Late
+	}
+
+	return OnTime
+}
+
+// Result of the shifts analysed so far
+func (a *Analyzer) Result() Result {
+	result := Result{
+		Gears:    a.car.DriverCarGearNumForward,
+		ShiftRPM: float64(a.car.DriverCarSLShiftRPM),
+		RedLine:  float64(a.car.DriverCarRedLine),
+		Shifts:   a.shifts,
+		GearTime: make(map[int]float64),
+	}
+
+	// Every forward gear of the car is reported, including those that were never engaged
+	for gear := 1; gear <= result.Gears; gear++ {
+		result.GearTime[gear] = 0
+	}
+	for gear, t := range a.gearTime {
+		result.GearTime[gear] = t
+	}
+
+	for _, s := range a.shifts {
+		if s.Up() {
+			result.Upshifts++
+		} else {
+			result.Downshifts++
+		}
+
+		switch s.Flag {
+		case Early:
+			result.Early++
+		case Late:
+			result.Late++
+		case OverRev:
+			result.OverRev++
+		}
+	}
+
+	return result
+}
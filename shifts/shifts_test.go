@@ -0,0 +1,209 @@
+package shifts
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/ibttest"
+)
+
+var testSession = &headers.Session{DriverInfo: headers.DriverInfo{
+	DriverCarGearNumForward: 6,
+	DriverCarSLShiftRPM:     7000,
+	DriverCarRedLine:        7800,
+}}
+
+// segment of a gear engaged for a duration, with the RPM changing linearly between from and to
+type segment struct {
+	gear     int
+	duration float64
+	from, to float64
+}
+
+// testSegments contains an on time, early, late and over-rev upshift, followed by an on time and over-rev
+// downshift. The car is then stopped in neutral and pulls away in first gear.
+var testSegments = []segment{
+	{gear: 1, duration: 2, from: 4000, to: 7000},
+	{gear: 2, duration: 2, from: 5000, to: 6500},
+	{gear: 0, duration: 0.1, from: 6000, to: 6000},
+	{gear: 3, duration: 2, from: 5500, to: 7500},
+	{gear: 4, duration: 2, from: 6000, to: 7900},
+	{gear: 5, duration: 2, from: 7000, to: 4500},
+	{gear: 4, duration: 1, from: 6000, to: 5000},
+	{gear: 3, duration: 1, from: 7700, to: 4000},
+	{gear: 0, duration: 2, from: 1000, to: 1000},
+	{gear: 1, duration: 1, from: 3000, to: 5000},
+}
+
+// testTicks creates the ticks of the segments at 60 ticks per second
+func testTicks(segments []segment) []ibt.Tick {
+	ticks := make([]ibt.Tick, 0)
+	sessionTime := 0.0
+
+	for _, s := range segments {
+		n := int(math.Round(s.duration * 60))
+		for idx := 0; idx < n; idx++ {
+			ticks = append(ticks, ibt.Tick{
+				"SessionTime": sessionTime,
+				"Lap":         1,
+				"LapDistPct":  float32(sessionTime / 100),
+				"Gear":        s.gear,
+				"RPM":         float32(s.from + (s.to-s.from)*float64(idx)/math.Max(1, float64(n-1))),
+			})
+			sessionTime += 1.0 / 60
+		}
+	}
+
+	return ticks
+}
+
+var testStub = ibt.StubInfo{Index: 0, Filename: "test.ibt"}
+
+func TestAnalyzer(t *testing.T) {
+	t.Run("test Analyzer shifts", func(t *testing.T) {
+		analyzer := NewAnalyzer(Options{})
+		ibttest.Process(t, analyzer, testStub, testTicks(testSegments), testSession)
+
+		result := analyzer.Result()
+		if len(result.Shifts) != 6 {
+			t.Errorf("expected %d shifts. received %d", 6, len(result.Shifts))
+			return
+		}
+
+		tests := []struct {
+			from, to int
+			rpm      float64
+			rpmAfter float64
+			flag     Flag
+		}{
+			{from: 1, to: 2, rpm: 7000, rpmAfter: 5000, flag: OnTime},
+			{from: 2, to: 3, rpm: 6500, rpmAfter: 5500, flag: Early},
+			{from: 3, to: 4, rpm: 7500, rpmAfter: 6000, flag: Late},
+			{from: 4, to: 5, rpm: 7900, rpmAfter: 7000, flag: OverRev},
+			{from: 5, to: 4, rpm: 4500, rpmAfter: 6000, flag: OnTime},
+			{from: 4, to: 3, rpm: 5000, rpmAfter: 7700, flag: OverRev},
+		}
+
+		for idx, test := range tests {
+			s := result.Shifts[idx]
+			if s.From != test.from || s.To != test.to || s.Up() != (test.to > test.from) || s.Filename != "test.ibt" || s.Lap != 1 {
+				t.Errorf("expected shift %d from %d to %d. received %d to %d", idx, test.from, test.to, s.From, s.To)
+			}
+			if math.Abs(s.RPM-test.rpm) > 1 || math.Abs(s.RPMAfter-test.rpmAfter) > 1 || s.Flag != test.flag {
+				t.Errorf("expected shift %d at %.0f RPM to be %q. received %.0f RPM and %q", idx, test.rpm, test.flag, s.RPM, s.Flag)
+			}
+		}
+
+		if math.Abs(result.Shifts[0].Duration-1.0/60) > 0.0001 || math.Abs(result.Shifts[1].Duration-0.1-1.0/60) > 0.0001 {
+			t.Errorf("expected the shift through neutral to take %.2f seconds. received %f", 0.1, result.Shifts[1].Duration)
+		}
+
+		if result.Upshifts != 4 || result.Downshifts != 2 || result.Early != 1 || result.Late != 1 || result.OverRev != 2 {
+			t.Errorf("expected %d upshifts and %d downshifts. received %+v", 4, 2, result)
+		}
+
+		if result.Gears != 6 || result.ShiftRPM != 7000 || result.RedLine != 7800 || len(result.GearTime) != 7 {
+			t.Errorf("expected the time of %d forward gears and neutral. received %+v", 6, result.GearTime)
+		}
+
+		if math.Abs(result.GearTime[4]-3) > 0.0001 || math.Abs(result.GearTime[0]-2.1) > 0.0001 || result.GearTime[6] != 0 {
+			t.Errorf("expected %d seconds in fourth gear and %.1f in neutral. received %+v", 3, 2.1, result.GearTime)
+		}
+	})
+
+	t.Run("test Analyzer without a session", func(t *testing.T) {
+		analyzer := NewAnalyzer(Options{})
+		ibttest.Process(t, analyzer, testStub, testTicks(testSegments), nil)
+
+		result := analyzer.Result()
+		if len(result.Shifts) != 6 || result.Early+result.Late+result.OverRev != 0 || result.Gears != 0 {
+			t.Errorf("expected shifts without flags. received %+v", result)
+		}
+	})
+
+	t.Run("test Analyzer peak RPM after a downshift", func(t *testing.T) {
+		// The engine only reaches the redline 0.3 seconds after third gear is engaged
+		segments := []segment{
+			{gear: 4, duration: 1, from: 5000, to: 5000},
+			{gear: 3, duration: 0.3, from: 6500, to: 7700},
+			{gear: 3, duration: 1, from: 7700, to: 5000},
+		}
+
+		analyzer := NewAnalyzer(Options{})
+		ibttest.Process(t, analyzer, testStub, testTicks(segments), testSession)
+
+		result := analyzer.Result()
+		if len(result.Shifts) != 1 {
+			t.Errorf("expected %d shift. received %d", 1, len(result.Shifts))
+			return
+		}
+		if s := result.Shifts[0]; s.RPMAfter != 6500 || s.PeakRPM != 7700 || s.Flag != OverRev || result.OverRev != 1 {
+			t.Errorf("expected a peak of %d RPM to over-rev. received %+v", 7700, s)
+		}
+
+		// The redline is reached after the window has closed
+		analyzer = NewAnalyzer(Options{PeakWindow: 0.1})
+		ibttest.Process(t, analyzer, testStub, testTicks(segments), testSession)
+
+		if s := analyzer.Result().Shifts[0]; s.PeakRPM >= 7600 || s.Flag != OnTime {
+			t.Errorf("expected the peak within %.1f seconds to be on time. received %+v", 0.1, s)
+		}
+	})
+
+	t.Run("test Analyzer reverse", func(t *testing.T) {
+		analyzer := NewAnalyzer(Options{})
+		ibttest.Process(t, analyzer, testStub, testTicks([]segment{
+			{gear: 1, duration: 1, from: 3000, to: 3000},
+			{gear: -1, duration: 0.1, from: 2000, to: 2000},
+			{gear: 2, duration: 1, from: 3000, to: 3000},
+		}), testSession)
+
+		if result := analyzer.Result(); len(result.Shifts) != 0 || math.Abs(result.GearTime[-1]-0.1) > 0.0001 {
+			t.Errorf("expected no shifts through reverse. received %+v", result)
+		}
+	})
+
+	t.Run("test Analyzer options from registry", func(t *testing.T) {
+		processor, err := ibt.DefaultRegistry.NewProcessor("shifts", ibt.Params{"tolerance": 600, "redline_margin": 50, "max_shift_time": 0.05})
+		if err != nil {
+			t.Errorf("expected NewProcessor() to run without err. received error: %v", err)
+			return
+		}
+
+		analyzer := processor.(*Analyzer)
+		ibttest.Process(t, analyzer, testStub, testTicks(testSegments), testSession)
+
+		// The shift through neutral takes too long, while the late upshift and downshift are within the margins
+		if result := analyzer.Result(); len(result.Shifts) != 5 || result.Early+result.Late != 0 || result.OverRev != 1 {
+			t.Errorf("expected %d shifts with a single over-rev. received %+v", 5, result)
+		}
+	})
+
+	t.Run("test Analyzer missing variables", func(t *testing.T) {
+		if err := NewAnalyzer(Options{}).Process(ibt.Tick{"Gear": 1}, true, nil); err == nil {
+			t.Error("expected Process() to return an error when RPM is missing")
+		}
+	})
+
+	t.Run("test Analyzer with ibt.Process", func(t *testing.T) {
+		stubs, err := ibt.ParseStubs("../.testing/valid_test_file.ibt")
+		if err != nil {
+			t.Errorf("failed to parse stubs for testing file - %v", err)
+			return
+		}
+		defer stubs.Close()
+
+		analyzer := NewAnalyzer(Options{})
+		if err := ibt.Process(context.Background(), stubs, analyzer); err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		result := analyzer.Result()
+		if result.Gears == 0 || result.ShiftRPM == 0 || result.GearTime[1] <= 0 {
+			t.Errorf("expected the gears of the car of the session. received %+v", result)
+		}
+	})
+}
@@ -0,0 +1,276 @@
+// Package stats provides a processor computing streaming statistics of telemetry variables.
+//
+// For every numeric variable, including each element of array variables, the minimum, maximum, mean,
+// standard deviation, percentiles and a histogram are computed. Statistics are available per lap,
+// per stub and for all stubs processed together.
+//
+// Percentiles are estimated with a Sketch, which uses a fixed amount of memory regardless of the
+// number of ticks processed.
+package stats
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+)
+
+const (
+	// Default number of bins of each histogram
+	defaultBins int = 20
+	// Name of the telemetry variable used to summarise each lap
+	lapVar string = "Lap"
+)
+
+// Default percentiles estimated for each variable
+var defaultPercentiles = []float64{5, 25, 50, 75, 95}
+
+func init() {
+	ibt.Register("stats", func(params ibt.Params) (ibt.Processor, error) {
+		var opts Options
+		if err := params.Decode(&opts); err != nil {
+			return nil, err
+		}
+
+		return New(opts), nil
+	})
+}
+
+// Options configures the statistics computed by a Processor.
+type Options struct {
+	// Vars to compute statistics for. All numeric variables are used when empty.
+	Vars []string `yaml:"vars"`
+	// Percentiles to estimate, between 0 and 100. Defaults to 5, 25, 50, 75 and 95.
+	Percentiles []float64 `yaml:"percentiles"`
+	// Bins of each histogram. Defaults to 20.
+	Bins int `yaml:"bins"`
+	// Ranges of the histograms of specific variables. The elements of array variables use the range of
+	// the variable, unless a range is provided for the element, such as CarIdxRPM[3].
+	//
+	// Histograms of variables without a range are estimated from the percentile sketch over the observed
+	// minimum and maximum values.
+	Ranges map[string]Range `yaml:"ranges"`
+	// Compression of the percentile sketch. Defaults to 100.
+	Compression float64 `yaml:"compression"`
+}
+
+// Result of a Processor.
+type Result struct {
+	// Group summarises the variables across all ticks processed
+	Group map[string]Summary
+	// Stubs summarises the variables of each stub, in the order they were processed
+	Stubs []StubResult
+	// Laps summarises the variables of each lap across all stubs
+	Laps map[int]map[string]Summary
+}
+
+// StubResult summarises the variables of a single stub
+type StubResult struct {
+	Filename string
+	Vars     map[string]Summary
+}
+
+// Processor computes streaming statistics of telemetry variables.
+type Processor struct {
+	opts Options
+	vars map[string]struct{}
+
+	group accumulators
+	stubs []stubAccumulators
+	laps  map[int]accumulators
+
+	// names of the elements of array variables along with their histogram ranges, to avoid
+	// formatting them for every tick
+	elements map[string][]element
+}
+
+// accumulators of each variable
+type accumulators map[string]*accumulator
+
+type stubAccumulators struct {
+	filename string
+	vars     accumulators
+}
+
+// element is a single value of a variable
+type element struct {
+	name      string
+	histRange *Range
+}
+
+// New creates a stats Processor with the given options.
+func New(opts Options) *Processor {
+	if opts.Bins <= 0 {
+		opts.Bins = defaultBins
+	}
+	if len(opts.Percentiles) == 0 {
+		opts.Percentiles = defaultPercentiles
+	}
+
+	p := &Processor{
+		opts:     opts,
+		group:    make(accumulators),
+		laps:     make(map[int]accumulators),
+		elements: make(map[string][]element),
+	}
+
+	if len(opts.Vars) > 0 {
+		p.vars = make(map[string]struct{}, len(opts.Vars))
+		for _, v := range opts.Vars {
+			p.vars[v] = struct{}{}
+		}
+	}
+
+	return p
+}
+
+// Whitelist of the configured variables, along with the lap
+func (p *Processor) Whitelist() []string {
+	if len(p.opts.Vars) == 0 {
+		return nil
+	}
+
+	return append(append([]string{}, p.opts.Vars...), lapVar)
+}
+
+// StartStub starts the statistics of a new stub
+func (p *Processor) StartStub(stub ibt.StubInfo) error {
+	p.stubs = append(p.stubs, stubAccumulators{filename: stub.Filename, vars: make(accumulators)})
+
+	return nil
+}
+
+// Process adds the numeric values of the tick to the statistics of its lap, stub and group
+func (p *Processor) Process(input ibt.Tick, hasNext bool, session *headers.Session) error {
+	// Processors used outside of ibt.Process may not be notified of the start of a stub
+	if len(p.stubs) == 0 {
+		p.stubs = append(p.stubs, stubAccumulators{vars: make(accumulators)})
+	}
+	stub := p.stubs[len(p.stubs)-1].vars
+
+	var lap accumulators
+	if lapNum, err := ibt.GetTickValue[int](input, lapVar); err == nil {
+		if lap = p.laps[lapNum]; lap == nil {
+			lap = make(accumulators)
+			p.laps[lapNum] = lap
+		}
+	}
+
+	for name, value := range input {
+		if p.vars != nil {
+			if _, ok := p.vars[name]; !ok {
+				continue
+			}
+		}
+
+		switch v := value.(type) {
+		case float32:
+			p.add(p.element(name, -1), float64(v), stub, lap)
+		case float64:
+			p.add(p.element(name, -1), v, stub, lap)
+		case int:
+			p.add(p.element(name, -1), float64(v), stub, lap)
+		case []float32:
+			for idx, x := range v {
+				p.add(p.element(name, idx), float64(x), stub, lap)
+			}
+		case []float64:
+			for idx, x := range v {
+				p.add(p.element(name, idx), x, stub, lap)
+			}
+		case []int:
+			for idx, x := range v {
+				p.add(p.element(name, idx), float64(x), stub, lap)
+			}
+		}
+	}
+
+	return nil
+}
+
+// add the value to the statistics of the group, stub and lap
+func (p *Processor) add(e element, value float64, stub, lap accumulators) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+
+	p.accumulator(p.group, e).add(value)
+	p.accumulator(stub, e).add(value)
+	if lap != nil {
+		p.accumulator(lap, e).add(value)
+	}
+}
+
+// accumulator of the element, which is created if it does not exist yet
+func (p *Processor) accumulator(accs accumulators, e element) *accumulator {
+	acc, ok := accs[e.name]
+	if !ok {
+		acc = newAccumulator(p.opts, e.histRange)
+		accs[e.name] = acc
+	}
+
+	return acc
+}
+
+// element of the variable at the given index. An index of -1 refers to a variable that is not an array.
+func (p *Processor) element(name string, idx int) element {
+	elements := p.elements[name]
+
+	if idx < 0 {
+		if len(elements) == 0 {
+			elements = []element{{name: name, histRange: p.histRange(name, name)}}
+			p.elements[name] = elements
+		}
+		return elements[0]
+	}
+
+	for len(elements) <= idx {
+		elementName := fmt.Sprintf("%s[%d]", name, len(elements))
+		elements = append(elements, element{name: elementName, histRange: p.histRange(name, elementName)})
+	}
+	p.elements[name] = elements
+
+	return elements[idx]
+}
+
+// histRange configured for the element, falling back to the range of its variable
+func (p *Processor) histRange(name, elementName string) *Range {
+	if r, ok := p.opts.Ranges[elementName]; ok {
+		return &r
+	}
+	if r, ok := p.opts.Ranges[name]; ok {
+		return &r
+	}
+
+	return nil
+}
+
+// Result summarises the statistics of the ticks processed so far
+func (p *Processor) Result() Result {
+	result := Result{
+		Group: p.summarise(p.group),
+		Stubs: make([]StubResult, len(p.stubs)),
+		Laps:  make(map[int]map[string]Summary, len(p.laps)),
+	}
+
+	for idx, stub := range p.stubs {
+		result.Stubs[idx] = StubResult{Filename: stub.filename, Vars: p.summarise(stub.vars)}
+	}
+
+	for lap, accs := range p.laps {
+		result.Laps[lap] = p.summarise(accs)
+	}
+
+	return result
+}
+
+func (p *Processor) summarise(accs accumulators) map[string]Summary {
+	summaries := make(map[string]Summary, len(accs))
+
+	for name, acc := range accs {
+		summaries[name] = acc.summary(p.opts)
+	}
+
+	return summaries
+}
@@ -0,0 +1,100 @@
+package stats
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/teamjorge/ibt"
+)
+
+func TestProcessor(t *testing.T) {
+	t.Run("test Processor per lap and stub", func(t *testing.T) {
+		proc := New(Options{Vars: []string{"Speed", "CarIdxRPM"}, Ranges: map[string]Range{"CarIdxRPM": {Min: 0, Max: 10000}}})
+
+		ticks := []ibt.Tick{
+			{"Lap": 1, "Speed": float32(10), "CarIdxRPM": []float32{1000, 2000}, "Gear": 2},
+			{"Lap": 1, "Speed": float32(20), "CarIdxRPM": []float32{3000, 4000}, "Gear": 3},
+			{"Lap": 2, "Speed": float32(math.NaN()), "CarIdxRPM": []float32{5000, 6000}, "Gear": 3},
+			{"Lap": 2, "Speed": float32(30), "CarIdxRPM": []float32{7000, 8000}, "Gear": 4},
+		}
+
+		proc.StartStub(ibt.StubInfo{Filename: "first.ibt"})
+		for idx, tick := range ticks[:2] {
+			proc.Process(tick, idx < 1, nil)
+		}
+		proc.StartStub(ibt.StubInfo{Filename: "second.ibt"})
+		for idx, tick := range ticks[2:] {
+			proc.Process(tick, idx < 1, nil)
+		}
+
+		result := proc.Result()
+
+		if _, ok := result.Group["Gear"]; ok {
+			t.Error("expected variables that were not configured to be excluded")
+		}
+
+		if speed := result.Group["Speed"]; speed.Count != 3 || speed.Mean != 20 || speed.Min != 10 || speed.Max != 30 {
+			t.Errorf("expected group speed count 3, mean 20, min 10 and max 30. received %+v", speed)
+		}
+
+		if rpm := result.Group["CarIdxRPM[1]"]; rpm.Count != 4 || rpm.Mean != 5000 || rpm.Histogram.Counts[4] != 1 {
+			t.Errorf("expected array element statistics for CarIdxRPM[1] with the configured range. received %+v", rpm)
+		}
+
+		if len(result.Stubs) != 2 || result.Stubs[1].Filename != "second.ibt" || result.Stubs[1].Vars["Speed"].Count != 1 {
+			t.Errorf("expected statistics for each stub. received %+v", result.Stubs)
+		}
+
+		if len(result.Laps) != 2 || result.Laps[1]["Speed"].Mean != 15 || result.Laps[2]["CarIdxRPM[0]"].Max != 7000 {
+			t.Errorf("expected statistics for each lap. received %+v", result.Laps)
+		}
+	})
+
+	t.Run("test Processor Whitelist()", func(t *testing.T) {
+		if whitelist := New(Options{}).Whitelist(); whitelist != nil {
+			t.Errorf("expected all variables to be whitelisted when none are configured. received %v", whitelist)
+		}
+
+		whitelist := New(Options{Vars: []string{"Speed"}}).Whitelist()
+		if len(whitelist) != 2 || whitelist[0] != "Speed" || whitelist[1] != "Lap" {
+			t.Errorf("expected whitelist of %v. received %v", []string{"Speed", "Lap"}, whitelist)
+		}
+	})
+
+	t.Run("test Processor with ibt.Process", func(t *testing.T) {
+		stubs, err := ibt.ParseStubs("../.testing/valid_test_file.ibt")
+		if err != nil {
+			t.Errorf("failed to parse stubs for testing file - %v", err)
+			return
+		}
+		defer stubs.Close()
+
+		proc := New(Options{Vars: []string{"Speed", "RPM"}})
+		if err := ibt.Process(context.Background(), stubs, proc); err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		result := proc.Result()
+		speed := result.Group["Speed"]
+		if speed.Count != 389 || speed.Max < speed.Percentiles[95] || speed.Percentiles[5] < speed.Min {
+			t.Errorf("expected statistics for %d ticks with ordered percentiles. received %+v", 389, speed)
+		}
+
+		if len(result.Laps) != 1 || result.Laps[9]["RPM"].Count != 389 {
+			t.Errorf("expected statistics for lap 9. received %+v", result.Laps)
+		}
+	})
+
+	t.Run("test Processor from registry", func(t *testing.T) {
+		proc, err := ibt.DefaultRegistry.NewProcessor("stats", ibt.Params{"vars": []string{"Speed"}, "bins": 5})
+		if err != nil {
+			t.Errorf("expected NewProcessor() to run without err. received error: %v", err)
+			return
+		}
+
+		if opts := proc.(*Processor).opts; opts.Bins != 5 || opts.Vars[0] != "Speed" {
+			t.Errorf("expected params to configure the processor. received %+v", opts)
+		}
+	})
+}
@@ -0,0 +1,192 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// Default compression of a Sketch. Higher values are more accurate, but use more memory.
+	defaultCompression float64 = 100
+)
+
+// centroid is a cluster of values summarised by their mean and count
+type centroid struct {
+	mean  float64
+	count float64
+}
+
+// Sketch estimates quantiles of a stream of values using a merging t-digest.
+//
+// Values are summarised in a bounded number of centroids, which are smaller near the tails of the
+// distribution. This keeps the estimates of extreme percentiles accurate, while the memory used is
+// independent of the number of values added.
+type Sketch struct {
+	compression float64
+	centroids   []centroid
+	buffer      []centroid
+	count       float64
+	min, max    float64
+}
+
+// NewSketch creates a Sketch with the given compression.
+//
+// If compression is equal to or less than 0, a default of 100 will be used.
+func NewSketch(compression float64) *Sketch {
+	if compression <= 0 {
+		compression = defaultCompression
+	}
+
+	return &Sketch{
+		compression: compression,
+		buffer:      make([]centroid, 0, int(compression)*5),
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+// Add a value to the sketch
+func (s *Sketch) Add(value float64) {
+	s.buffer = append(s.buffer, centroid{mean: value, count: 1})
+	s.count++
+	s.min = math.Min(s.min, value)
+	s.max = math.Max(s.max, value)
+
+	if len(s.buffer) == cap(s.buffer) {
+		s.compress()
+	}
+}
+
+// Merge the values of the other sketch into this sketch
+func (s *Sketch) Merge(other *Sketch) {
+	if other.count == 0 {
+		return
+	}
+
+	s.buffer = append(s.buffer, other.centroids...)
+	s.buffer = append(s.buffer, other.buffer...)
+	s.count += other.count
+	s.min = math.Min(s.min, other.min)
+	s.max = math.Max(s.max, other.max)
+
+	s.compress()
+}
+
+// Count of the values added to the sketch
+func (s *Sketch) Count() int { return int(s.count) }
+
+// Quantile estimates the value at the given quantile, between 0 and 1.
+//
+// NaN is returned when no values were added.
+func (s *Sketch) Quantile(q float64) float64 {
+	s.compress()
+
+	if len(s.centroids) == 0 {
+		return math.NaN()
+	}
+	if q <= 0 {
+		return s.min
+	}
+	if q >= 1 {
+		return s.max
+	}
+
+	target := q * s.count
+	cumulative := 0.0
+
+	for idx, c := range s.centroids {
+		// The mean of a centroid is treated as the location of the middle of its values
+		mid := cumulative + c.count/2
+		if target < mid {
+			if idx == 0 {
+				return interpolate(s.min, c.mean, target/mid)
+			}
+			prev := s.centroids[idx-1]
+			prevMid := cumulative - prev.count/2
+			return interpolate(prev.mean, c.mean, (target-prevMid)/(mid-prevMid))
+		}
+		cumulative += c.count
+	}
+
+	last := s.centroids[len(s.centroids)-1]
+	lastMid := s.count - last.count/2
+
+	return interpolate(last.mean, s.max, (target-lastMid)/(s.count-lastMid))
+}
+
+// CDF estimates the fraction of values that are equal to or less than the given value.
+//
+// NaN is returned when no values were added.
+func (s *Sketch) CDF(value float64) float64 {
+	s.compress()
+
+	if len(s.centroids) == 0 {
+		return math.NaN()
+	}
+	if value < s.min {
+		return 0
+	}
+	if value >= s.max {
+		return 1
+	}
+
+	cumulative := 0.0
+	prevMean, prevMid := s.min, 0.0
+
+	for _, c := range s.centroids {
+		mid := cumulative + c.count/2
+		if value < c.mean {
+			return interpolate(prevMid, mid, (value-prevMean)/(c.mean-prevMean)) / s.count
+		}
+		cumulative += c.count
+		prevMean, prevMid = c.mean, mid
+	}
+
+	return interpolate(prevMid, s.count, (value-prevMean)/(s.max-prevMean)) / s.count
+}
+
+// compress merges the buffered values into the centroids of the sketch
+func (s *Sketch) compress() {
+	if len(s.buffer) == 0 {
+		return
+	}
+
+	all := append(s.buffer, s.centroids...)
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+
+	merged := make([]centroid, 0, int(s.compression))
+	current := all[0]
+	cumulative := 0.0
+	kLower := s.scale(0)
+
+	for _, c := range all[1:] {
+		// Centroids may only span a single unit of the scale function, which keeps them small near the tails
+		q := (cumulative + current.count + c.count) / s.count
+		if s.scale(q)-kLower <= 1 {
+			current.mean += (c.mean - current.mean) * c.count / (current.count + c.count)
+			current.count += c.count
+			continue
+		}
+
+		cumulative += current.count
+		merged = append(merged, current)
+		current = c
+		kLower = s.scale(cumulative / s.count)
+	}
+	merged = append(merged, current)
+
+	s.centroids = merged
+	s.buffer = s.buffer[:0]
+}
+
+// scale maps a quantile to the scale of the sketch, which changes more rapidly near the tails
+func (s *Sketch) scale(q float64) float64 {
+	return s.compression / (2 * math.Pi) * math.Asin(2*math.Min(q, 1)-1)
+}
+
+// interpolate linearly between a and b, where fraction is clamped between 0 and 1
+func interpolate(a, b, fraction float64) float64 {
+	fraction = math.Max(0, math.Min(1, fraction))
+
+	return a + (b-a)*fraction
+}
@@ -0,0 +1,106 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSketchQuantile(t *testing.T) {
+	t.Run("test Sketch Quantile() uniform values", func(t *testing.T) {
+		sketch := NewSketch(0)
+
+		// Add the values out of order to ensure they are sorted when compressed
+		for _, idx := range rand.New(rand.NewSource(1)).Perm(10000) {
+			sketch.Add(float64(idx + 1))
+		}
+
+		tests := []struct {
+			q        float64
+			expected float64
+			delta    float64
+		}{
+			{q: 0, expected: 1, delta: 0},
+			{q: 0.01, expected: 100, delta: 5},
+			{q: 0.5, expected: 5000, delta: 50},
+			{q: 0.99, expected: 9900, delta: 5},
+			{q: 1, expected: 10000, delta: 0},
+		}
+
+		for _, test := range tests {
+			if received := sketch.Quantile(test.q); math.Abs(received-test.expected) > test.delta {
+				t.Errorf("expected quantile %.2f to be %.1f (+/- %.1f). received %.3f", test.q, test.expected, test.delta, received)
+			}
+		}
+
+		if sketch.Count() != 10000 {
+			t.Errorf("expected sketch to contain %d values. received %d", 10000, sketch.Count())
+		}
+
+		if len(sketch.centroids) > 200 {
+			t.Errorf("expected sketch to be compressed to at most %d centroids. received %d", 200, len(sketch.centroids))
+		}
+	})
+
+	t.Run("test Sketch Quantile() single value", func(t *testing.T) {
+		sketch := NewSketch(0)
+		sketch.Add(42)
+
+		if received := sketch.Quantile(0.5); received != 42 {
+			t.Errorf("expected quantile of a single value to be %d. received %f", 42, received)
+		}
+	})
+
+	t.Run("test Sketch Quantile() empty", func(t *testing.T) {
+		if received := NewSketch(0).Quantile(0.5); !math.IsNaN(received) {
+			t.Errorf("expected quantile of an empty sketch to be NaN. received %f", received)
+		}
+	})
+}
+
+func TestSketchMerge(t *testing.T) {
+	a, b := NewSketch(0), NewSketch(0)
+
+	for i := 1; i <= 5000; i++ {
+		a.Add(float64(i))
+		b.Add(float64(i + 5000))
+	}
+
+	a.Merge(b)
+	a.Merge(NewSketch(0))
+
+	if a.Count() != 10000 {
+		t.Errorf("expected merged sketch to contain %d values. received %d", 10000, a.Count())
+	}
+
+	if received := a.Quantile(0.5); math.Abs(received-5000) > 50 {
+		t.Errorf("expected median of merged sketch to be %d. received %f", 5000, received)
+	}
+
+	if a.Quantile(1) != 10000 {
+		t.Errorf("expected maximum of merged sketch to be %d. received %f", 10000, a.Quantile(1))
+	}
+}
+
+func TestSketchCDF(t *testing.T) {
+	sketch := NewSketch(0)
+	for i := 1; i <= 1000; i++ {
+		sketch.Add(float64(i))
+	}
+
+	tests := []struct {
+		value    float64
+		expected float64
+	}{
+		{value: 0, expected: 0},
+		{value: 250, expected: 0.25},
+		{value: 500, expected: 0.5},
+		{value: 1000, expected: 1},
+	}
+
+	for _, test := range tests {
+		if received := sketch.CDF(test.value); math.Abs(received-test.expected) > 0.01 {
+			t.Errorf("expected CDF of %.0f to be %.2f. received %.3f", test.value, test.expected, received)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package stats
+
+import (
+	"math"
+)
+
+// Range of values covered by a Histogram
+type Range struct {
+	Min float64 `yaml:"min"`
+	Max float64 `yaml:"max"`
+}
+
+// Histogram counts values in bins of equal width.
+type Histogram struct {
+	// Range covered by the bins. Values outside the range are counted in the first and last bins.
+	Range Range
+	// Counts of values in each bin
+	Counts []int
+}
+
+// BinWidth is the width of each bin of the histogram
+func (h Histogram) BinWidth() float64 {
+	if len(h.Counts) == 0 {
+		return 0
+	}
+
+	return (h.Range.Max - h.Range.Min) / float64(len(h.Counts))
+}
+
+// Summary of the values of a single variable.
+type Summary struct {
+	Count  int
+	Min    float64
+	Max    float64
+	Mean   float64
+	StdDev float64
+	// Percentiles estimated by the streaming sketch, keyed by the configured percentile, such as 95.
+	Percentiles map[float64]float64
+	// Histogram of the values.
+	Histogram Histogram
+
+	sketch *Sketch
+}
+
+// Quantile estimates the value at the given quantile, between 0 and 1.
+func (s Summary) Quantile(q float64) float64 {
+	if s.sketch == nil {
+		return math.NaN()
+	}
+
+	return s.sketch.Quantile(q)
+}
+
+// accumulator computes the summary of a stream of values
+type accumulator struct {
+	count    int
+	min, max float64
+	// mean and m2 are updated with Welford's algorithm to remain numerically stable
+	mean, m2 float64
+	sketch   *Sketch
+	// counts of values for a fixed histogram range. Only used when a range was configured.
+	histRange *Range
+	counts    []int
+}
+
+func newAccumulator(opts Options, histRange *Range) *accumulator {
+	acc := &accumulator{
+		min:       math.Inf(1),
+		max:       math.Inf(-1),
+		sketch:    NewSketch(opts.Compression),
+		histRange: histRange,
+	}
+
+	if histRange != nil {
+		acc.counts = make([]int, opts.Bins)
+	}
+
+	return acc
+}
+
+// add a single value to the accumulator
+func (a *accumulator) add(value float64) {
+	a.count++
+	a.min = math.Min(a.min, value)
+	a.max = math.Max(a.max, value)
+
+	delta := value - a.mean
+	a.mean += delta / float64(a.count)
+	a.m2 += delta * (value - a.mean)
+
+	a.sketch.Add(value)
+
+	if a.histRange != nil {
+		a.counts[bin(value, *a.histRange, len(a.counts))]++
+	}
+}
+
+// summary of the values added to the accumulator
+func (a *accumulator) summary(opts Options) Summary {
+	s := Summary{Count: a.count, Percentiles: make(map[float64]float64, len(opts.Percentiles)), sketch: a.sketch}
+	if a.count == 0 {
+		return s
+	}
+
+	s.Min, s.Max, s.Mean = a.min, a.max, a.mean
+	if a.count > 1 {
+		s.StdDev = math.Sqrt(a.m2 / float64(a.count-1))
+	}
+
+	for _, p := range opts.Percentiles {
+		s.Percentiles[p] = a.sketch.Quantile(p / 100)
+	}
+
+	if a.histRange != nil {
+		s.Histogram = Histogram{Range: *a.histRange, Counts: append([]int{}, a.counts...)}
+	} else {
+		s.Histogram = a.sketchHistogram(opts.Bins)
+	}
+
+	return s
+}
+
+// sketchHistogram estimates a histogram over the observed range of values from the sketch
+func (a *accumulator) sketchHistogram(bins int) Histogram {
+	h := Histogram{Range: Range{Min: a.min, Max: a.max}, Counts: make([]int, bins)}
+
+	if a.min == a.max {
+		h.Counts[0] = a.count
+		return h
+	}
+
+	width := h.BinWidth()
+	prev := 0
+	for idx := range h.Counts {
+		upper := a.count
+		if idx < bins-1 {
+			upper = int(math.Round(a.sketch.CDF(a.min+width*float64(idx+1)) * float64(a.count)))
+		}
+		h.Counts[idx] = max(upper-prev, 0)
+		prev = max(upper, prev)
+	}
+
+	return h
+}
+
+// bin is the index of the histogram bin for the given value
+func bin(value float64, r Range, bins int) int {
+	if r.Max <= r.Min {
+		return 0
+	}
+
+	idx := int((value - r.Min) / (r.Max - r.Min) * float64(bins))
+
+	return max(0, min(bins-1, idx))
+}
@@ -0,0 +1,78 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAccumulatorSummary(t *testing.T) {
+	opts := Options{Bins: 4, Percentiles: []float64{50}}
+
+	t.Run("test summary moments", func(t *testing.T) {
+		acc := newAccumulator(opts, nil)
+		for _, v := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+			acc.add(v)
+		}
+
+		summary := acc.summary(opts)
+
+		if summary.Count != 8 || summary.Min != 2 || summary.Max != 9 || summary.Mean != 5 {
+			t.Errorf("expected count 8, min 2, max 9 and mean 5. received %+v", summary)
+		}
+
+		if math.Abs(summary.StdDev-2.13809) > 0.0001 {
+			t.Errorf("expected sample standard deviation of %.5f. received %.5f", 2.13809, summary.StdDev)
+		}
+
+		if summary.Percentiles[50] < 4 || summary.Percentiles[50] > 5 {
+			t.Errorf("expected median between 4 and 5. received %f", summary.Percentiles[50])
+		}
+
+		total := 0
+		for _, count := range summary.Histogram.Counts {
+			total += count
+		}
+		if total != 8 || summary.Histogram.Range != (Range{Min: 2, Max: 9}) {
+			t.Errorf("expected estimated histogram over the observed range to contain all values. received %+v", summary.Histogram)
+		}
+	})
+
+	t.Run("test summary fixed histogram", func(t *testing.T) {
+		acc := newAccumulator(opts, &Range{Min: 0, Max: 100})
+		for _, v := range []float64{-10, 10, 30, 55, 60, 99, 100, 150} {
+			acc.add(v)
+		}
+
+		histogram := acc.summary(opts).Histogram
+		expected := []int{2, 1, 2, 3}
+
+		for idx, count := range expected {
+			if histogram.Counts[idx] != count {
+				t.Errorf("expected histogram counts %v. received %v", expected, histogram.Counts)
+				break
+			}
+		}
+
+		if histogram.BinWidth() != 25 {
+			t.Errorf("expected bin width of %d. received %f", 25, histogram.BinWidth())
+		}
+	})
+
+	t.Run("test summary constant values", func(t *testing.T) {
+		acc := newAccumulator(opts, nil)
+		acc.add(3)
+		acc.add(3)
+
+		summary := acc.summary(opts)
+		if summary.StdDev != 0 || summary.Histogram.Counts[0] != 2 {
+			t.Errorf("expected no deviation and all values in the first bin. received %+v", summary)
+		}
+	})
+
+	t.Run("test summary empty", func(t *testing.T) {
+		summary := newAccumulator(opts, nil).summary(opts)
+		if summary.Count != 0 || summary.Histogram.Counts != nil {
+			t.Errorf("expected an empty summary. received %+v", summary)
+		}
+	})
+}
@@ -0,0 +1,409 @@
+// Package stints splits telemetry into stints and the pit stops between them.
+//
+// A Detector starts a stint whenever the car leaves pit road and ends it when the car enters pit road again.
+// Each PitStop reports the time spent in the pit lane and in the pit stall, the service requested and the
+// fuel added. Each Stint reports its laps, as segmented by the laps package, along with its average pace
+// and how much the lap time degraded over the stint.
+package stints
+
+import (
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/laps"
+)
+
+// Service flags of PitSvFlags
+const (
+	LFTireChange uint32 = 0x01
+	RFTireChange uint32 = 0x02
+	LRTireChange uint32 = 0x04
+	RRTireChange uint32 = 0x08
+	FuelFill     uint32 = 0x10
+	Tearoff      uint32 = 0x20
+	FastRepair   uint32 = 0x40
+)
+
+func init() {
+	ibt.Register("stints", func(params ibt.Params) (ibt.Processor, error) {
+		var opts laps.Options
+		if err := params.Decode(&opts); err != nil {
+			return nil, err
+		}
+
+		return NewDetector(opts), nil
+	})
+}
+
+// Service requested for a pit stop.
+type Service struct {
+	// Flags are the PitSvFlags of the service
+	Flags uint32
+	// Fuel in liters requested, as reported by PitSvFuel
+	Fuel float64
+	// Pressures in kPa requested for the left front, right front, left rear and right rear tyres
+	Pressures [4]float64
+	// Compound of the tyres requested, as reported by PitSvTireCompound
+	Compound int
+}
+
+// Has indicates whether the given service flag was requested
+func (s Service) Has(flag uint32) bool { return s.Flags&flag != 0 }
+
+// Tyres is the number of tyres requested to be changed
+func (s Service) Tyres() int {
+	count := 0
+	for _, flag := range []uint32{LFTireChange, RFTireChange, LRTireChange, RRTireChange} {
+		if s.Has(flag) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// PitStop is a single visit to pit road.
+type PitStop struct {
+	// Number of the pit stop, starting at 1
+	Number int
+	// Stub is the index of the stub within the group being processed in which the car entered pit road
+	Stub int
+	// Filename of the stub
+	Filename string
+	// Lap during which the car entered pit road
+	Lap int
+	// EntryTime and ExitTime are the session times at which the car entered and left pit road
+	EntryTime float64
+	ExitTime  float64
+	// PitLaneTime is the number of seconds spent on pit road
+	PitLaneTime float64
+	// StationaryTime is the number of seconds spent in the pit stall
+	StationaryTime float64
+	// Service requested when the car arrived in the pit stall, or when it entered pit road when it did not stop
+	Service Service
+	// FuelAdded in liters while in the pit stall
+	FuelAdded float64
+	// Compound of the tyres when leaving pit road, as reported by PlayerTireCompound
+	Compound int
+	// Complete indicates that both the entry and exit of pit road were driven in the same stub
+	Complete bool
+}
+
+// Stint is the time spent on track between pit stops.
+type Stint struct {
+	// Number of the stint, starting at 1
+	Number int
+	// Stub is the index of the stub within the group being processed in which the stint started
+	Stub int
+	// Filename of the stub
+	Filename string
+	// StartTime and EndTime are the session times at which the stint started and ended
+	StartTime float64
+	EndTime   float64
+	// Laps of the stint, including out and in laps
+	Laps []laps.Lap
+	// ValidLaps is the number of valid laps of the stint
+	ValidLaps int
+	// Pace is the average time of the valid laps
+	Pace float64
+	// Degradation is the increase in lap time in seconds per lap over the valid laps of the stint. This is 0
+	// when fewer than two valid laps were driven.
+	Degradation float64
+	// Compound of the tyres at the start of the stint, as reported by PlayerTireCompound
+	Compound int
+
+	// start and end of the stint, used for matching laps across stubs and sub-sessions
+	start, end position
+}
+
+// position of a tick within the group being processed. The session time restarts with every stub and
+// sub-session, so positions are ordered by stub and SessionNum before the session time.
+type position struct {
+	stub        int
+	sessionNum  int
+	sessionTime float64
+}
+
+// before indicates whether the position is earlier than the given position
+func (p position) before(other position) bool {
+	if p.stub != other.stub {
+		return p.stub < other.stub
+	}
+	if p.sessionNum != other.sessionNum {
+		return p.sessionNum < other.sessionNum
+	}
+
+	return p.sessionTime < other.sessionTime
+}
+
+// Result of the stints and pit stops detected by a Detector.
+type Result struct {
+	Stints   []Stint
+	PitStops []PitStop
+}
+
+// Detector is a processor that detects stints and pit stops.
+type Detector struct {
+	segmenter *laps.Segmenter
+	stints    []Stint
+	stops     []PitStop
+
+	stub    ibt.StubInfo
+	stint   *Stint
+	stop    *PitStop
+	prev    pitTick
+	hasPrev bool
+	// fuel level in liters when the car arrived in the pit stall
+	stallFuel float64
+}
+
+// pitTick is the part of a tick used for detecting stints
+type pitTick struct {
+	lap         int
+	sessionTime float64
+	sessionNum  int
+	onPitRoad   bool
+	inPitStall  bool
+	fuel        float64
+	compound    int
+}
+
+// NewDetector creates a stint Detector, segmenting laps with the given options.
+func NewDetector(opts laps.Options) *Detector {
+	return &Detector{segmenter: laps.NewSegmenter(opts)}
+}
+
+// Whitelist of the variables required for detecting stints
+func (d *Detector) Whitelist() []string {
+	return append(d.segmenter.Whitelist(),
+		"PlayerCarInPitStall", "FuelLevel", "PlayerTireCompound",
+		"PitSvFlags", "PitSvFuel", "PitSvLFP", "PitSvRFP", "PitSvLRP", "PitSvRRP", "PitSvTireCompound",
+	)
+}
+
+// StartStub notifies the lap segmentation of the new stub. Stints and pit stops continue across stubs.
+func (d *Detector) StartStub(stub ibt.StubInfo) error {
+	// Time in the pit stall is not counted between stubs, so a stall visit ends with the previous stub
+	if d.stop != nil && d.prev.inPitStall {
+		d.stop.FuelAdded += d.prev.fuel - d.stallFuel
+		d.prev.inPitStall = false
+	}
+	d.stub = stub
+
+	return d.segmenter.StartStub(stub)
+}
+
+// EndStub notifies the lap segmentation of the end of the stub
+func (d *Detector) EndStub(stub ibt.StubInfo) error {
+	return d.segmenter.EndStub(stub)
+}
+
+// Process the tick without a TickContext. Stints and pit stops are timed with the SessionTime of the tick.
+func (d *Detector) Process(input ibt.Tick, hasNext bool, session *headers.Session) error {
+	return d.ProcessContext(input, ibt.ContextFromTick(input), hasNext, session)
+}
+
+// ProcessContext starts and ends stints and pit stops when the car enters or leaves pit road
+func (d *Detector) ProcessContext(input ibt.Tick, tickCtx ibt.TickContext, hasNext bool, session *headers.Session) error {
+	if err := d.segmenter.ProcessContext(input, tickCtx, hasNext, session); err != nil {
+		return err
+	}
+
+	onPitRoad, err := ibt.GetTickValue[bool](input, "OnPitRoad")
+	if err != nil {
+		return err
+	}
+
+	// The stall, fuel and tyres are only used to describe pit stops
+	lap, _ := ibt.GetTickValue[int](input, "Lap")
+	sessionNum, _ := ibt.GetTickValue[int](input, "SessionNum")
+	inPitStall, _ := ibt.GetTickValue[bool](input, "PlayerCarInPitStall")
+	fuel, _ := ibt.GetTickValue[float32](input, "FuelLevel")
+	compound, _ := ibt.GetTickValue[int](input, "PlayerTireCompound")
+
+	tick := pitTick{
+		lap:         lap,
+		sessionTime: tickCtx.SessionTime,
+		sessionNum:  sessionNum,
+		onPitRoad:   onPitRoad,
+		inPitStall:  inPitStall,
+		fuel:        float64(fuel),
+		compound:    compound,
+	}
+
+	if d.stop != nil {
+		d.updateStop(tick, input)
+	}
+
+	switch {
+	case !d.hasPrev && !tick.onPitRoad:
+		d.startStint(tick)
+	case d.hasPrev && !d.prev.onPitRoad && tick.onPitRoad:
+		d.endStint(tick)
+		d.startStop(tick, input)
+	case d.hasPrev && d.prev.onPitRoad && !tick.onPitRoad:
+		d.endStop(tick)
+		d.startStint(tick)
+	}
+
+	d.prev = tick
+	d.hasPrev = true
+
+	return nil
+}
+
+// startStint starts a new stint at the given tick
+func (d *Detector) startStint(tick pitTick) {
+	d.stint = &Stint{
+		Number:    len(d.stints) + 1,
+		Stub:      d.stub.Index,
+		Filename:  d.stub.Filename,
+		StartTime: tick.sessionTime,
+		Compound:  tick.compound,
+		start:     d.position(tick),
+	}
+}
+
+// endStint ends the current stint at the given tick
+func (d *Detector) endStint(tick pitTick) {
+	if d.stint != nil {
+		d.stint.EndTime = tick.sessionTime
+		d.stint.end = d.position(tick)
+		d.stints = append(d.stints, *d.stint)
+		d.stint = nil
+	}
+}
+
+// startStop starts a new pit stop at the given tick
+func (d *Detector) startStop(tick pitTick, input ibt.Tick) {
+	d.stop = &PitStop{
+		Number:    len(d.stops) + 1,
+		Stub:      d.stub.Index,
+		Filename:  d.stub.Filename,
+		Lap:       tick.lap,
+		EntryTime: tick.sessionTime,
+		Service:   service(input),
+	}
+}
+
+// updateStop updates the pit stop in progress with the time spent in the pit stall
+func (d *Detector) updateStop(tick pitTick, input ibt.Tick) {
+	stalled := d.hasPrev && d.prev.inPitStall
+
+	switch {
+	case tick.inPitStall && !stalled:
+		// The service is read on arrival, as it may be cleared once it was performed
+		d.stop.Service = service(input)
+		d.stallFuel = tick.fuel
+	case stalled:
+		d.stop.StationaryTime += tick.sessionTime - d.prev.sessionTime
+		if !tick.inPitStall {
+			d.stop.FuelAdded += tick.fuel - d.stallFuel
+		}
+	}
+}
+
+// position of the given tick within the current stub
+func (d *Detector) position(tick pitTick) position {
+	return position{stub: d.stub.Index, sessionNum: tick.sessionNum, sessionTime: tick.sessionTime}
+}
+
+// endStop completes the current pit stop at the given tick
+func (d *Detector) endStop(tick pitTick) {
+	if d.stop == nil {
+		return
+	}
+
+	d.stop.ExitTime = tick.sessionTime
+	d.stop.PitLaneTime = d.stop.ExitTime - d.stop.EntryTime
+	d.stop.Compound = tick.compound
+	d.stop.Complete = d.stop.Stub == d.stub.Index
+	d.stops = append(d.stops, *d.stop)
+	d.stop = nil
+}
+
+// Current returns the stint in progress, without its laps. False is returned when the car is on pit road.
+func (d *Detector) Current() (Stint, bool) {
+	if d.stint == nil {
+		return Stint{}, false
+	}
+
+	return *d.stint, true
+}
+
+// service requested in the given tick
+func service(input ibt.Tick) Service {
+	var s Service
+
+	s.Flags, _ = ibt.GetTickBitField(input, "PitSvFlags")
+	s.Compound, _ = ibt.GetTickValue[int](input, "PitSvTireCompound")
+
+	fuel, _ := ibt.GetTickValue[float32](input, "PitSvFuel")
+	s.Fuel = float64(fuel)
+
+	for idx, name := range []string{"PitSvLFP", "PitSvRFP", "PitSvLRP", "PitSvRRP"} {
+		pressure, _ := ibt.GetTickValue[float32](input, name)
+		s.Pressures[idx] = float64(pressure)
+	}
+
+	return s
+}
+
+// Result of the stints and pit stops detected so far. A stint in progress is ended at the latest tick.
+func (d *Detector) Result() Result {
+	result := Result{Stints: append([]Stint(nil), d.stints...), PitStops: d.stops}
+
+	if d.stint != nil {
+		stint := *d.stint
+		stint.EndTime = d.prev.sessionTime
+		stint.end = d.position(d.prev)
+		result.Stints = append(result.Stints, stint)
+	}
+
+	segmented := d.segmenter.Result()
+
+	for idx := range result.Stints {
+		stint := &result.Stints[idx]
+
+		// Laps belong to the stint during which most of the lap was driven
+		for _, lap := range segmented {
+			mid := position{stub: lap.Stub, sessionNum: lap.SessionNum, sessionTime: (lap.StartTime + lap.EndTime) / 2}
+			if !mid.before(stint.start) && !stint.end.before(mid) {
+				stint.Laps = append(stint.Laps, lap)
+			}
+		}
+
+		stint.ValidLaps, stint.Pace, stint.Degradation = pace(stint.Laps)
+	}
+
+	return result
+}
+
+// pace returns the number of valid laps, their average time and the slope of their times over the laps
+func pace(stintLaps []laps.Lap) (int, float64, float64) {
+	var n, sumX, sumY, sumXY, sumXX float64
+
+	for _, lap := range stintLaps {
+		if !lap.Valid {
+			continue
+		}
+
+		x, y := float64(lap.Number), float64(lap.Time)
+		n++
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	if n == 0 {
+		return 0, 0, 0
+	}
+
+	// The degradation is the slope of a least squares fit of the lap times
+	slope := 0.0
+	if denominator := n*sumXX - sumX*sumX; n > 1 && denominator != 0 {
+		slope = (n*sumXY - sumX*sumY) / denominator
+	}
+
+	return int(n), sumY / n, slope
+}
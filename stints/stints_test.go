@@ -0,0 +1,280 @@
+package stints
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/ibttest"
+	"github.com/teamjorge/ibt/laps"
+)
+
+// testLapTime returns the lap time in seconds of the given lap when driven without stopping
+func testLapTime(lap int) float64 {
+	switch lap {
+	case 3:
+		return 60.5
+	case 4:
+		return 61
+	case 7:
+		return 59
+	case 8:
+		return 59.5
+	}
+
+	return 60
+}
+
+// testTicks creates the ticks from 0.9 of lap 1 until the middle of lap 9. The car enters pit road at 0.9 of
+// lap 5 and stops in the pit stall at 0.98 for 10 seconds, during which 10 liters of fuel and new tyres of
+// compound 1 are fitted. The car leaves pit road at 0.05 of lap 6.
+func testTicks() []ibt.Tick {
+	ticks := make([]ibt.Tick, 0)
+	pos, fuel, compound := 0.9, 40.0, 0
+	stalled, stopped := 0, false
+
+	for idx := 0; ; idx++ {
+		lap, pct := 1+int(math.Floor(pos)), pos-math.Floor(pos)
+		if lap == 9 && pct >= 0.5 {
+			break
+		}
+
+		inPitStall := lap == 5 && pct >= 0.98 && !stopped
+		flags := "0x1f"
+		if stopped {
+			flags = "0x0"
+		}
+
+		ticks = append(ticks, ibt.Tick{
+			"SessionTime":         float64(idx) / 60,
+			"Lap":                 lap,
+			"LapDistPct":          float32(pct),
+			"OnPitRoad":           (lap == 5 && pct >= 0.9) || (lap == 6 && pct < 0.05),
+			"PlayerCarInPitStall": inPitStall,
+			"FuelLevel":           float32(fuel),
+			"PlayerTireCompound":  compound,
+			"PitSvFlags":          flags,
+			"PitSvFuel":           float32(10),
+			"PitSvLFP":            float32(170),
+			"PitSvRFP":            float32(170),
+			"PitSvLRP":            float32(165),
+			"PitSvRRP":            float32(165),
+			"PitSvTireCompound":   1,
+		})
+
+		if inPitStall {
+			stalled++
+			fuel += 1.0 / 60
+			if stalled == 600 {
+				stopped, compound = true, 1
+			}
+			continue
+		}
+
+		pos += 1 / (60 * testLapTime(lap))
+		fuel -= 0.001
+	}
+
+	return ticks
+}
+
+var testStub = ibt.StubInfo{Index: 0, Filename: "test.ibt"}
+
+func TestDetector(t *testing.T) {
+	t.Run("test Detector pit stops", func(t *testing.T) {
+		detector := NewDetector(laps.Options{})
+		ibttest.Process(t, detector, testStub, testTicks(), nil)
+
+		stops := detector.Result().PitStops
+		if len(stops) != 1 {
+			t.Errorf("expected %d pit stop. received %d", 1, len(stops))
+			return
+		}
+
+		stop := stops[0]
+		if stop.Number != 1 || stop.Lap != 5 || !stop.Complete || stop.Filename != "test.ibt" {
+			t.Errorf("expected a complete pit stop on lap %d of test.ibt. received %+v", 5, stop)
+		}
+		if math.Abs(stop.PitLaneTime-19) > 0.05 {
+			t.Errorf("expected a pit lane time of %d. received %f", 19, stop.PitLaneTime)
+		}
+		if math.Abs(stop.StationaryTime-10) > 0.001 {
+			t.Errorf("expected a stationary time of %d. received %f", 10, stop.StationaryTime)
+		}
+		if math.Abs(stop.FuelAdded-10) > 0.01 {
+			t.Errorf("expected %d liters of fuel added. received %f", 10, stop.FuelAdded)
+		}
+		if stop.Compound != 1 {
+			t.Errorf("expected compound %d after the stop. received %d", 1, stop.Compound)
+		}
+
+		service := stop.Service
+		if service.Tyres() != 4 || !service.Has(FuelFill) || service.Has(FastRepair) || service.Fuel != 10 || service.Compound != 1 {
+			t.Errorf("expected a service of 4 tyres and 10 liters of fuel. received %+v", service)
+		}
+		if service.Pressures != [4]float64{170, 170, 165, 165} {
+			t.Errorf("expected the requested pressures. received %v", service.Pressures)
+		}
+	})
+
+	t.Run("test Detector stints", func(t *testing.T) {
+		detector := NewDetector(laps.Options{})
+		ibttest.Process(t, detector, testStub, testTicks(), nil)
+
+		stints := detector.Result().Stints
+		if len(stints) != 2 {
+			t.Errorf("expected %d stints. received %d", 2, len(stints))
+			return
+		}
+
+		expected := []struct {
+			laps      []int
+			validLaps int
+			pace      float64
+			compound  int
+		}{
+			{[]int{1, 2, 3, 4, 5}, 3, 60.5, 0},
+			{[]int{6, 7, 8, 9}, 2, 59.25, 1},
+		}
+
+		for idx, stint := range stints {
+			want := expected[idx]
+
+			if stint.Number != idx+1 || len(stint.Laps) != len(want.laps) {
+				t.Errorf("expected stint %d with %d laps. received stint %d with %d laps", idx+1, len(want.laps), stint.Number, len(stint.Laps))
+				continue
+			}
+			for lapIdx, lap := range stint.Laps {
+				if lap.Number != want.laps[lapIdx] {
+					t.Errorf("expected lap %d in stint %d. received lap %d", want.laps[lapIdx], idx+1, lap.Number)
+				}
+			}
+
+			if stint.ValidLaps != want.validLaps || math.Abs(stint.Pace-want.pace) > 0.01 {
+				t.Errorf("expected %d valid laps at a pace of %.2f in stint %d. received %d at %f", want.validLaps, want.pace, idx+1, stint.ValidLaps, stint.Pace)
+			}
+			if math.Abs(stint.Degradation-0.5) > 0.01 {
+				t.Errorf("expected a degradation of %.1f in stint %d. received %f", 0.5, idx+1, stint.Degradation)
+			}
+			if stint.Compound != want.compound {
+				t.Errorf("expected compound %d in stint %d. received %d", want.compound, idx+1, stint.Compound)
+			}
+		}
+
+		if stints[0].EndTime >= stints[1].StartTime {
+			t.Errorf("expected the first stint to end before the second started. received %f and %f", stints[0].EndTime, stints[1].StartTime)
+		}
+	})
+
+	t.Run("test Detector across stubs", func(t *testing.T) {
+		ticks := testTicks()
+		split := 0
+		for idx, tick := range ticks {
+			if tick["PlayerCarInPitStall"].(bool) {
+				split = idx + 300
+				break
+			}
+		}
+
+		detector := NewDetector(laps.Options{})
+		for stubIdx, part := range [][]ibt.Tick{ticks[:split], ticks[split:]} {
+			ibttest.Process(t, detector, ibt.StubInfo{Index: stubIdx, Filename: "test.ibt"}, part, nil)
+		}
+
+		result := detector.Result()
+		if len(result.Stints) != 2 || len(result.PitStops) != 1 {
+			t.Errorf("expected %d stints and %d pit stop. received %d and %d", 2, 1, len(result.Stints), len(result.PitStops))
+			return
+		}
+
+		stop := result.PitStops[0]
+		if stop.Complete || math.Abs(stop.FuelAdded-10) > 0.05 || stop.Service.Tyres() != 4 {
+			t.Errorf("expected an incomplete pit stop adding %d liters. received %+v", 10, stop)
+		}
+		if result.Stints[1].Stub != 1 {
+			t.Errorf("expected the second stint to start in stub %d. received %d", 1, result.Stints[1].Stub)
+		}
+	})
+
+	t.Run("test Detector laps across stubs", func(t *testing.T) {
+		ticks := testTicks()
+		split := 0
+		for idx, tick := range ticks {
+			if tick["PlayerCarInPitStall"].(bool) {
+				split = idx + 300
+				break
+			}
+		}
+
+		// The session time restarts with the second stub
+		second := make([]ibt.Tick, 0, len(ticks)-split)
+		for idx, tick := range ticks[split:] {
+			copied := ibt.Tick{}
+			for k, v := range tick {
+				copied[k] = v
+			}
+			copied["SessionTime"] = float64(idx) / 60
+			second = append(second, copied)
+		}
+
+		detector := NewDetector(laps.Options{})
+		for stubIdx, part := range [][]ibt.Tick{ticks[:split], second} {
+			ibttest.Process(t, detector, ibt.StubInfo{Index: stubIdx, Filename: "test.ibt"}, part, nil)
+		}
+
+		stints := detector.Result().Stints
+		if len(stints) != 2 {
+			t.Errorf("expected %d stints. received %d", 2, len(stints))
+			return
+		}
+
+		for idx, want := range [][]int{{1, 2, 3, 4, 5}, {6, 7, 8, 9}} {
+			received := make([]int, 0)
+			for _, lap := range stints[idx].Laps {
+				received = append(received, lap.Number)
+			}
+			if len(received) != len(want) || received[0] != want[0] || received[len(received)-1] != want[len(want)-1] {
+				t.Errorf("expected laps %v in stint %d. received %v", want, idx+1, received)
+			}
+		}
+	})
+
+	t.Run("test Detector missing variables", func(t *testing.T) {
+		if err := NewDetector(laps.Options{}).Process(ibt.Tick{"Lap": 1, "LapDistPct": float32(0.1)}, true, nil); err == nil {
+			t.Error("expected Process() to return an error when OnPitRoad is missing")
+		}
+	})
+
+	t.Run("test Detector from registry", func(t *testing.T) {
+		processor, err := ibt.DefaultRegistry.NewProcessor("stints", ibt.Params{"min_coverage": 0.9})
+		if err != nil {
+			t.Errorf("expected NewProcessor() to run without err. received error: %v", err)
+			return
+		}
+
+		if _, ok := processor.(*Detector); !ok {
+			t.Errorf("expected a *Detector. received %T", processor)
+		}
+	})
+
+	t.Run("test Detector with ibt.Process", func(t *testing.T) {
+		stubs, err := ibt.ParseStubs("../.testing/valid_test_file.ibt")
+		if err != nil {
+			t.Errorf("failed to parse stubs for testing file - %v", err)
+			return
+		}
+		defer stubs.Close()
+
+		detector := NewDetector(laps.Options{})
+		if err := ibt.Process(context.Background(), stubs, detector); err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		// The car remains on pit road throughout the testing file, so it neither starts a stint nor a pit stop
+		result := detector.Result()
+		if len(result.Stints) != 0 || len(result.PitStops) != 0 {
+			t.Errorf("expected no stints or pit stops. received %+v", result)
+		}
+	})
+}
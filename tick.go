@@ -3,6 +3,8 @@ package ibt
 import (
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
 // Tick is a single instance of telemetry data
@@ -44,3 +46,98 @@ func GetTickValue[T TickValueType](tick Tick, key string) (T, error) {
 
 	return value, nil
 }
+
+// GetTickBitField will retrieve the given bitfield variable, such as SessionFlags, as its flags.
+//
+// Bitfields are parsed as hexadecimal strings, such as "0x10004".
+func GetTickBitField(tick Tick, key string) (uint32, error) {
+	value, err := GetTickValue[string](tick, key)
+	if err != nil {
+		return 0, err
+	}
+
+	flags, err := strconv.ParseUint(strings.TrimPrefix(value, "0x"), 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("value of %s was not a bitfield: %v", key, err)
+	}
+
+	return uint32(flags), nil
+}
+
+// Copy the tick into a new Tick.
+//
+// Array values are shared with the original tick.
+func (t Tick) Copy() Tick {
+	copied := make(Tick, len(t))
+
+	for field, val := range t {
+		copied[field] = val
+	}
+
+	return copied
+}
+
+// MeanTicks combines the given ticks into a single tick by averaging their values.
+//
+// Only floating point values (including arrays) are averaged. Other values, such as the current
+// lap or gear, are taken from the last tick. A nil Tick will be returned if no ticks are given.
+func MeanTicks(ticks []Tick) Tick {
+	if len(ticks) == 0 {
+		return nil
+	}
+
+	last := ticks[len(ticks)-1]
+	result := make(Tick, len(last))
+
+	for field, val := range last {
+		switch v := val.(type) {
+		case float32:
+			result[field] = meanValue(ticks, field, v, func(a, b float32) float32 { return a + b },
+				func(sum float32, n int) float32 { return sum / float32(n) })
+		case float64:
+			result[field] = meanValue(ticks, field, v, func(a, b float64) float64 { return a + b },
+				func(sum float64, n int) float64 { return sum / float64(n) })
+		case []float32:
+			result[field] = meanValue(ticks, field, append([]float32{}, v...), addSlices[float32],
+				func(sum []float32, n int) []float32 { return divideSlice(sum, float32(n)) })
+		case []float64:
+			result[field] = meanValue(ticks, field, append([]float64{}, v...), addSlices[float64],
+				func(sum []float64, n int) []float64 { return divideSlice(sum, float64(n)) })
+		default:
+			result[field] = val
+		}
+	}
+
+	return result
+}
+
+// meanValue sums the values of the field in all ticks with the given sum function, starting from the
+// value of the last tick, and averages it with mean.
+func meanValue[T any](ticks []Tick, field string, sum T, add func(T, T) T, mean func(T, int) T) T {
+	n := 1
+
+	for _, tick := range ticks[:len(ticks)-1] {
+		if v, ok := tick[field].(T); ok {
+			sum = add(sum, v)
+			n++
+		}
+	}
+
+	return mean(sum, n)
+}
+
+func addSlices[T float32 | float64](a, b []T) []T {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		a[i] += b[i]
+	}
+
+	return a
+}
+
+func divideSlice[T float32 | float64](a []T, n T) []T {
+	for i := range a {
+		a[i] /= n
+	}
+
+	return a
+}
@@ -0,0 +1,58 @@
+package ibt
+
+import (
+	"math"
+	"time"
+
+	"github.com/teamjorge/ibt/headers"
+)
+
+// TickContext describes where a tick is located within the stubs being processed.
+type TickContext struct {
+	// Index of the tick within the current stub
+	Index int
+	// GroupIndex is the index of the tick across all stubs being processed
+	GroupIndex int
+	// Stub the tick belongs to
+	Stub StubInfo
+	// SessionTime is the number of seconds since the start of the session
+	SessionTime float64
+	// Time is the absolute time of the tick, derived from the DiskHeader StartDate and StartTime
+	Time time.Time
+}
+
+// ContextProcessor is a Processor that receives the TickContext of every tick.
+//
+// Process will call ProcessContext instead of Process for processors implementing this interface.
+type ContextProcessor interface {
+	Processor
+	ProcessContext(input Tick, tickCtx TickContext, hasNext bool, session *headers.Session) error
+}
+
+// newTickContext creates the TickContext for the given tick.
+//
+// The session time is read from the SessionTime variable of the tick. If it is not available, it will
+// be estimated from the tick index and rate of the stub.
+func newTickContext(stub StubInfo, idx, groupIdx int, tick Tick) TickContext {
+	tickCtx := TickContext{Index: idx, GroupIndex: groupIdx, Stub: stub}
+
+	sessionTime, err := GetTickValue[float64](tick, sessionTimeVar)
+	if err != nil && stub.Header.TelemetryHeader.TickRate > 0 {
+		sessionTime = stub.Header.DiskHeader.StartTime + float64(idx)/float64(stub.Header.TelemetryHeader.TickRate)
+	}
+	tickCtx.SessionTime = sessionTime
+
+	offset := sessionTime - stub.Header.DiskHeader.StartTime
+	seconds, fraction := math.Modf(offset)
+	tickCtx.Time = time.Unix(stub.Header.DiskHeader.StartDate+int64(seconds), int64(fraction*float64(time.Second)))
+
+	return tickCtx
+}
+
+// ContextFromTick creates the TickContext of a tick that is processed without one, such as when calling
+// Process directly. Only the session time is available, which is read from the tick if present.
+func ContextFromTick(input Tick) TickContext {
+	sessionTime, _ := GetTickValue[float64](input, sessionTimeVar)
+
+	return TickContext{SessionTime: sessionTime}
+}
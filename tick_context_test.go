@@ -0,0 +1,103 @@
+package ibt
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/teamjorge/ibt/headers"
+)
+
+type testContextProcessor struct {
+	testProcessor
+	contexts []TickContext
+}
+
+func (t *testContextProcessor) ProcessContext(input Tick, tickCtx TickContext, hasNext bool, session *headers.Session) error {
+	t.contexts = append(t.contexts, tickCtx)
+
+	return t.Process(input, hasNext, session)
+}
+
+func TestTickContext(t *testing.T) {
+	f, err := os.Open(".testing/valid_test_file.ibt")
+	if err != nil {
+		t.Errorf("failed to open testing file - %v", err)
+		return
+	}
+	defer f.Close()
+
+	testHeaders, err := headers.ParseHeaders(f)
+	if err != nil {
+		t.Errorf("failed to parse header for testing file - %v", err)
+		return
+	}
+
+	stubs := StubGroup{
+		{filepath: ".testing/valid_test_file.ibt", header: testHeaders, r: f},
+		{filepath: ".testing/valid_test_file.ibt", header: testHeaders, r: f},
+	}
+
+	t.Run("test Process() with ContextProcessor", func(t *testing.T) {
+		proc := testContextProcessor{testProcessor: testProcessor{whitelist: []string{"Speed"}}}
+
+		if err := Process(context.Background(), stubs, &proc); err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		if len(proc.contexts) != 778 {
+			t.Errorf("expected a tick context for each of the %d ticks. received %d", 778, len(proc.contexts))
+			return
+		}
+
+		first := proc.contexts[0]
+		if first.Index != 0 || first.GroupIndex != 0 || first.Stub.Index != 0 {
+			t.Errorf("expected first tick context to be at index 0 of stub 0. received %+v", first)
+		}
+
+		if first.SessionTime < 932.03 || first.SessionTime > 932.04 {
+			t.Errorf("expected first tick context to have a session time of %f. received %f", 932.0333, first.SessionTime)
+		}
+
+		expectedTime := time.Unix(1719258336, int64(33*time.Millisecond))
+		if first.Time.Sub(expectedTime).Abs() > time.Millisecond {
+			t.Errorf("expected first tick context to have a time of %v. received %v", expectedTime, first.Time)
+		}
+
+		secondStub := proc.contexts[389]
+		if secondStub.Index != 0 || secondStub.GroupIndex != 389 || secondStub.Stub.Index != 1 {
+			t.Errorf("expected first tick of second stub to be at index 0 and group index 389. received %+v", secondStub)
+		}
+
+		if _, ok := proc.results[0][sessionTimeVar]; ok {
+			t.Errorf("expected %s to not be included in the tick when it was not whitelisted", sessionTimeVar)
+		}
+	})
+
+	t.Run("test newTickContext without SessionTime", func(t *testing.T) {
+		info := StubInfo{Header: testHeaders}
+
+		tickCtx := newTickContext(info, 120, 120, Tick{})
+
+		expectedSessionTime := testHeaders.DiskHeader.StartTime + 2
+		if tickCtx.SessionTime != expectedSessionTime {
+			t.Errorf("expected session time to be estimated as %f. received %f", expectedSessionTime, tickCtx.SessionTime)
+		}
+
+		expectedTime := time.Unix(1719258338, 0)
+		if !tickCtx.Time.Equal(expectedTime) {
+			t.Errorf("expected time to be %v. received %v", expectedTime, tickCtx.Time)
+		}
+	})
+
+	t.Run("test ContextFromTick", func(t *testing.T) {
+		if tickCtx := ContextFromTick(Tick{sessionTimeVar: 12.5}); tickCtx.SessionTime != 12.5 {
+			t.Errorf("expected a session time of %.1f. received %f", 12.5, tickCtx.SessionTime)
+		}
+
+		if tickCtx := ContextFromTick(Tick{}); tickCtx != (TickContext{}) {
+			t.Errorf("expected an empty tick context without SessionTime. received %+v", tickCtx)
+		}
+	})
+}
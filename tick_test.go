@@ -34,3 +34,69 @@ func TestGetTickValue(t *testing.T) {
 		}
 	})
 }
+
+func TestGetTickBitField(t *testing.T) {
+	testTick := Tick{
+		"SessionFlags": "0x10004",
+		"Gear":         5,
+		"Invalid":      "green",
+	}
+
+	t.Run("test normal scenario", func(t *testing.T) {
+		value, err := GetTickBitField(testTick, "SessionFlags")
+		if err != nil {
+			t.Errorf("expected err to be nil but received: %v", err)
+		}
+
+		if value != 0x10004 {
+			t.Errorf("expected SessionFlags value to be %#x. received: %#x", 0x10004, value)
+		}
+	})
+
+	t.Run("test invalid values", func(t *testing.T) {
+		for _, key := range []string{"Gear", "Invalid", "NotFound"} {
+			if _, err := GetTickBitField(testTick, key); err == nil {
+				t.Errorf("expected an error to occur when retrieving the bitfield of %s", key)
+			}
+		}
+	})
+}
+
+func TestMeanTicks(t *testing.T) {
+	t.Run("test MeanTicks averages floats", func(t *testing.T) {
+		ticks := []Tick{
+			{"Speed": float32(10), "Lat": float64(1), "Gear": 3, "Temps": []float32{1, 2}},
+			{"Speed": float32(20), "Lat": float64(2), "Gear": 4, "Temps": []float32{3, 4}},
+			{"Speed": float32(30), "Lat": float64(6), "Gear": 5, "Temps": []float32{5, 6}},
+		}
+
+		result := MeanTicks(ticks)
+
+		if result["Speed"] != float32(20) {
+			t.Errorf("expected mean Speed to be %f. received %v", 20.0, result["Speed"])
+		}
+
+		if result["Lat"] != float64(3) {
+			t.Errorf("expected mean Lat to be %f. received %v", 3.0, result["Lat"])
+		}
+
+		if result["Gear"] != 5 {
+			t.Errorf("expected Gear to be taken from the last tick. received %v", result["Gear"])
+		}
+
+		temps := result["Temps"].([]float32)
+		if temps[0] != 3 || temps[1] != 4 {
+			t.Errorf("expected mean Temps to be %v. received %v", []float32{3, 4}, temps)
+		}
+
+		if ticks[2]["Temps"].([]float32)[0] != 5 {
+			t.Error("expected MeanTicks to not modify the given ticks")
+		}
+	})
+
+	t.Run("test MeanTicks empty", func(t *testing.T) {
+		if MeanTicks(nil) != nil {
+			t.Error("expected MeanTicks of no ticks to be nil")
+		}
+	})
+}
@@ -0,0 +1,396 @@
+// Package trackmap builds the outline of a track from the positions of a car during a clean lap.
+//
+// A Builder records the GPS position of the car from Lat and Lon. When these are not available, the
+// position is integrated from VelocityX, VelocityY and Yaw, using the TrackNorthOffset of the session to
+// align the outline with north, and the drift of the integration is removed by closing the outline at the
+// start/finish line. The resulting Map is a polyline indexed by LapDistPct, which can be written as GeoJSON
+// or drawn as SVG.
+package trackmap
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/laps"
+)
+
+const (
+	// Default number of points of the outline
+	defaultResolution int = 1000
+	// Minimum fraction of the points of a lap that need to be driven for it to be used
+	minCoverage float64 = 0.97
+)
+
+var (
+	// ErrNoLap is returned when no clean lap was driven from which the map can be built.
+	ErrNoLap = errors.New("no clean lap available for building a track map")
+	// ErrNoPosition is returned when the lap does not contain the variables of the requested source.
+	ErrNoPosition = errors.New("no position available for building a track map")
+)
+
+func init() {
+	ibt.Register("trackmap", func(params ibt.Params) (ibt.Processor, error) {
+		var opts Options
+		if err := params.Decode(&opts); err != nil {
+			return nil, err
+		}
+
+		return NewBuilder(opts), nil
+	})
+}
+
+// Options configures the building of a map.
+type Options struct {
+	// Lap to build the map from. Defaults to the fastest clean lap.
+	Lap int `yaml:"lap"`
+	// Resolution is the number of points of the outline. Defaults to 1000.
+	Resolution int `yaml:"resolution"`
+	// Source of the positions. Defaults to GPS when available for the whole lap, otherwise Velocity.
+	Source Source `yaml:"source"`
+}
+
+// Builder is a processor that builds the map of the track from a clean lap.
+//
+// A lap is clean when it is driven from crossing the line until crossing it again without entering pit
+// road. Only the samples of the current and fastest clean lap are kept, regardless of the number of laps
+// processed.
+type Builder struct {
+	opts  Options
+	track headers.WeekendInfo
+	best  *lapPath
+
+	current *lapPath
+	prev    mapTick
+}
+
+// mapTick is the part of a tick used for building a map
+type mapTick struct {
+	lap         int
+	pct         float64
+	sessionTime float64
+	onPitRoad   bool
+	lat, lon    float64
+	hasGPS      bool
+	// velocity in meters per second in the frame of the track, where x is along a Yaw of 0
+	vx, vy      float64
+	hasVelocity bool
+}
+
+// lapPath is the path driven by the car during a lap
+type lapPath struct {
+	lap       int
+	started   bool
+	pitted    bool
+	startTime float64
+	time      float64
+	samples   []sample
+	// x and y are the position in meters integrated from the velocity since the start of the lap
+	x, y float64
+}
+
+// sample is a single position of a lap
+type sample struct {
+	pct      float64
+	lat, lon float64
+	x, y     float64
+	gps      bool
+	velocity bool
+}
+
+// NewBuilder creates a map Builder with the given options.
+func NewBuilder(opts Options) *Builder {
+	if opts.Resolution <= 0 {
+		opts.Resolution = defaultResolution
+	}
+
+	return &Builder{opts: opts}
+}
+
+// FromStubs builds the map of the track of the given stubs.
+func FromStubs(ctx context.Context, stubs ibt.StubGroup, opts Options) (Map, error) {
+	builder := NewBuilder(opts)
+
+	if err := ibt.Process(ctx, stubs, builder); err != nil {
+		return Map{}, err
+	}
+
+	return builder.Map()
+}
+
+// Whitelist of the variables required for building a map
+func (b *Builder) Whitelist() []string {
+	return []string{"Lap", "LapDistPct", "OnPitRoad", "Lat", "Lon", "VelocityX", "VelocityY", "Yaw"}
+}
+
+// StartStub discards the lap in progress of the previous stub
+func (b *Builder) StartStub(stub ibt.StubInfo) error {
+	b.current = nil
+
+	return nil
+}
+
+// Process the tick without a TickContext. The velocity of the car is integrated over the SessionTime of the
+// tick, so the path can only be traced when it is included.
+func (b *Builder) Process(input ibt.Tick, hasNext bool, session *headers.Session) error {
+	return b.ProcessContext(input, ibt.ContextFromTick(input), hasNext, session)
+}
+
+// ProcessContext adds the position of the tick to the path of its lap, keeping the path of the previous lap
+// when it is the fastest clean lap so far
+func (b *Builder) ProcessContext(input ibt.Tick, tickCtx ibt.TickContext, hasNext bool, session *headers.Session) error {
+	lap, err := ibt.GetTickValue[int](input, "Lap")
+	if err != nil {
+		return err
+	}
+
+	pct, err := ibt.GetTickValue[float32](input, "LapDistPct")
+	if err != nil {
+		return err
+	}
+
+	if b.track.TrackID == 0 && session != nil {
+		b.track = session.WeekendInfo
+	}
+
+	tick := mapTick{lap: lap, pct: float64(pct), sessionTime: tickCtx.SessionTime}
+	tick.onPitRoad, _ = ibt.GetTickValue[bool](input, "OnPitRoad")
+
+	// Either source of the position may be missing
+	lat, latErr := ibt.GetTickValue[float64](input, "Lat")
+	lon, lonErr := ibt.GetTickValue[float64](input, "Lon")
+	tick.lat, tick.lon = lat, lon
+	tick.hasGPS = latErr == nil && lonErr == nil && (lat != 0 || lon != 0)
+
+	vx, vxErr := ibt.GetTickValue[float32](input, "VelocityX")
+	vy, vyErr := ibt.GetTickValue[float32](input, "VelocityY")
+	yaw, yawErr := ibt.GetTickValue[float32](input, "Yaw")
+	if tick.hasVelocity = vxErr == nil && vyErr == nil && yawErr == nil; tick.hasVelocity {
+		// VelocityX is forwards and VelocityY to the left of the car
+		sin, cos := math.Sincos(float64(yaw))
+		tick.vx = float64(vx)*cos - float64(vy)*sin
+		tick.vy = float64(vx)*sin + float64(vy)*cos
+	}
+
+	step := tick.pct - b.prev.pct
+
+	switch {
+	case b.current == nil:
+		b.current = &lapPath{lap: tick.lap}
+	case tick.lap == b.current.lap+1 && step < -laps.MaxPctStep:
+		// The crossing of the line is interpolated between the ticks on either side of it
+		before, after := 1-b.prev.pct, tick.pct
+		fraction := 0.0
+		if before+after > 0 {
+			fraction = before / (before + after)
+		}
+		crossing := b.prev.sessionTime + (tick.sessionTime-b.prev.sessionTime)*fraction
+
+		b.current.integrate(b.prev, crossing-b.prev.sessionTime)
+		b.complete(crossing)
+		b.current = &lapPath{lap: tick.lap, started: true, startTime: crossing}
+		b.current.integrate(tick, tick.sessionTime-crossing)
+	case tick.lap != b.current.lap || step >= laps.MaxPctStep || step <= -laps.MaxPctStep:
+		// The car was relocated, so the lap can no longer be used
+		b.current = &lapPath{lap: tick.lap}
+	default:
+		b.current.integrate(tick, tick.sessionTime-b.prev.sessionTime)
+	}
+
+	b.current.add(tick)
+	b.prev = tick
+
+	return nil
+}
+
+// integrate the velocity of the tick over the given number of seconds
+func (p *lapPath) integrate(tick mapTick, dt float64) {
+	p.x += tick.vx * dt
+	p.y += tick.vy * dt
+}
+
+// add the position of the tick to the path
+func (p *lapPath) add(tick mapTick) {
+	p.pitted = p.pitted || tick.onPitRoad
+	p.samples = append(p.samples, sample{
+		pct:      tick.pct,
+		lat:      tick.lat,
+		lon:      tick.lon,
+		x:        p.x,
+		y:        p.y,
+		gps:      tick.hasGPS,
+		velocity: tick.hasVelocity,
+	})
+}
+
+// coverage is the fraction of the given number of points of the lap that contain a sample
+func (p *lapPath) coverage(resolution int) float64 {
+	covered := make([]bool, resolution)
+	count := 0
+
+	for _, s := range p.samples {
+		idx := min(int(s.pct*float64(resolution)), resolution-1)
+		if !covered[idx] {
+			covered[idx] = true
+			count++
+		}
+	}
+
+	return float64(count) / float64(resolution)
+}
+
+// complete the current lap at the given time, keeping it if it is the fastest clean lap
+func (b *Builder) complete(endTime float64) {
+	p := b.current
+	if !p.started || p.pitted || (b.opts.Lap > 0 && p.lap != b.opts.Lap) || p.coverage(b.opts.Resolution) < minCoverage {
+		return
+	}
+
+	p.time = endTime - p.startTime
+	if b.best == nil || p.time < b.best.time {
+		b.best = p
+	}
+}
+
+// Map of the track, built from the fastest clean lap processed so far.
+//
+// ErrNoLap is returned when no clean lap was processed, and ErrNoPosition when the lap does not contain
+// the positions of the requested Source.
+func (b *Builder) Map() (Map, error) {
+	m := Map{
+		TrackID:         b.track.TrackID,
+		TrackName:       b.track.TrackDisplayName,
+		TrackConfigName: b.track.TrackConfigName,
+	}
+
+	if b.best == nil {
+		return m, ErrNoLap
+	}
+	m.Lap = b.best.lap
+
+	gps, velocity := true, true
+	for _, s := range b.best.samples {
+		gps = gps && s.gps
+		velocity = velocity && s.velocity
+	}
+
+	switch m.Source = b.opts.Source; {
+	case m.Source == "" && gps, m.Source == GPS && gps:
+		m.Source = GPS
+		b.gpsPoints(&m)
+	case (m.Source == "" || m.Source == Velocity) && velocity:
+		m.Source = Velocity
+		b.velocityPoints(&m)
+	default:
+		return m, ErrNoPosition
+	}
+
+	return m, nil
+}
+
+// gpsPoints sets the points of the map from the GPS positions of the best lap
+func (b *Builder) gpsPoints(m *Map) {
+	lats, lons := b.bin(func(s sample) (float64, float64) { return s.lat, s.lon })
+
+	var originLat, originLon float64
+	for idx := range lats {
+		originLat += lats[idx] / float64(len(lats))
+		originLon += lons[idx] / float64(len(lons))
+	}
+
+	m.Aligned, m.Georeferenced = true, true
+	m.Points = make([]Point, len(lats))
+
+	for idx := range lats {
+		x, y := project(lats[idx], lons[idx], originLat, originLon)
+		m.Points[idx] = Point{Pct: b.pct(idx), X: x, Y: y, Lat: lats[idx], Lon: lons[idx]}
+	}
+}
+
+// velocityPoints sets the points of the map from the positions of the best lap integrated from its velocity
+func (b *Builder) velocityPoints(m *Map) {
+	// The outline is closed by removing the drift of the integration in proportion to the distance driven
+	endX, endY := b.best.x, b.best.y
+	xs, ys := b.bin(func(s sample) (float64, float64) { return s.x - endX*s.pct, s.y - endY*s.pct })
+
+	var meanX, meanY float64
+	for idx := range xs {
+		meanX += xs[idx] / float64(len(xs))
+		meanY += ys[idx] / float64(len(ys))
+	}
+
+	// Without the offset of the track, the outline is drawn as integrated with a Yaw of 0 pointing east
+	offset, err := b.track.TrackNorthOffsetRadians()
+	m.Aligned = err == nil
+
+	// A Yaw of 0 points at the TrackNorthOffset clockwise from north
+	sin, cos := math.Sincos(offset)
+	if !m.Aligned {
+		sin, cos = 1, 0
+	}
+
+	originLat, originLon, err := b.track.TrackCoordinates()
+	m.Georeferenced = m.Aligned && err == nil
+
+	m.Points = make([]Point, len(xs))
+
+	for idx := range xs {
+		x, y := xs[idx]-meanX, ys[idx]-meanY
+		p := Point{Pct: b.pct(idx), X: x*sin - y*cos, Y: x*cos + y*sin}
+
+		if m.Georeferenced {
+			p.Lat, p.Lon = unproject(p.X, p.Y, originLat, originLon)
+		}
+
+		m.Points[idx] = p
+	}
+}
+
+// bin averages the values of the samples of the best lap in each point of the outline. Points without
+// samples are interpolated from the points on either side of them.
+func (b *Builder) bin(value func(s sample) (float64, float64)) ([]float64, []float64) {
+	n := b.opts.Resolution
+	first, second := make([]float64, n), make([]float64, n)
+	counts := make([]int, n)
+
+	for _, s := range b.best.samples {
+		idx := min(int(s.pct*float64(n)), n-1)
+		a, b := value(s)
+		first[idx] += a
+		second[idx] += b
+		counts[idx]++
+	}
+
+	filled := make([]int, 0, n)
+	for idx := range counts {
+		if counts[idx] > 0 {
+			first[idx] /= float64(counts[idx])
+			second[idx] /= float64(counts[idx])
+			filled = append(filled, idx)
+		}
+	}
+
+	for i, from := range filled {
+		to := filled[(i+1)%len(filled)]
+		gap := (to - from + n) % n
+		for step := 1; step < gap; step++ {
+			idx, fraction := (from+step)%n, float64(step)/float64(gap)
+			first[idx] = first[from] + (first[to]-first[from])*fraction
+			second[idx] = second[from] + (second[to]-second[from])*fraction
+		}
+	}
+
+	return first, second
+}
+
+// pct is the LapDistPct at the centre of the given point of the outline
+func (b *Builder) pct(idx int) float64 { return (float64(idx) + 0.5) / float64(b.opts.Resolution) }
+
+// Result returns the Map of the track, which contains no points when no map could be built
+func (b *Builder) Result() Map {
+	m, _ := b.Map()
+
+	return m
+}
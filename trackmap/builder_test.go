@@ -0,0 +1,247 @@
+package trackmap
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/ibttest"
+)
+
+const (
+	testRadius      float64 = 100
+	testLapTime     float64 = 60
+	testNorthOffset float64 = 1.5876
+	testLatitude    float64 = 47.220305
+	testLongitude   float64 = 14.766722
+)
+
+// testSession is a session on a track at the coordinates of the testing file
+func testSession() *headers.Session {
+	return &headers.Session{WeekendInfo: headers.WeekendInfo{
+		TrackID:          1,
+		TrackDisplayName: "Circle",
+		TrackNorthOffset: "1.5876 rad",
+		TrackLatitude:    "47.220305 m",
+		TrackLongitude:   "14.766722 m",
+	}}
+}
+
+// testTicks creates the ticks of a car driving counter-clockwise around a circle with a radius of 100 meters,
+// starting at 90% of lap 0 and ending at 10% of the last lap. The line is crossed on the east of the circle.
+//
+// The car is on pit road during the pitted lap, and laps after the first full lap are driven a second faster.
+func testTicks(laps, pitted int, gps bool) []ibt.Tick {
+	ticks := make([]ibt.Tick, 0)
+	speed := 2 * math.Pi * testRadius / testLapTime
+	sessionTime := 0.0
+
+	for lap := 0; lap <= laps+1; lap++ {
+		lapTime := testLapTime
+		if lap > 1 {
+			lapTime--
+		}
+
+		for step := 0; step < int(lapTime*60); step++ {
+			pct := float64(step) / (lapTime * 60)
+			if (lap == 0 && pct < 0.9) || (lap == laps+1 && pct > 0.1) {
+				continue
+			}
+
+			theta := 2 * math.Pi * pct
+			tick := ibt.Tick{
+				"SessionTime": sessionTime,
+				"Lap":         lap,
+				"LapDistPct":  float32(pct),
+				"OnPitRoad":   lap == pitted,
+				"VelocityX":   float32(speed * testLapTime / lapTime),
+				"VelocityY":   float32(0),
+				// The heading is a quarter turn counter-clockwise from the angle on the circle
+				"Yaw": float32(math.Remainder(testNorthOffset+theta, 2*math.Pi)),
+			}
+
+			if gps {
+				east, north := testRadius*math.Cos(theta), testRadius*math.Sin(theta)
+				tick["Lat"], tick["Lon"] = unproject(east, north, testLatitude, testLongitude)
+			}
+
+			ticks = append(ticks, tick)
+			sessionTime += 1.0 / 60
+		}
+	}
+
+	return ticks
+}
+
+var testStub = ibt.StubInfo{Index: 0, Filename: "test.ibt"}
+
+func expectCircle(t *testing.T, m Map, tolerance float64) {
+	t.Helper()
+
+	if len(m.Points) != 1000 {
+		t.Errorf("expected %d points. received %d", 1000, len(m.Points))
+		return
+	}
+
+	expected := []struct {
+		pct  float64
+		x, y float64
+	}{
+		{0, testRadius, 0},
+		{0.25, 0, testRadius},
+		{0.5, -testRadius, 0},
+		{0.75, 0, -testRadius},
+	}
+
+	for _, want := range expected {
+		if p := m.At(want.pct); math.Abs(p.X-want.x) > tolerance || math.Abs(p.Y-want.y) > tolerance {
+			t.Errorf("expected the position at %.2f to be %.1f, %.1f. received %f, %f", want.pct, want.x, want.y, p.X, p.Y)
+		}
+	}
+
+	if length := m.Length(); math.Abs(length-2*math.Pi*testRadius) > 1 {
+		t.Errorf("expected a length of %f. received %f", 2*math.Pi*testRadius, length)
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	t.Run("test Builder from GPS", func(t *testing.T) {
+		builder := NewBuilder(Options{})
+		ibttest.Process(t, builder, testStub, testTicks(3, -1, true), testSession())
+
+		m, err := builder.Map()
+		if err != nil {
+			t.Errorf("expected Map() to run without err. received error: %v", err)
+			return
+		}
+
+		if m.Source != GPS || !m.Aligned || !m.Georeferenced || m.TrackID != 1 || m.TrackName != "Circle" {
+			t.Errorf("expected an aligned and georeferenced map from GPS. received %+v", m)
+		}
+		// The fastest lap is the first of the faster laps
+		if m.Lap != 2 {
+			t.Errorf("expected the map of lap %d. received %d", 2, m.Lap)
+		}
+
+		expectCircle(t, m, 1)
+
+		// The centre of the map is the centre of the circle
+		if p := m.At(0); math.Abs(p.Lat-testLatitude) > 0.00001 || math.Abs(p.Lon-(testLongitude+0.00132)) > 0.00001 {
+			t.Errorf("expected the coordinates of the start east of the centre. received %f, %f", p.Lat, p.Lon)
+		}
+	})
+
+	t.Run("test Builder from velocity", func(t *testing.T) {
+		builder := NewBuilder(Options{})
+		ibttest.Process(t, builder, testStub, testTicks(3, -1, false), testSession())
+
+		m, err := builder.Map()
+		if err != nil {
+			t.Errorf("expected Map() to run without err. received error: %v", err)
+			return
+		}
+
+		if m.Source != Velocity || !m.Aligned || !m.Georeferenced {
+			t.Errorf("expected an aligned and georeferenced map from velocity. received %+v", m)
+		}
+
+		expectCircle(t, m, 1)
+
+		if p := m.At(0.25); math.Abs(p.Lat-(testLatitude+0.0009)) > 0.00001 || math.Abs(p.Lon-testLongitude) > 0.00001 {
+			t.Errorf("expected the coordinates of a quarter lap north of the track. received %f, %f", p.Lat, p.Lon)
+		}
+	})
+
+	t.Run("test Builder forced velocity", func(t *testing.T) {
+		builder := NewBuilder(Options{Source: Velocity})
+		ibttest.Process(t, builder, testStub, testTicks(2, -1, true), testSession())
+
+		if m, err := builder.Map(); err != nil || m.Source != Velocity {
+			t.Errorf("expected a map from velocity. received %+v (%v)", m.Source, err)
+		}
+
+		builder = NewBuilder(Options{Source: GPS})
+		ibttest.Process(t, builder, testStub, testTicks(2, -1, false), testSession())
+
+		if _, err := builder.Map(); !errors.Is(err, ErrNoPosition) {
+			t.Errorf("expected ErrNoPosition without GPS. received %v", err)
+		}
+	})
+
+	t.Run("test Builder lap", func(t *testing.T) {
+		builder := NewBuilder(Options{Lap: 1, Resolution: 200})
+		ibttest.Process(t, builder, testStub, testTicks(3, -1, true), testSession())
+
+		if m := builder.Result(); m.Lap != 1 || len(m.Points) != 200 {
+			t.Errorf("expected %d points of lap %d. received %d points of lap %d", 200, 1, len(m.Points), m.Lap)
+		}
+	})
+
+	t.Run("test Builder pitted laps", func(t *testing.T) {
+		builder := NewBuilder(Options{})
+		ibttest.Process(t, builder, testStub, testTicks(3, 2, true), testSession())
+
+		if m := builder.Result(); m.Lap != 3 {
+			t.Errorf("expected the map of lap %d. received %d", 3, m.Lap)
+		}
+
+		builder = NewBuilder(Options{})
+		ibttest.Process(t, builder, testStub, testTicks(1, 1, true), testSession())
+
+		if m, err := builder.Map(); !errors.Is(err, ErrNoLap) || len(m.Points) != 0 {
+			t.Errorf("expected ErrNoLap without a clean lap. received %v", err)
+		}
+	})
+
+	t.Run("test Builder across stubs", func(t *testing.T) {
+		ticks := testTicks(1, -1, true)
+		builder := NewBuilder(Options{})
+		ibttest.Process(t, builder, testStub, ticks[:len(ticks)/2], testSession())
+		ibttest.Process(t, builder, testStub, ticks[len(ticks)/2:], testSession())
+
+		// The only full lap is split between the stubs
+		if _, err := builder.Map(); !errors.Is(err, ErrNoLap) {
+			t.Errorf("expected ErrNoLap across stubs. received %v", err)
+		}
+	})
+
+	t.Run("test Builder missing variables", func(t *testing.T) {
+		if err := NewBuilder(Options{}).Process(ibt.Tick{"Lap": 1}, true, nil); err == nil {
+			t.Error("expected Process() to return an error when LapDistPct is missing")
+		}
+	})
+
+	t.Run("test Builder from registry", func(t *testing.T) {
+		processor, err := ibt.DefaultRegistry.NewProcessor("trackmap", ibt.Params{"lap": 3, "source": "velocity"})
+		if err != nil {
+			t.Errorf("expected NewProcessor() to run without err. received error: %v", err)
+			return
+		}
+
+		builder := processor.(*Builder)
+		if builder.opts.Lap != 3 || builder.opts.Resolution != 1000 || builder.opts.Source != Velocity {
+			t.Errorf("expected the options of the params. received %+v", builder.opts)
+		}
+	})
+
+	t.Run("test FromStubs", func(t *testing.T) {
+		stubs, err := ibt.ParseStubs("../.testing/valid_test_file.ibt")
+		if err != nil {
+			t.Errorf("failed to parse stubs for testing file - %v", err)
+			return
+		}
+		defer stubs.Close()
+
+		// The car remains in the pits throughout the testing file
+		m, err := FromStubs(context.Background(), stubs, Options{})
+		if !errors.Is(err, ErrNoLap) {
+			t.Errorf("expected ErrNoLap. received %v", err)
+		}
+		if m.TrackID == 0 || m.TrackName == "" {
+			t.Errorf("expected the track of the testing file. received %+v", m)
+		}
+	})
+}
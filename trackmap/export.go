@@ -0,0 +1,157 @@
+package trackmap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+const (
+	// Default width and height of an SVG in pixels
+	defaultSVGWidth  float64 = 800
+	defaultSVGHeight float64 = 600
+	// Default colour of the outline of an SVG
+	defaultSVGStroke string = "#000000"
+	// Default width of the outline of an SVG in pixels
+	defaultSVGStrokeWidth float64 = 4
+	// Fraction of the width and height of an SVG left empty around the outline
+	svgMargin float64 = 0.05
+)
+
+// geoJSON is a FeatureCollection containing the outline of a track as a single LineString
+type geoJSON struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string            `json:"type"`
+	Geometry   geoJSONLineString `json:"geometry"`
+	Properties map[string]any    `json:"properties"`
+}
+
+type geoJSONLineString struct {
+	Type        string       `json:"type"`
+	Coordinates [][2]float64 `json:"coordinates"`
+}
+
+// WriteGeoJSON writes the outline of the map as a GeoJSON FeatureCollection with a single, closed LineString.
+//
+// The coordinates are the longitude and latitude of the points when the map is georeferenced. Otherwise,
+// the X and Y of the points in meters are used. The LapDistPct of each coordinate is included in the pct
+// property of the feature.
+func WriteGeoJSON(w io.Writer, m Map) error {
+	coordinates := make([][2]float64, 0, len(m.Points)+1)
+	pcts := make([]float64, 0, len(m.Points)+1)
+
+	for idx := 0; idx <= len(m.Points) && len(m.Points) > 0; idx++ {
+		p := m.Points[idx%len(m.Points)]
+
+		if m.Georeferenced {
+			coordinates = append(coordinates, [2]float64{p.Lon, p.Lat})
+		} else {
+			coordinates = append(coordinates, [2]float64{p.X, p.Y})
+		}
+		pcts = append(pcts, p.Pct)
+	}
+
+	collection := geoJSON{
+		Type: "FeatureCollection",
+		Features: []geoJSONFeature{{
+			Type:     "Feature",
+			Geometry: geoJSONLineString{Type: "LineString", Coordinates: coordinates},
+			Properties: map[string]any{
+				"track_id":          m.TrackID,
+				"track_name":        m.TrackName,
+				"track_config_name": m.TrackConfigName,
+				"lap":               m.Lap,
+				"source":            m.Source,
+				"aligned":           m.Aligned,
+				"georeferenced":     m.Georeferenced,
+				"pct":               pcts,
+			},
+		}},
+	}
+
+	if err := json.NewEncoder(w).Encode(collection); err != nil {
+		return fmt.Errorf("failed to encode track map: %v", err)
+	}
+
+	return nil
+}
+
+// SVGOptions configures the drawing of a map as SVG.
+type SVGOptions struct {
+	// Width and Height of the image in pixels. Default to 800 and 600.
+	Width  float64 `yaml:"width"`
+	Height float64 `yaml:"height"`
+	// Stroke is the colour of the outline. Defaults to black.
+	Stroke string `yaml:"stroke"`
+	// StrokeWidth is the width of the outline in pixels. Defaults to 4.
+	StrokeWidth float64 `yaml:"stroke_width"`
+}
+
+// WriteSVG draws the outline of the map as SVG, with north at the top. The outline is scaled to fit the
+// image while keeping its proportions, and the start/finish line is marked with a circle.
+func WriteSVG(w io.Writer, m Map, opts SVGOptions) error {
+	if opts.Width <= 0 {
+		opts.Width = defaultSVGWidth
+	}
+	if opts.Height <= 0 {
+		opts.Height = defaultSVGHeight
+	}
+	if opts.Stroke == "" {
+		opts.Stroke = defaultSVGStroke
+	}
+	if opts.StrokeWidth <= 0 {
+		opts.StrokeWidth = defaultSVGStrokeWidth
+	}
+
+	minX, minY, maxX, maxY := m.Bounds()
+
+	// The outline is centred in the image at the largest scale that fits both dimensions
+	scale := 0.0
+	if width, height := maxX-minX, maxY-minY; width > 0 || height > 0 {
+		scale = math.Min(opts.Width*(1-2*svgMargin)/width, opts.Height*(1-2*svgMargin)/height)
+	}
+	offsetX := (opts.Width - (maxX-minX)*scale) / 2
+	offsetY := (opts.Height - (maxY-minY)*scale) / 2
+
+	pixel := func(p Point) (string, string) {
+		x := offsetX + (p.X-minX)*scale
+		y := offsetY + (maxY-p.Y)*scale
+
+		return strconv.FormatFloat(x, 'f', 2, 64), strconv.FormatFloat(y, 'f', 2, 64)
+	}
+
+	writer := bufio.NewWriter(w)
+
+	fmt.Fprintf(writer, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %[1]s %[2]s">`+"\n",
+		strconv.FormatFloat(opts.Width, 'f', -1, 64), strconv.FormatFloat(opts.Height, 'f', -1, 64))
+
+	if len(m.Points) > 0 {
+		writer.WriteString(`  <path d="`)
+		for idx, p := range m.Points {
+			command := "L"
+			if idx == 0 {
+				command = "M"
+			}
+
+			x, y := pixel(p)
+			fmt.Fprintf(writer, "%s%s %s ", command, x, y)
+		}
+		fmt.Fprintf(writer, `Z" fill="none" stroke="%s" stroke-width="%s" stroke-linejoin="round"/>`+"\n",
+			opts.Stroke, strconv.FormatFloat(opts.StrokeWidth, 'f', -1, 64))
+
+		x, y := pixel(m.At(0))
+		fmt.Fprintf(writer, `  <circle cx="%s" cy="%s" r="%s" fill="%s"/>`+"\n",
+			x, y, strconv.FormatFloat(opts.StrokeWidth*1.5, 'f', -1, 64), opts.Stroke)
+	}
+
+	writer.WriteString("</svg>\n")
+
+	return writer.Flush()
+}
@@ -0,0 +1,112 @@
+package trackmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteGeoJSON(t *testing.T) {
+	t.Run("test WriteGeoJSON meters", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteGeoJSON(&buf, squareMap()); err != nil {
+			t.Errorf("expected WriteGeoJSON() to run without err. received error: %v", err)
+			return
+		}
+
+		var collection geoJSON
+		if err := json.Unmarshal(buf.Bytes(), &collection); err != nil {
+			t.Errorf("expected valid GeoJSON. received error: %v", err)
+			return
+		}
+
+		if collection.Type != "FeatureCollection" || len(collection.Features) != 1 {
+			t.Errorf("expected a FeatureCollection with a single feature. received %+v", collection)
+			return
+		}
+
+		feature := collection.Features[0]
+		coordinates := feature.Geometry.Coordinates
+		if feature.Geometry.Type != "LineString" || len(coordinates) != 5 {
+			t.Errorf("expected a LineString of %d coordinates. received %+v", 5, feature.Geometry)
+			return
+		}
+		if coordinates[0] != [2]float64{-50, -50} || coordinates[4] != coordinates[0] {
+			t.Errorf("expected a closed LineString in meters. received %v", coordinates)
+		}
+
+		if feature.Properties["track_name"] != "Square" || feature.Properties["source"] != "velocity" {
+			t.Errorf("expected the properties of the map. received %v", feature.Properties)
+		}
+		if pcts, ok := feature.Properties["pct"].([]any); !ok || len(pcts) != 5 || pcts[1] != 0.375 {
+			t.Errorf("expected the pct of each coordinate. received %v", feature.Properties["pct"])
+		}
+	})
+
+	t.Run("test WriteGeoJSON georeferenced", func(t *testing.T) {
+		m := squareMap()
+		m.Georeferenced = true
+		m.Points[0].Lat, m.Points[0].Lon = 47.2, 14.7
+
+		var buf bytes.Buffer
+		WriteGeoJSON(&buf, m)
+
+		var collection geoJSON
+		json.Unmarshal(buf.Bytes(), &collection)
+
+		if coordinates := collection.Features[0].Geometry.Coordinates; coordinates[0] != [2]float64{14.7, 47.2} {
+			t.Errorf("expected the longitude and latitude of the first point. received %v", coordinates[0])
+		}
+	})
+}
+
+func TestWriteSVG(t *testing.T) {
+	t.Run("test WriteSVG defaults", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteSVG(&buf, squareMap(), SVGOptions{}); err != nil {
+			t.Errorf("expected WriteSVG() to run without err. received error: %v", err)
+			return
+		}
+
+		// The square is scaled to 90% of the height and centred, with north at the top
+		expected := []string{
+			`width="800" height="600" viewBox="0 0 800 600"`,
+			`d="M130.00 570.00 L670.00 570.00 L670.00 30.00 L130.00 30.00 Z"`,
+			`stroke="#000000" stroke-width="4"`,
+			`<circle cx="130.00" cy="300.00" r="6" fill="#000000"/>`,
+		}
+
+		for _, want := range expected {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("expected the SVG to contain %s. received %s", want, buf.String())
+			}
+		}
+	})
+
+	t.Run("test WriteSVG options", func(t *testing.T) {
+		var buf bytes.Buffer
+		WriteSVG(&buf, squareMap(), SVGOptions{Width: 200, Height: 400, Stroke: "red", StrokeWidth: 2})
+
+		expected := []string{
+			`width="200" height="400"`,
+			`d="M10.00 290.00 L190.00 290.00 L190.00 110.00 L10.00 110.00 Z"`,
+			`stroke="red" stroke-width="2"`,
+		}
+
+		for _, want := range expected {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("expected the SVG to contain %s. received %s", want, buf.String())
+			}
+		}
+	})
+
+	t.Run("test WriteSVG without points", func(t *testing.T) {
+		var buf bytes.Buffer
+		WriteSVG(&buf, Map{}, SVGOptions{})
+
+		if strings.Contains(buf.String(), "<path") || !strings.HasSuffix(buf.String(), "</svg>\n") {
+			t.Errorf("expected an empty SVG. received %s", buf.String())
+		}
+	})
+}
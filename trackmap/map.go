@@ -0,0 +1,136 @@
+package trackmap
+
+import (
+	"math"
+)
+
+// Mean radius of the earth in meters
+const earthRadius float64 = 6371000
+
+// Source of the positions of a Map.
+type Source string
+
+const (
+	// GPS positions from the Lat and Lon variables
+	GPS Source = "gps"
+	// Velocity positions integrated from the VelocityX, VelocityY and Yaw variables
+	Velocity Source = "velocity"
+)
+
+// Point is a single position of the outline of the track.
+type Point struct {
+	// Pct is the LapDistPct of the position
+	Pct float64 `json:"pct"`
+	// X and Y are the distances in meters east and north of the centre of the map
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	// Lat and Lon are the coordinates in decimal degrees of the position. These are 0 when the map is not
+	// georeferenced.
+	Lat float64 `json:"lat,omitempty"`
+	Lon float64 `json:"lon,omitempty"`
+}
+
+// Map is the outline of a track as a polyline indexed by LapDistPct.
+type Map struct {
+	TrackID         int    `json:"track_id"`
+	TrackName       string `json:"track_name"`
+	TrackConfigName string `json:"track_config_name"`
+	// Lap the map was built from
+	Lap int `json:"lap"`
+	// Source of the positions
+	Source Source `json:"source"`
+	// Aligned indicates that the map is aligned with north. Maps built from Velocity without the
+	// TrackNorthOffset of the session are rotated by an unknown angle.
+	Aligned bool `json:"aligned"`
+	// Georeferenced indicates that the Lat and Lon of the points are available
+	Georeferenced bool `json:"georeferenced"`
+	// Points of the outline, ordered by Pct. The outline is closed from the last point to the first.
+	Points []Point `json:"points"`
+}
+
+// At returns the position at the given LapDistPct, interpolated between the points on either side of it.
+func (m Map) At(pct float64) Point {
+	n := len(m.Points)
+	if n == 0 {
+		return Point{}
+	}
+
+	pct -= math.Floor(pct)
+
+	// The first point after the given position, wrapping around to the first point of the next lap
+	next := 0
+	for next < n && m.Points[next].Pct < pct {
+		next++
+	}
+
+	prev := (next - 1 + n) % n
+	next %= n
+
+	before, after := m.Points[prev], m.Points[next]
+	span := after.Pct - before.Pct
+	offset := pct - before.Pct
+	if span <= 0 {
+		span++
+	}
+	if offset < 0 {
+		offset++
+	}
+
+	fraction := 0.0
+	if span > 0 {
+		fraction = offset / span
+	}
+
+	return Point{
+		Pct: pct,
+		X:   before.X + (after.X-before.X)*fraction,
+		Y:   before.Y + (after.Y-before.Y)*fraction,
+		Lat: before.Lat + (after.Lat-before.Lat)*fraction,
+		Lon: before.Lon + (after.Lon-before.Lon)*fraction,
+	}
+}
+
+// Bounds returns the smallest and largest X and Y of the points
+func (m Map) Bounds() (minX, minY, maxX, maxY float64) {
+	if len(m.Points) == 0 {
+		return 0, 0, 0, 0
+	}
+
+	minX, minY = math.Inf(1), math.Inf(1)
+	maxX, maxY = math.Inf(-1), math.Inf(-1)
+
+	for _, p := range m.Points {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+
+	return minX, minY, maxX, maxY
+}
+
+// Length of the outline in meters
+func (m Map) Length() float64 {
+	length := 0.0
+
+	for idx, p := range m.Points {
+		next := m.Points[(idx+1)%len(m.Points)]
+		length += math.Hypot(next.X-p.X, next.Y-p.Y)
+	}
+
+	return length
+}
+
+// project the given coordinates to meters east and north of the origin
+func project(lat, lon, originLat, originLon float64) (float64, float64) {
+	x := (lon - originLon) * math.Pi / 180 * earthRadius * math.Cos(originLat*math.Pi/180)
+	y := (lat - originLat) * math.Pi / 180 * earthRadius
+
+	return x, y
+}
+
+// unproject the given meters east and north of the origin to coordinates
+func unproject(x, y, originLat, originLon float64) (float64, float64) {
+	lat := originLat + y/earthRadius*180/math.Pi
+	lon := originLon + x/(earthRadius*math.Cos(originLat*math.Pi/180))*180/math.Pi
+
+	return lat, lon
+}
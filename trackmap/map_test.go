@@ -0,0 +1,76 @@
+package trackmap
+
+import (
+	"math"
+	"testing"
+)
+
+// squareMap is a map of a square with sides of 100 meters, starting at its bottom left corner
+func squareMap() Map {
+	return Map{
+		TrackID:   1,
+		TrackName: "Square",
+		Lap:       2,
+		Source:    Velocity,
+		Aligned:   true,
+		Points: []Point{
+			{Pct: 0.125, X: -50, Y: -50},
+			{Pct: 0.375, X: 50, Y: -50},
+			{Pct: 0.625, X: 50, Y: 50},
+			{Pct: 0.875, X: -50, Y: 50},
+		},
+	}
+}
+
+func TestMapAt(t *testing.T) {
+	m := squareMap()
+
+	tests := []struct {
+		pct  float64
+		x, y float64
+	}{
+		{0.125, -50, -50},
+		{0.25, 0, -50},
+		{0.5, 50, 0},
+		{0.875, -50, 50},
+		// Positions before the first and after the last point are interpolated between them
+		{0.95, -50, 20},
+		{0.05, -50, -20},
+		{1.25, 0, -50},
+	}
+
+	for _, test := range tests {
+		if p := m.At(test.pct); math.Abs(p.X-test.x) > 0.0001 || math.Abs(p.Y-test.y) > 0.0001 {
+			t.Errorf("expected the position at %.3f to be %.1f, %.1f. received %f, %f", test.pct, test.x, test.y, p.X, p.Y)
+		}
+	}
+
+	if p := (Map{}).At(0.5); p != (Point{}) {
+		t.Errorf("expected an empty point without points. received %+v", p)
+	}
+}
+
+func TestMapBounds(t *testing.T) {
+	minX, minY, maxX, maxY := squareMap().Bounds()
+	if minX != -50 || minY != -50 || maxX != 50 || maxY != 50 {
+		t.Errorf("expected bounds of -50, -50, 50, 50. received %f, %f, %f, %f", minX, minY, maxX, maxY)
+	}
+}
+
+func TestMapLength(t *testing.T) {
+	if length := squareMap().Length(); length != 400 {
+		t.Errorf("expected a length of %d. received %f", 400, length)
+	}
+}
+
+func TestProject(t *testing.T) {
+	x, y := project(47.221205, 14.766722, 47.220305, 14.766722)
+	if math.Abs(x) > 0.0001 || math.Abs(y-100.07) > 0.01 {
+		t.Errorf("expected a position 100 meters north. received %f, %f", x, y)
+	}
+
+	lat, lon := unproject(250, -120, 47.220305, 14.766722)
+	if x, y := project(lat, lon, 47.220305, 14.766722); math.Abs(x-250) > 0.0001 || math.Abs(y+120) > 0.0001 {
+		t.Errorf("expected unproject() to reverse project(). received %f, %f", x, y)
+	}
+}
@@ -0,0 +1,87 @@
+package tyres
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/teamjorge/ibt"
+)
+
+// Conversions of the pressure units used in car setups to kPa
+var pressureUnits = map[string]float64{
+	"kpa": 1,
+	"psi": 6.894757,
+	"bar": 100,
+}
+
+// Names of the setup items containing the starting pressure of a tyre
+var pressureItems = []string{"StartingPressure", "ColdPressure"}
+
+// setupName of the wheel as used in the subcategories of car setups
+func (w Wheel) setupName() string {
+	switch w {
+	case LF:
+		return "LeftFront"
+	case RF:
+		return "RightFront"
+	case LR:
+		return "LeftRear"
+	case RR:
+		return "RightRear"
+	}
+
+	return ""
+}
+
+// SetupCamber returns the camber in degrees of the given wheel in the car setup.
+//
+// The camber is found in the subcategory of the wheel, such as LeftFront, regardless of its category, as
+// the categories differ between cars. False is returned when the setup does not contain the camber.
+func SetupCamber(setup *ibt.CarSetup, wheel Wheel) (float64, bool) {
+	value, _, ok := setupValue(setup, wheel.setupName(), "Camber")
+
+	return value, ok
+}
+
+// SetupPressure returns the starting pressure in kPa of the given wheel in the car setup.
+//
+// The pressure is found in the tyre subcategory of the wheel, such as LeftFrontTire, and converted from psi
+// or bar when required. False is returned when the setup does not contain the pressure.
+func SetupPressure(setup *ibt.CarSetup, wheel Wheel) (float64, bool) {
+	for _, item := range pressureItems {
+		value, unit, ok := setupValue(setup, wheel.setupName()+"Tire", item)
+		if !ok {
+			continue
+		}
+
+		if conversion, ok := pressureUnits[strings.ToLower(unit)]; ok {
+			return value * conversion, true
+		}
+	}
+
+	return 0, false
+}
+
+// setupValue returns the signed value and unit of the first parsed value of the setup item in the given
+// subcategory of any category. When multiple categories contain the item, the first category by name is used.
+func setupValue(setup *ibt.CarSetup, subCategory, itemName string) (float64, string, bool) {
+	keys := make(ibt.CarSetupKeys, 0)
+	for key, item := range setup.Values {
+		if key.SubCategory() == subCategory && key.ItemName() == itemName && item.IsParsed() {
+			keys = append(keys, key)
+		}
+	}
+	sort.Sort(keys)
+
+	for _, key := range keys {
+		parsed := setup.Values[key].Parsed[0]
+		value := parsed.NumericalValue
+		if parsed.NumericalSign < 0 {
+			value = -value
+		}
+
+		return value, parsed.MeasurementUnit, true
+	}
+
+	return 0, "", false
+}
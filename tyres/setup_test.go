@@ -0,0 +1,77 @@
+package tyres
+
+import (
+	"math"
+	"testing"
+
+	"github.com/teamjorge/ibt"
+)
+
+func TestSetup(t *testing.T) {
+	t.Run("test SetupCamber and SetupPressure", func(t *testing.T) {
+		stubs, err := ibt.ParseStubs("../.testing/valid_test_file.ibt")
+		if err != nil {
+			t.Errorf("failed to parse stubs for testing file - %v", err)
+			return
+		}
+		defer stubs.Close()
+
+		setup := stubs[0].CarSetup()
+
+		expected := map[Wheel][2]float64{
+			LF: {-3.15, 165.5},
+			RF: {-3.15, 165.5},
+			LR: {-1.57, 144.8},
+			RR: {-1.57, 144.8},
+		}
+
+		for wheel, want := range expected {
+			camber, ok := SetupCamber(setup, wheel)
+			if !ok || camber != want[0] {
+				t.Errorf("expected a camber of %.2f for %s. received %f", want[0], wheel, camber)
+			}
+
+			pressure, ok := SetupPressure(setup, wheel)
+			if !ok || math.Abs(pressure-want[1]) > 0.001 {
+				t.Errorf("expected a pressure of %.1f for %s. received %f", want[1], wheel, pressure)
+			}
+		}
+	})
+
+	t.Run("test SetupPressure units", func(t *testing.T) {
+		setup := &ibt.CarSetup{Values: make(ibt.CarSetupDetails)}
+		setup.Values.Add("Tires", "LeftFrontTire", "ColdPressure", &ibt.CarSetupItem{
+			RawValue: "1.8 bar", Parsed: ibt.ParseSetupItem("1.8 bar"),
+		})
+		setup.Values.Add("Tires", "RightFrontTire", "ColdPressure", &ibt.CarSetupItem{
+			RawValue: "1.8 atm", Parsed: ibt.ParseSetupItem("1.8 atm"),
+		})
+
+		if pressure, ok := SetupPressure(setup, LF); !ok || math.Abs(pressure-180) > 0.001 {
+			t.Errorf("expected a pressure of %d kPa. received %f", 180, pressure)
+		}
+		if _, ok := SetupPressure(setup, RF); ok {
+			t.Error("expected SetupPressure() to fail for an unknown unit")
+		}
+		if _, ok := SetupCamber(setup, LF); ok {
+			t.Error("expected SetupCamber() to fail without a camber")
+		}
+	})
+
+	t.Run("test SetupCamber in multiple categories", func(t *testing.T) {
+		setup := &ibt.CarSetup{Values: make(ibt.CarSetupDetails)}
+		for _, category := range []string{"Suspension", "Chassis", "Tires"} {
+			setup.Values.Add(category, "LeftFront", "Camber", &ibt.CarSetupItem{
+				RawValue: category, Parsed: ibt.ParseSetupItem(map[string]string{"Suspension": "-2.0 deg", "Chassis": "-3.0 deg", "Tires": "-1.0 deg"}[category]),
+			})
+		}
+
+		// The camber of the first category by name is used every time
+		for idx := 0; idx < 10; idx++ {
+			if camber, ok := SetupCamber(setup, LF); !ok || camber != -3 {
+				t.Errorf("expected the camber of the Chassis category. received %f", camber)
+				break
+			}
+		}
+	})
+}
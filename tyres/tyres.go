@@ -0,0 +1,410 @@
+// Package tyres reports the temperatures, pressures and wear of the tyres over every stint.
+//
+// The carcass temperatures and tread remaining of the tyres are only updated by iRacing while the car is in
+// the pit stall, so an Analyzer records them as snapshots at the start of each stint and when the car next
+// arrives in the pit stall. The surface temperatures and pressures are updated continuously and are averaged
+// over the stint, from which the temperature spread across the tread and a camber hint are derived. When
+// the stub contains the car setup, the report is joined with the camber and starting pressure of each tyre.
+package tyres
+
+import (
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/laps"
+	"github.com/teamjorge/ibt/stints"
+)
+
+const (
+	// Default difference in °C between the inner and outer surface temperature of a tyre with a good camber
+	defaultTargetSpread float64 = 7
+	// Default number of °C either side of the target spread within which no camber change is suggested
+	defaultSpreadTolerance float64 = 3
+)
+
+func init() {
+	ibt.Register("tyres", func(params ibt.Params) (ibt.Processor, error) {
+		var opts Options
+		if err := params.Decode(&opts); err != nil {
+			return nil, err
+		}
+
+		return NewAnalyzer(opts), nil
+	})
+}
+
+// Wheel is the position of a tyre on the car.
+type Wheel string
+
+const (
+	LF Wheel = "LF"
+	RF Wheel = "RF"
+	LR Wheel = "LR"
+	RR Wheel = "RR"
+)
+
+// Wheels of the car in the order they are reported
+var Wheels = []Wheel{LF, RF, LR, RR}
+
+// left indicates whether the tyre is on the left of the car, where its inner edge is on the right
+func (w Wheel) left() bool { return w == LF || w == LR }
+
+// CamberHint suggests a change of camber from the temperature spread across a tyre.
+type CamberHint string
+
+const (
+	// CamberOK is a spread within the tolerance of the target spread
+	CamberOK CamberHint = ""
+	// MoreNegative camber is suggested when the inner edge is not hot enough compared to the outer edge
+	MoreNegative CamberHint = "more-negative"
+	// LessNegative camber is suggested when the inner edge is too hot compared to the outer edge
+	LessNegative CamberHint = "less-negative"
+)
+
+// Options configures the assessment of the tyres.
+type Options struct {
+	// TargetSpread is the number of °C by which the inner edge of a tyre should be hotter than its outer
+	// edge. Defaults to 7.
+	TargetSpread float64 `yaml:"target_spread"`
+	// SpreadTolerance is the number of °C either side of the TargetSpread within which the camber is
+	// considered correct. Defaults to 3.
+	SpreadTolerance float64 `yaml:"spread_tolerance"`
+}
+
+// Tread contains a value for the inner, middle and outer part of a tyre.
+type Tread struct {
+	Inner  float64
+	Middle float64
+	Outer  float64
+}
+
+// Spread is the difference between the inner and outer values
+func (t Tread) Spread() float64 { return t.Inner - t.Outer }
+
+// Mean of the inner, middle and outer values
+func (t Tread) Mean() float64 { return (t.Inner + t.Middle + t.Outer) / 3 }
+
+// Tyre is the report of a single tyre over a stint.
+type Tyre struct {
+	Wheel Wheel
+	// Surface is the average surface temperature in °C while on track
+	Surface Tread
+	// Spread is the difference in °C between the average inner and outer surface temperature
+	Spread float64
+	// Camber change suggested by the Spread
+	Camber CamberHint
+	// StartCarcass and EndCarcass are the carcass temperatures in °C at the start of the stint and when
+	// the car arrived in the pit stall, or at the end of the stint if it did not
+	StartCarcass Tread
+	EndCarcass   Tread
+	// ColdPressure in kPa, as set in the garage
+	ColdPressure float64
+	// StartPressure and EndPressure are the pressures in kPa at the start and end of the stint
+	StartPressure float64
+	EndPressure   float64
+	// PeakPressure is the highest pressure in kPa during the stint
+	PeakPressure float64
+	// BuildUp is the increase in pressure in kPa from the start to the end of the stint
+	BuildUp float64
+	// StartWear and EndWear are the fractions of tread remaining at the start of the stint and when the car
+	// arrived in the pit stall, or at the end of the stint if it did not
+	StartWear Tread
+	EndWear   Tread
+	// WearRate is the average fraction of tread worn per full lap of the stint
+	WearRate float64
+	// SetupCamber in degrees and SetupPressure in kPa of the tyre in the car setup. These are 0 when the
+	// setup is not available.
+	SetupCamber   float64
+	SetupPressure float64
+}
+
+// Stint is the report of the tyres over a single stint.
+type Stint struct {
+	// Number of the stint, as detected by the stints package
+	Number int
+	// Stub is the index of the stub within the group being processed in which the stint started
+	Stub int
+	// Filename of the stub
+	Filename string
+	// Laps is the number of full laps of the stint
+	Laps int
+	// Compound of the tyres, as reported by PlayerTireCompound
+	Compound int
+	Tyres    map[Wheel]Tyre
+}
+
+// Result of the tyres analysed by an Analyzer.
+type Result struct {
+	Stints []Stint
+}
+
+// Analyzer is a processor that reports the tyres over every stint.
+type Analyzer struct {
+	opts     Options
+	detector *stints.Detector
+	stints   []*stintTyres
+
+	// setup of the current stub
+	setup          *ibt.CarSetup
+	prevInPitStall bool
+	// ended is the most recent stint awaiting the snapshot of the tyres in the pit stall
+	ended *stintTyres
+}
+
+// stintTyres accumulates the tyres of a stint
+type stintTyres struct {
+	stint stints.Stint
+	tyres map[Wheel]*tyreSum
+	// ticks on track included in the surface temperatures
+	ticks int
+}
+
+// tyreSum accumulates a single tyre of a stint
+type tyreSum struct {
+	Tyre
+	surface Tread
+}
+
+// tyreTick is the part of a tick describing a single tyre
+type tyreTick struct {
+	surface      Tread
+	carcass      Tread
+	wear         Tread
+	pressure     float64
+	coldPressure float64
+}
+
+// NewAnalyzer creates a tyre Analyzer with the given options.
+func NewAnalyzer(opts Options) *Analyzer {
+	if opts.TargetSpread <= 0 {
+		opts.TargetSpread = defaultTargetSpread
+	}
+	if opts.SpreadTolerance <= 0 {
+		opts.SpreadTolerance = defaultSpreadTolerance
+	}
+
+	return &Analyzer{opts: opts, detector: stints.NewDetector(laps.Options{})}
+}
+
+// Whitelist of the variables required for reporting the tyres
+func (a *Analyzer) Whitelist() []string {
+	whitelist := a.detector.Whitelist()
+
+	for _, wheel := range Wheels {
+		for _, name := range []string{
+			"tempL", "tempM", "tempR", "tempCL", "tempCM", "tempCR", "wearL", "wearM", "wearR", "pressure", "coldPressure",
+		} {
+			whitelist = append(whitelist, string(wheel)+name)
+		}
+	}
+
+	return whitelist
+}
+
+// StartStub notifies the stint detection of the new stub
+func (a *Analyzer) StartStub(stub ibt.StubInfo) error {
+	a.setup = stub.CarSetup
+	a.prevInPitStall = false
+
+	return a.detector.StartStub(stub)
+}
+
+// EndStub notifies the stint detection of the end of the stub
+func (a *Analyzer) EndStub(stub ibt.StubInfo) error {
+	return a.detector.EndStub(stub)
+}
+
+// Process the tick without a TickContext. The stints of each set of tyres are timed with the SessionTime of
+// the tick.
+func (a *Analyzer) Process(input ibt.Tick, hasNext bool, session *headers.Session) error {
+	return a.ProcessContext(input, ibt.ContextFromTick(input), hasNext, session)
+}
+
+// ProcessContext adds the tyres of the tick to the stint in progress, or records the snapshot of the tyres
+// when the car arrives in the pit stall
+func (a *Analyzer) ProcessContext(input ibt.Tick, tickCtx ibt.TickContext, hasNext bool, session *headers.Session) error {
+	if err := a.detector.ProcessContext(input, tickCtx, hasNext, session); err != nil {
+		return err
+	}
+
+	tyres := make(map[Wheel]tyreTick, len(Wheels))
+	for _, wheel := range Wheels {
+		tyre, err := readTyre(input, wheel)
+		if err != nil {
+			return err
+		}
+		tyres[wheel] = tyre
+	}
+
+	inPitStall, _ := ibt.GetTickValue[bool](input, "PlayerCarInPitStall")
+
+	current, onTrack := a.detector.Current()
+	switch {
+	case onTrack:
+		acc := a.stint(current, tyres)
+		acc.add(tyres)
+		a.ended = acc
+	case inPitStall && !a.prevInPitStall && a.ended != nil:
+		// The snapshot taken on arrival in the pit stall describes the tyres at the end of the stint
+		for wheel, tyre := range tyres {
+			acc := a.ended.tyres[wheel]
+			acc.EndCarcass = tyre.carcass
+			acc.EndWear = tyre.wear
+		}
+		a.ended = nil
+	}
+
+	a.prevInPitStall = inPitStall
+
+	return nil
+}
+
+// stint returns the accumulator of the given stint, starting it with the given tyres if it is new
+func (a *Analyzer) stint(stint stints.Stint, tyres map[Wheel]tyreTick) *stintTyres {
+	if len(a.stints) > 0 && a.stints[len(a.stints)-1].stint.Number == stint.Number {
+		return a.stints[len(a.stints)-1]
+	}
+
+	acc := &stintTyres{stint: stint, tyres: make(map[Wheel]*tyreSum, len(Wheels))}
+
+	for wheel, tyre := range tyres {
+		sum := &tyreSum{Tyre: Tyre{
+			Wheel:         wheel,
+			StartCarcass:  tyre.carcass,
+			StartWear:     tyre.wear,
+			ColdPressure:  tyre.coldPressure,
+			StartPressure: tyre.pressure,
+		}}
+
+		if a.setup != nil {
+			sum.SetupCamber, _ = SetupCamber(a.setup, wheel)
+			sum.SetupPressure, _ = SetupPressure(a.setup, wheel)
+		}
+
+		acc.tyres[wheel] = sum
+	}
+
+	a.stints = append(a.stints, acc)
+
+	return acc
+}
+
+// add the tyres of a tick on track to the stint
+func (s *stintTyres) add(tyres map[Wheel]tyreTick) {
+	s.ticks++
+
+	for wheel, tyre := range tyres {
+		acc := s.tyres[wheel]
+
+		acc.surface.Inner += tyre.surface.Inner
+		acc.surface.Middle += tyre.surface.Middle
+		acc.surface.Outer += tyre.surface.Outer
+
+		acc.EndCarcass = tyre.carcass
+		acc.EndWear = tyre.wear
+		acc.EndPressure = tyre.pressure
+		acc.PeakPressure = max(acc.PeakPressure, tyre.pressure)
+	}
+}
+
+// readTyre reads the given tyre from the tick. Only the surface temperatures are required.
+func readTyre(input ibt.Tick, wheel Wheel) (tyreTick, error) {
+	var tyre tyreTick
+	var err error
+
+	prefix := string(wheel)
+
+	if tyre.surface, err = readTread(input, wheel, prefix+"tempL", prefix+"tempM", prefix+"tempR"); err != nil {
+		return tyre, err
+	}
+
+	tyre.carcass, _ = readTread(input, wheel, prefix+"tempCL", prefix+"tempCM", prefix+"tempCR")
+	tyre.wear, _ = readTread(input, wheel, prefix+"wearL", prefix+"wearM", prefix+"wearR")
+
+	pressure, _ := ibt.GetTickValue[float32](input, prefix+"pressure")
+	coldPressure, _ := ibt.GetTickValue[float32](input, prefix+"coldPressure")
+	tyre.pressure, tyre.coldPressure = float64(pressure), float64(coldPressure)
+
+	return tyre, nil
+}
+
+// readTread reads the values of the left, middle and right of a tyre, where the inner edge of a tyre on the
+// left of the car is its right
+func readTread(input ibt.Tick, wheel Wheel, left, middle, right string) (Tread, error) {
+	var values [3]float32
+
+	for idx, name := range []string{left, middle, right} {
+		value, err := ibt.GetTickValue[float32](input, name)
+		if err != nil {
+			return Tread{}, err
+		}
+		values[idx] = value
+	}
+
+	tread := Tread{Inner: float64(values[0]), Middle: float64(values[1]), Outer: float64(values[2])}
+	if wheel.left() {
+		tread.Inner, tread.Outer = tread.Outer, tread.Inner
+	}
+
+	return tread, nil
+}
+
+// camber suggests a change of camber for the given spread
+func (a *Analyzer) camber(spread float64) CamberHint {
+	switch {
+	case spread > a.opts.TargetSpread+a.opts.SpreadTolerance:
+		return LessNegative
+	case spread < a.opts.TargetSpread-a.opts.SpreadTolerance:
+		return MoreNegative
+	}
+
+	return CamberOK
+}
+
+// Result of the tyres reported so far
+func (a *Analyzer) Result() Result {
+	var result Result
+
+	// The laps of each stint are only assigned once the stint is complete
+	fullLaps := make(map[int]int)
+	for _, stint := range a.detector.Result().Stints {
+		for _, lap := range stint.Laps {
+			if lap.Full {
+				fullLaps[stint.Number]++
+			}
+		}
+	}
+
+	for _, acc := range a.stints {
+		stint := Stint{
+			Number:   acc.stint.Number,
+			Stub:     acc.stint.Stub,
+			Filename: acc.stint.Filename,
+			Laps:     fullLaps[acc.stint.Number],
+			Compound: acc.stint.Compound,
+			Tyres:    make(map[Wheel]Tyre, len(acc.tyres)),
+		}
+
+		for wheel, sum := range acc.tyres {
+			tyre := sum.Tyre
+
+			if acc.ticks > 0 {
+				n := float64(acc.ticks)
+				tyre.Surface = Tread{Inner: sum.surface.Inner / n, Middle: sum.surface.Middle / n, Outer: sum.surface.Outer / n}
+			}
+
+			tyre.Spread = tyre.Surface.Spread()
+			tyre.Camber = a.camber(tyre.Spread)
+			tyre.BuildUp = tyre.EndPressure - tyre.StartPressure
+
+			if stint.Laps > 0 {
+				tyre.WearRate = (tyre.StartWear.Mean() - tyre.EndWear.Mean()) / float64(stint.Laps)
+			}
+
+			stint.Tyres[wheel] = tyre
+		}
+
+		result.Stints = append(result.Stints, stint)
+	}
+
+	return result
+}
@@ -0,0 +1,225 @@
+package tyres
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/teamjorge/ibt"
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/ibttest"
+)
+
+var testSession = &headers.Session{
+	CarSetup: map[string]interface{}{
+		"UpdateCount": 1,
+		"Chassis": map[string]interface{}{
+			"LeftFront": map[string]interface{}{"Camber": "-3.15 deg"},
+		},
+		"TiresAero": map[string]interface{}{
+			"LeftFrontTire": map[string]interface{}{"StartingPressure": "24.0 psi"},
+		},
+	},
+}
+
+// testSurface is the left, middle and right surface temperature of each tyre. The inner edge of the left
+// front is too hot, while the inner edge of the left rear is not hot enough.
+var testSurface = map[Wheel][3]float32{
+	LF: {80, 85, 95},
+	RF: {88, 85, 81},
+	LR: {85, 84, 86},
+	RR: {88, 85, 81},
+}
+
+// testTicks creates the ticks of 60 second laps from 0.9 of lap 1 until the middle of lap 7. The car enters
+// pit road at 0.9 of lap 4 and stops in the pit stall at 0.98 for 5 seconds, during which new tyres are
+// fitted halfway through. The car leaves pit road at 0.05 of lap 5.
+//
+// The pressures build up by 0.1 kPa per second after leaving pit road to a maximum of 180 kPa. The carcass
+// temperatures and wear are only updated in the pit stall, where the worn tyres have 97% of their tread
+// remaining.
+func testTicks() []ibt.Tick {
+	ticks := make([]ibt.Tick, 0)
+	pos, stalled, stintStart := 0.9, 0, 0.0
+	carcass, wear, pressure := float32(60), float32(1), float32(165)
+
+	for idx := 0; ; idx++ {
+		sessionTime := float64(idx) / 60
+		lap, pct := 1+int(math.Floor(pos)), pos-math.Floor(pos)
+		if lap == 7 && pct >= 0.5 {
+			break
+		}
+
+		onPitRoad := (lap == 4 && pct >= 0.9) || (lap == 5 && pct < 0.05)
+		inPitStall := lap == 4 && pct >= 0.98 && stalled < 300
+
+		switch {
+		case inPitStall && stalled < 150:
+			carcass, wear = 90, 0.97
+		case inPitStall:
+			carcass, wear, pressure = 60, 1, 165
+		case onPitRoad:
+			stintStart = sessionTime
+		default:
+			pressure = float32(math.Min(180, 165+0.1*(sessionTime-stintStart)))
+		}
+
+		tick := ibt.Tick{
+			"SessionTime":         sessionTime,
+			"Lap":                 lap,
+			"LapDistPct":          float32(pct),
+			"OnPitRoad":           onPitRoad,
+			"PlayerCarInPitStall": inPitStall,
+		}
+
+		for wheel, surface := range testSurface {
+			prefix := string(wheel)
+			tick[prefix+"tempL"], tick[prefix+"tempM"], tick[prefix+"tempR"] = surface[0], surface[1], surface[2]
+			tick[prefix+"tempCL"], tick[prefix+"tempCM"], tick[prefix+"tempCR"] = carcass, carcass, carcass
+			tick[prefix+"wearL"], tick[prefix+"wearM"], tick[prefix+"wearR"] = wear, wear, wear
+			tick[prefix+"pressure"], tick[prefix+"coldPressure"] = pressure, float32(165)
+		}
+
+		ticks = append(ticks, tick)
+
+		if inPitStall {
+			stalled++
+			continue
+		}
+		pos += 1.0 / 3600
+	}
+
+	return ticks
+}
+
+var testStub = ibt.StubInfo{Index: 0, Filename: "test.ibt"}
+
+func TestAnalyzer(t *testing.T) {
+	t.Run("test Analyzer temperatures", func(t *testing.T) {
+		analyzer := NewAnalyzer(Options{})
+		ibttest.Process(t, analyzer, testStub, testTicks(), testSession)
+
+		result := analyzer.Result()
+		if len(result.Stints) != 2 {
+			t.Errorf("expected %d stints. received %d", 2, len(result.Stints))
+			return
+		}
+
+		expected := map[Wheel]struct {
+			surface Tread
+			camber  CamberHint
+		}{
+			LF: {Tread{Inner: 95, Middle: 85, Outer: 80}, LessNegative},
+			RF: {Tread{Inner: 88, Middle: 85, Outer: 81}, CamberOK},
+			LR: {Tread{Inner: 86, Middle: 84, Outer: 85}, MoreNegative},
+			RR: {Tread{Inner: 88, Middle: 85, Outer: 81}, CamberOK},
+		}
+
+		for _, stint := range result.Stints {
+			for wheel, want := range expected {
+				tyre := stint.Tyres[wheel]
+				if tyre.Surface != want.surface || tyre.Spread != want.surface.Spread() {
+					t.Errorf("expected %s surface temperatures of %+v in stint %d. received %+v", wheel, want.surface, stint.Number, tyre.Surface)
+				}
+				if tyre.Camber != want.camber {
+					t.Errorf("expected %s camber hint %q in stint %d. received %q", wheel, want.camber, stint.Number, tyre.Camber)
+				}
+			}
+		}
+	})
+
+	t.Run("test Analyzer pressures and wear", func(t *testing.T) {
+		analyzer := NewAnalyzer(Options{})
+		ibttest.Process(t, analyzer, testStub, testTicks(), testSession)
+
+		result := analyzer.Result()
+		if len(result.Stints) != 2 {
+			t.Errorf("expected %d stints. received %d", 2, len(result.Stints))
+			return
+		}
+
+		first, second := result.Stints[0], result.Stints[1]
+		if first.Laps != 3 || second.Laps != 2 {
+			t.Errorf("expected %d and %d full laps. received %d and %d", 3, 2, first.Laps, second.Laps)
+		}
+
+		tyre := first.Tyres[RR]
+		if tyre.ColdPressure != 165 || tyre.StartPressure != 165 || tyre.PeakPressure != 180 || math.Abs(tyre.BuildUp-15) > 0.001 {
+			t.Errorf("expected a build up from %d to %d kPa. received %+v", 165, 180, tyre)
+		}
+		if tyre.EndCarcass.Middle != 90 || tyre.EndWear.Middle != float64(float32(0.97)) {
+			t.Errorf("expected the carcass and wear of the pit stall. received %+v and %+v", tyre.EndCarcass, tyre.EndWear)
+		}
+		if math.Abs(tyre.WearRate-0.01) > 0.0001 {
+			t.Errorf("expected a wear rate of %.2f per lap. received %f", 0.01, tyre.WearRate)
+		}
+
+		tyre = second.Tyres[RR]
+		if tyre.StartCarcass.Middle != 60 || tyre.StartWear.Middle != 1 || tyre.WearRate != 0 || math.Abs(tyre.StartPressure-165) > 0.01 {
+			t.Errorf("expected new tyres in the second stint. received %+v", tyre)
+		}
+	})
+
+	t.Run("test Analyzer setup", func(t *testing.T) {
+		stub := ibt.StubInfo{Index: 0, Filename: "test.ibt", CarSetup: ibt.ParseCarSetup(testSession)}
+
+		analyzer := NewAnalyzer(Options{})
+		ibttest.Process(t, analyzer, stub, testTicks(), testSession)
+
+		tyres := analyzer.Result().Stints[0].Tyres
+		if tyres[LF].SetupCamber != -3.15 || math.Abs(tyres[LF].SetupPressure-165.47) > 0.01 {
+			t.Errorf("expected a camber of %.2f and pressure of %.2f. received %f and %f", -3.15, 165.47, tyres[LF].SetupCamber, tyres[LF].SetupPressure)
+		}
+		if tyres[RF].SetupCamber != 0 || tyres[RF].SetupPressure != 0 {
+			t.Errorf("expected no setup for %s. received %+v", RF, tyres[RF])
+		}
+	})
+
+	t.Run("test Analyzer options", func(t *testing.T) {
+		analyzer := NewAnalyzer(Options{TargetSpread: 15, SpreadTolerance: 1})
+		ibttest.Process(t, analyzer, testStub, testTicks(), nil)
+
+		tyres := analyzer.Result().Stints[0].Tyres
+		if tyres[LF].Camber != CamberOK || tyres[RF].Camber != MoreNegative {
+			t.Errorf("expected camber hints of %q and %q. received %q and %q", CamberOK, MoreNegative, tyres[LF].Camber, tyres[RF].Camber)
+		}
+	})
+
+	t.Run("test Analyzer missing variables", func(t *testing.T) {
+		tick := ibt.Tick{"Lap": 1, "LapDistPct": float32(0.1), "OnPitRoad": false}
+		if err := NewAnalyzer(Options{}).Process(tick, true, nil); err == nil {
+			t.Error("expected Process() to return an error when the tyre temperatures are missing")
+		}
+	})
+
+	t.Run("test Analyzer from registry", func(t *testing.T) {
+		processor, err := ibt.DefaultRegistry.NewProcessor("tyres", ibt.Params{"target_spread": 5})
+		if err != nil {
+			t.Errorf("expected NewProcessor() to run without err. received error: %v", err)
+			return
+		}
+
+		if analyzer := processor.(*Analyzer); analyzer.opts.TargetSpread != 5 {
+			t.Errorf("expected a target spread of %d. received %f", 5, analyzer.opts.TargetSpread)
+		}
+	})
+
+	t.Run("test Analyzer with ibt.Process", func(t *testing.T) {
+		stubs, err := ibt.ParseStubs("../.testing/valid_test_file.ibt")
+		if err != nil {
+			t.Errorf("failed to parse stubs for testing file - %v", err)
+			return
+		}
+		defer stubs.Close()
+
+		analyzer := NewAnalyzer(Options{})
+		if err := ibt.Process(context.Background(), stubs, analyzer); err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		// The car remains on pit road throughout the testing file
+		if result := analyzer.Result(); len(result.Stints) != 0 {
+			t.Errorf("expected no stints. received %+v", result)
+		}
+	})
+}
@@ -6,7 +6,7 @@
 //   - Store - FIFO fixed-size storage with fast-lookup.
 //   - List - Fixed-size doubly-linked list. (Used by Store for tracking order).
 //   - Node - Item type by both Store and List.
-//   - Simple - An extremely simplistic FIFO store implementation.
+//   - Simple - An extremely simplistic FIFO store implementation that can hold values of any type.
 //
 // # Scenario
 //
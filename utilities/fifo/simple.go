@@ -5,9 +5,10 @@ const (
 )
 
 // Simple FIFO list implementation with size constraints.
-type Simple[k comparable] struct {
-	head int
-	size int
+type Simple[k any] struct {
+	head  int
+	size  int
+	count int
 
 	l []k
 }
@@ -17,7 +18,7 @@ type Simple[k comparable] struct {
 //   - size: specifies the fixed amount of values the Simple can hold
 //
 // If size is equal to or less than 0, the default size (5) will be used
-func NewSimple[k comparable](size int) *Simple[k] {
+func NewSimple[k any](size int) *Simple[k] {
 	if size <= 0 {
 		size = simpleDefaultSize
 	}
@@ -31,10 +32,15 @@ func NewSimple[k comparable](size int) *Simple[k] {
 func (s *Simple[k]) Add(item k) {
 	s.l[s.head] = item
 	s.head = (s.head + 1) % s.size
+	if s.count < s.size {
+		s.count++
+	}
 }
 
 // Get the item at the provided offset.
 //
+// An offset of 0 refers to the oldest item in the list.
+//
 // A nil value of type k will be returned in the following scenarios:
 //
 //   - Offset is less than zero
@@ -42,9 +48,28 @@ func (s *Simple[k]) Add(item k) {
 //   - A value does not exist in the specified index
 func (s *Simple[k]) Get(offset int) k {
 	var def k
-	if offset < 0 || offset >= len(s.l) || offset >= s.size {
+	if offset < 0 || offset >= len(s.l) || offset >= s.count {
 		return def
 	}
 
-	return s.l[(s.head+offset)%s.size]
+	oldest := (s.head - s.count + s.size) % s.size
+
+	return s.l[(oldest+offset)%s.size]
+}
+
+// Len provides the number of items currently in the list
+func (s *Simple[k]) Len() int { return s.count }
+
+// Size provides the fixed amount of values the list can hold
+func (s *Simple[k]) Size() int { return s.size }
+
+// Reset empties the list without releasing its underlying storage
+func (s *Simple[k]) Reset() {
+	var def k
+	for i := range s.l {
+		s.l[i] = def
+	}
+
+	s.head = 0
+	s.count = 0
 }
@@ -91,3 +91,60 @@ func TestSimpleGet(t *testing.T) {
 		}
 	})
 }
+
+func TestSimpleGetOrder(t *testing.T) {
+	t.Run("test get on partially filled Simple", func(t *testing.T) {
+		simple := NewSimple[int](3)
+
+		simple.Add(9)
+		simple.Add(55)
+
+		if simple.Get(0) != 9 || simple.Get(1) != 55 {
+			t.Errorf("expected items to be %d and %d. received %d and %d", 9, 55, simple.Get(0), simple.Get(1))
+		}
+
+		if simple.Get(2) != 0 {
+			t.Errorf("expected get beyond the number of items to return nil value. received %d", simple.Get(2))
+		}
+	})
+
+	t.Run("test get on Simple over capacity", func(t *testing.T) {
+		simple := NewSimple[int](3)
+
+		for _, item := range []int{9, 55, 69, 93} {
+			simple.Add(item)
+		}
+
+		if simple.Get(0) != 55 || simple.Get(1) != 69 || simple.Get(2) != 93 {
+			t.Errorf("expected items to be ordered from oldest to newest. received %d, %d, %d", simple.Get(0), simple.Get(1), simple.Get(2))
+		}
+	})
+}
+
+func TestSimpleLenAndReset(t *testing.T) {
+	simple := NewSimple[[]int](2)
+
+	if simple.Len() != 0 || simple.Size() != 2 {
+		t.Errorf("expected empty Simple with size %d. received length %d and size %d", 2, simple.Len(), simple.Size())
+	}
+
+	simple.Add([]int{1})
+	simple.Add([]int{2})
+	simple.Add([]int{3})
+
+	if simple.Len() != 2 {
+		t.Errorf("expected Simple length to be capped at %d. received %d", 2, simple.Len())
+	}
+
+	simple.Reset()
+
+	if simple.Len() != 0 || simple.head != 0 {
+		t.Errorf("expected Simple to be empty after reset. received length %d and head %d", simple.Len(), simple.head)
+	}
+
+	for _, item := range simple.l {
+		if item != nil {
+			t.Errorf("expected reset to clear all items. found %v", item)
+		}
+	}
+}
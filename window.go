@@ -0,0 +1,59 @@
+package ibt
+
+import (
+	"github.com/teamjorge/ibt/headers"
+	"github.com/teamjorge/ibt/utilities/fifo"
+)
+
+// WindowProcessor is a Processor that receives the ticks preceding the current tick.
+//
+// This is useful for calculating derivatives, smoothing values or detecting events. The window
+// is reset at the start of each stub. Process will call ProcessWindow instead of Process for
+// processors implementing this interface. ProcessWindow is also called instead of ProcessContext for
+// processors implementing ContextProcessor, which can read the TickContext with TickWindow.Context.
+type WindowProcessor interface {
+	Processor
+	// WindowSize is the maximum number of preceding ticks to retain
+	WindowSize() int
+	ProcessWindow(input Tick, window TickWindow, hasNext bool, session *headers.Session) error
+}
+
+// TickWindow is a read-only view of the ticks preceding the current tick.
+type TickWindow struct {
+	ring *fifo.Simple[Tick]
+	ctx  TickContext
+}
+
+// Context is the TickContext of the current tick
+func (w TickWindow) Context() TickContext { return w.ctx }
+
+// Len is the number of ticks currently in the window
+func (w TickWindow) Len() int {
+	if w.ring == nil {
+		return 0
+	}
+
+	return w.ring.Len()
+}
+
+// At returns the tick at the given index, where 0 is the oldest tick in the window.
+//
+// Nil will be returned if the index is out of range.
+func (w TickWindow) At(idx int) Tick {
+	if w.ring == nil {
+		return nil
+	}
+
+	return w.ring.Get(idx)
+}
+
+// Previous returns the tick n ticks before the current tick, where 1 is the tick immediately preceding it.
+//
+// Nil will be returned if n is out of range.
+func (w TickWindow) Previous(n int) Tick {
+	if n <= 0 {
+		return nil
+	}
+
+	return w.At(w.Len() - n)
+}
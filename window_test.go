@@ -0,0 +1,123 @@
+package ibt
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/teamjorge/ibt/headers"
+)
+
+type testWindowProcessor struct {
+	testProcessor
+	size     int
+	lengths  []int
+	previous []Tick
+	oldest   []Tick
+}
+
+func (t *testWindowProcessor) WindowSize() int { return t.size }
+
+func (t *testWindowProcessor) ProcessWindow(input Tick, window TickWindow, hasNext bool, session *headers.Session) error {
+	t.lengths = append(t.lengths, window.Len())
+	t.previous = append(t.previous, window.Previous(1))
+	t.oldest = append(t.oldest, window.At(0))
+
+	return t.Process(input, hasNext, session)
+}
+
+type testWindowContextProcessor struct {
+	testWindowProcessor
+	contexts []TickContext
+}
+
+func (t *testWindowContextProcessor) ProcessWindow(input Tick, window TickWindow, hasNext bool, session *headers.Session) error {
+	t.contexts = append(t.contexts, window.Context())
+
+	return t.testWindowProcessor.ProcessWindow(input, window, hasNext, session)
+}
+
+func (t *testWindowContextProcessor) ProcessContext(input Tick, tickCtx TickContext, hasNext bool, session *headers.Session) error {
+	return errors.New("expected ProcessWindow to be called instead of ProcessContext")
+}
+
+func TestWindowProcessor(t *testing.T) {
+	f, err := os.Open(".testing/valid_test_file.ibt")
+	if err != nil {
+		t.Errorf("failed to open testing file - %v", err)
+		return
+	}
+	defer f.Close()
+
+	testHeaders, err := headers.ParseHeaders(f)
+	if err != nil {
+		t.Errorf("failed to parse header for testing file - %v", err)
+		return
+	}
+
+	stubs := StubGroup{
+		{filepath: ".testing/valid_test_file.ibt", header: testHeaders, r: f},
+		{filepath: ".testing/valid_test_file.ibt", header: testHeaders, r: f},
+	}
+
+	t.Run("test Process() with WindowProcessor", func(t *testing.T) {
+		proc := testWindowProcessor{testProcessor: testProcessor{whitelist: []string{"SessionTime"}}, size: 5}
+
+		if err := Process(context.Background(), stubs, &proc); err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		if len(proc.results) != 778 {
+			t.Errorf("expected %d ticks to be processed. received %d", 778, len(proc.results))
+			return
+		}
+
+		for idx, expected := range []int{0, 1, 2, 3, 4, 5, 5} {
+			if proc.lengths[idx] != expected {
+				t.Errorf("expected window length of tick %d to be %d. received %d", idx, expected, proc.lengths[idx])
+			}
+		}
+
+		if proc.previous[0] != nil {
+			t.Errorf("expected no previous tick for the first tick. received %v", proc.previous[0])
+		}
+
+		if proc.previous[10]["SessionTime"] != proc.results[9]["SessionTime"] {
+			t.Errorf("expected previous tick to be %v. received %v", proc.results[9], proc.previous[10])
+		}
+
+		if proc.oldest[10]["SessionTime"] != proc.results[5]["SessionTime"] {
+			t.Errorf("expected oldest tick in the window to be %v. received %v", proc.results[5], proc.oldest[10])
+		}
+
+		if proc.lengths[389] != 0 {
+			t.Errorf("expected window to be reset at the start of the second stub. received length %d", proc.lengths[389])
+		}
+	})
+
+	t.Run("test Process() with WindowProcessor and ContextProcessor", func(t *testing.T) {
+		proc := testWindowContextProcessor{testWindowProcessor: testWindowProcessor{testProcessor: testProcessor{whitelist: []string{"Speed"}}, size: 5}}
+
+		if err := Process(context.Background(), stubs, &proc); err != nil {
+			t.Errorf("expected Process() to run without err. received error: %v", err)
+		}
+
+		if len(proc.contexts) != 778 || len(proc.lengths) != 778 {
+			t.Errorf("expected every tick to be processed as a window with its context. received %d windows and %d contexts", len(proc.lengths), len(proc.contexts))
+			return
+		}
+
+		if last := proc.contexts[777]; last.Index != 388 || last.GroupIndex != 777 || last.Stub.Index != 1 || last.SessionTime <= 0 {
+			t.Errorf("expected the context of the last tick of the second stub. received %+v", last)
+		}
+	})
+
+	t.Run("test empty TickWindow", func(t *testing.T) {
+		window := TickWindow{}
+
+		if window.Len() != 0 || window.At(0) != nil || window.Previous(1) != nil {
+			t.Error("expected an empty TickWindow to not contain any ticks")
+		}
+	})
+}